@@ -3,6 +3,7 @@ package adapter
 import (
 	"context"
 	"net"
+	"time"
 
 	"github.com/sagernet/sing-box/common/urltest"
 	N "github.com/sagernet/sing/common/network"
@@ -14,16 +15,18 @@ type ClashServer interface {
 	Mode() string
 	StoreSelected() bool
 	StoreFakeIP() bool
+	StoreQuota() bool
 	CacheFile() ClashCacheFile
 	HistoryStorage() *urltest.HistoryStorage
-	RoutedConnection(ctx context.Context, conn net.Conn, metadata InboundContext, matchedRule Rule) (net.Conn, Tracker)
-	RoutedPacketConnection(ctx context.Context, conn N.PacketConn, metadata InboundContext, matchedRule Rule) (N.PacketConn, Tracker)
 }
 
 type ClashCacheFile interface {
 	LoadSelected(group string) string
 	StoreSelected(group string, selected string) error
+	LoadConnectionHistory() []byte
+	StoreConnectionHistory(content []byte) error
 	FakeIPStorage
+	QuotaStorage
 }
 
 type Tracker interface {
@@ -38,6 +41,7 @@ type OutboundGroup interface {
 type URLTestGroup interface {
 	OutboundGroup
 	URLTest(ctx context.Context, url string) (map[string]uint16, error)
+	HistoryStorage() *urltest.HistoryStorage
 }
 
 func OutboundTag(detour Outbound) string {
@@ -56,3 +60,8 @@ type V2RayStatsService interface {
 	RoutedConnection(inbound string, outbound string, user string, conn net.Conn) net.Conn
 	RoutedPacketConnection(inbound string, outbound string, user string, conn N.PacketConn) N.PacketConn
 }
+
+type MetricsServer interface {
+	Service
+	DNSQuery(transport string, queryType string, elapsed time.Duration, err error)
+}
@@ -12,6 +12,13 @@ type FakeIPStore interface {
 	Create(domain string, strategy dns.DomainStrategy) (netip.Addr, error)
 	Lookup(address netip.Addr) (string, bool)
 	Reset() error
+	Usage() []FakeIPUsage
+}
+
+type FakeIPUsage struct {
+	Range     netip.Prefix
+	Allocated float64
+	Size      float64
 }
 
 type FakeIPStorage interface {
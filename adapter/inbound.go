@@ -2,8 +2,10 @@ package adapter
 
 import (
 	"context"
+	"crypto/x509"
 	"net"
 	"net/netip"
+	"time"
 
 	"github.com/sagernet/sing-box/common/process"
 	"github.com/sagernet/sing-box/option"
@@ -24,6 +26,28 @@ type InjectableInbound interface {
 	NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata InboundContext) error
 }
 
+type TLSInbound interface {
+	Inbound
+	Certificates() []*x509.Certificate
+}
+
+type AccessControlInbound interface {
+	Inbound
+	AccessController() InboundAccessController
+}
+
+type InboundAccessController interface {
+	RoutedConnection(ctx context.Context, metadata InboundContext) (Tracker, error)
+	Bans() []InboundBan
+	Unban(address netip.Addr) bool
+}
+
+type InboundBan struct {
+	Address  netip.Addr
+	Failures int
+	Expires  time.Time
+}
+
 type InboundContext struct {
 	Inbound     string
 	InboundType string
@@ -36,6 +60,8 @@ type InboundContext struct {
 	User        string
 	Outbound    string
 
+	ProxyProtocol *ProxyProtocolInfo
+
 	// cache
 
 	InboundDetour        string
@@ -53,6 +79,16 @@ type InboundContext struct {
 	QueryType uint16
 }
 
+// ProxyProtocolInfo holds the PROXY protocol v2 TLVs received by the inbound.
+type ProxyProtocolInfo struct {
+	Authority  string
+	UniqueID   string
+	SSL        bool
+	SSLVersion string
+	SSLCN      string
+	RawTLVs    []byte
+}
+
 type inboundContextKey struct{}
 
 func WithContext(ctx context.Context, inboundContext *InboundContext) context.Context {
@@ -0,0 +1,36 @@
+package adapter
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+type QuotaStorage interface {
+	LoadQuotaUsage(name string) *QuotaUsage
+	StoreQuotaUsage(name string, usage *QuotaUsage) error
+}
+
+type QuotaUsage struct {
+	PeriodStart time.Time
+	Upload      uint64
+	Download    uint64
+}
+
+func (u *QuotaUsage) MarshalBinary() (data []byte, err error) {
+	data = make([]byte, 24)
+	binary.BigEndian.PutUint64(data, uint64(u.PeriodStart.Unix()))
+	binary.BigEndian.PutUint64(data[8:], u.Upload)
+	binary.BigEndian.PutUint64(data[16:], u.Download)
+	return
+}
+
+func (u *QuotaUsage) UnmarshalBinary(data []byte) error {
+	if len(data) < 24 {
+		return io.ErrUnexpectedEOF
+	}
+	u.PeriodStart = time.Unix(int64(binary.BigEndian.Uint64(data)), 0)
+	u.Upload = binary.BigEndian.Uint64(data[8:])
+	u.Download = binary.BigEndian.Uint64(data[16:])
+	return nil
+}
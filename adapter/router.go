@@ -17,6 +17,7 @@ import (
 type Router interface {
 	Service
 
+	Inbounds() []Inbound
 	Outbounds() []Outbound
 	Outbound(tag string) (Outbound, bool)
 	DefaultOutbound(network string) Outbound
@@ -52,6 +53,9 @@ type Router interface {
 	V2RayServer() V2RayServer
 	SetV2RayServer(server V2RayServer)
 
+	MetricsServer() MetricsServer
+	SetMetricsServer(server MetricsServer)
+
 	ResetNetwork() error
 }
 
@@ -78,6 +82,18 @@ type Rule interface {
 	String() string
 }
 
+type RouteRule interface {
+	Rule
+	RateLimit() string
+}
+
+// PACRule is implemented by rules that can be translated into a PAC condition,
+// a JavaScript expression over the host and port variables of FindProxyForURL.
+type PACRule interface {
+	Rule
+	PACCondition(metadata *InboundContext) (condition string, loaded bool)
+}
+
 type DNSRule interface {
 	Rule
 	DisableCache() bool
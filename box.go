@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/traffic"
 	"github.com/sagernet/sing-box/experimental"
 	"github.com/sagernet/sing-box/experimental/libbox/platform"
 	"github.com/sagernet/sing-box/inbound"
@@ -19,6 +20,7 @@ import (
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/service"
 )
 
 var _ adapter.Service = (*Box)(nil)
@@ -57,6 +59,10 @@ func New(options Options) (*Box, error) {
 	if experimentalOptions.V2RayAPI != nil && experimentalOptions.V2RayAPI.Listen != "" {
 		needV2RayAPI = true
 	}
+	needMetrics := experimentalOptions.Metrics != nil && experimentalOptions.Metrics.Listen != ""
+	if needClashAPI || needMetrics || common.PtrValueOrDefault(options.Route).AccessLog != nil {
+		ctx = service.ContextWith(ctx, traffic.NewManager())
+	}
 	var defaultLogWriter io.Writer
 	if options.PlatformInterface != nil {
 		defaultLogWriter = io.Discard
@@ -158,6 +164,14 @@ func New(options Options) (*Box, error) {
 		router.SetV2RayServer(v2rayServer)
 		preServices["v2ray api"] = v2rayServer
 	}
+	if needMetrics {
+		metricsServer, err := experimental.NewMetricsServer(ctx, router, logFactory.NewLogger("metrics"), *experimentalOptions.Metrics)
+		if err != nil {
+			return nil, E.Cause(err, "create metrics server")
+		}
+		router.SetMetricsServer(metricsServer)
+		preServices["metrics"] = metricsServer
+	}
 	return &Box{
 		router:       router,
 		inbounds:     inbounds,
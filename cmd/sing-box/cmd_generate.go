@@ -7,6 +7,7 @@ import (
 	"os"
 	"strconv"
 
+	"github.com/sagernet/sing-box/common/tls"
 	"github.com/sagernet/sing-box/log"
 
 	"github.com/gofrs/uuid/v5"
@@ -24,6 +25,7 @@ func init() {
 	commandGenerate.AddCommand(commandGenerateRandom)
 	commandGenerate.AddCommand(commandGenerateWireGuardKeyPair)
 	commandGenerate.AddCommand(commandGenerateRealityKeyPair)
+	commandGenerate.AddCommand(commandGenerateECHKeyPair)
 	mainCommand.AddCommand(commandGenerate)
 }
 
@@ -137,3 +139,25 @@ func generateRealityKey() error {
 	os.Stdout.WriteString("PublicKey: " + base64.RawURLEncoding.EncodeToString(publicKey[:]) + "\n")
 	return nil
 }
+
+var commandGenerateECHKeyPair = &cobra.Command{
+	Use:   "ech-keypair <public_name>",
+	Short: "Generate TLS ECH key pair",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := generateECHKey(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func generateECHKey(publicName string) error {
+	configList, keyPem, err := tls.ECHKeygenDefault(publicName)
+	if err != nil {
+		return err
+	}
+	os.Stdout.WriteString("Config: " + base64.StdEncoding.EncodeToString(configList) + "\n")
+	os.Stdout.WriteString(keyPem)
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/spf13/cobra"
+)
+
+var commandPinFlagServerName string
+
+var commandPin = &cobra.Command{
+	Use:   "pin [address]",
+	Short: "Print the certificate pins of a TLS server",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := pin(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandPin.Flags().StringVarP(&commandPinFlagServerName, "server-name", "s", "", "Set server name")
+	commandTools.AddCommand(commandPin)
+}
+
+func pin(address string) error {
+	serverAddress := M.ParseSocksaddr(address)
+	if serverAddress.Port == 0 {
+		serverAddress.Port = 443
+	}
+	serverName := commandPinFlagServerName
+	if serverName == "" && serverAddress.IsFqdn() {
+		serverName = serverAddress.Fqdn
+	}
+	instance, err := createPreStartedClient()
+	if err != nil {
+		return err
+	}
+	defer instance.Close()
+	dialer, err := createDialer(instance, N.NetworkTCP, commandToolsFlagOutbound)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), C.TCPTimeout)
+	defer cancel()
+	conn, err := dialer.DialContext(ctx, N.NetworkTCP, serverAddress)
+	if err != nil {
+		return E.Cause(err, "connect to server")
+	}
+	defer conn.Close()
+	tlsConfig, err := tls.NewClient(instance.Router(), serverAddress.AddrString(), option.OutboundTLSOptions{
+		Enabled:    true,
+		ServerName: serverName,
+		Insecure:   true,
+	})
+	if err != nil {
+		return err
+	}
+	tlsConn, err := tls.ClientHandshake(ctx, conn, tlsConfig)
+	if err != nil {
+		return E.Cause(err, "TLS handshake")
+	}
+	certificate := tlsConn.ConnectionState().PeerCertificates[0]
+	os.Stdout.WriteString("Subject: " + certificate.Subject.String() + "\n")
+	os.Stdout.WriteString("Certificate SHA-256: " + tls.CertificateSHA256(certificate) + "\n")
+	os.Stdout.WriteString("Public key SHA-256: " + tls.CertificatePublicKeySHA256(certificate) + "\n")
+	return nil
+}
@@ -0,0 +1,394 @@
+package access
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"go4.org/netipx"
+	"golang.org/x/exp/slices"
+)
+
+const cleanupInterval = time.Minute
+
+var _ adapter.InboundAccessController = (*Controller)(nil)
+
+type Controller struct {
+	logger                log.ContextLogger
+	allowAddress          *netipx.IPSet
+	denyAddress           *netipx.IPSet
+	maxConnectionsPerIP   int
+	maxConnectionsPerUser int
+	connectionRate        float64
+	connectionBurst       float64
+	authFailureLimit      int
+	authFailureWindow     time.Duration
+	banDuration           time.Duration
+
+	access          sync.RWMutex
+	ipConnections   map[netip.Addr]int
+	userConnections map[string]int
+	buckets         map[netip.Addr]*bucket
+	failures        map[netip.Addr]*failureRecord
+	bans            map[netip.Addr]adapter.InboundBan
+	done            chan struct{}
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+type failureRecord struct {
+	count int
+	start time.Time
+}
+
+func NewController(logger log.ContextLogger, options option.InboundAccessOptions) (*Controller, error) {
+	controller := &Controller{
+		logger:                logger,
+		maxConnectionsPerIP:   options.MaxConnectionsPerIP,
+		maxConnectionsPerUser: options.MaxConnectionsPerUser,
+		connectionRate:        float64(options.ConnectionRate),
+		connectionBurst:       float64(options.ConnectionBurst),
+		authFailureLimit:      options.AuthFailureLimit,
+		authFailureWindow:     time.Duration(options.AuthFailureWindow),
+		banDuration:           time.Duration(options.BanDuration),
+		ipConnections:         make(map[netip.Addr]int),
+		userConnections:       make(map[string]int),
+		buckets:               make(map[netip.Addr]*bucket),
+		failures:              make(map[netip.Addr]*failureRecord),
+		bans:                  make(map[netip.Addr]adapter.InboundBan),
+		done:                  make(chan struct{}),
+	}
+	var err error
+	if len(options.AllowAddress) > 0 {
+		controller.allowAddress, err = buildIPSet(options.AllowAddress)
+		if err != nil {
+			return nil, E.Cause(err, "parse allow_address")
+		}
+	}
+	if len(options.DenyAddress) > 0 {
+		controller.denyAddress, err = buildIPSet(options.DenyAddress)
+		if err != nil {
+			return nil, E.Cause(err, "parse deny_address")
+		}
+	}
+	if controller.connectionBurst == 0 {
+		controller.connectionBurst = controller.connectionRate
+	}
+	if controller.authFailureWindow == 0 {
+		controller.authFailureWindow = time.Minute
+	}
+	if controller.banDuration == 0 {
+		controller.banDuration = 10 * time.Minute
+	}
+	go controller.loopCleanup()
+	return controller, nil
+}
+
+func buildIPSet(prefixes []option.ListenPrefix) (*netipx.IPSet, error) {
+	var builder netipx.IPSetBuilder
+	for _, prefix := range prefixes {
+		builder.AddPrefix(prefix.Build())
+	}
+	return builder.IPSet()
+}
+
+func (c *Controller) Close() error {
+	select {
+	case <-c.done:
+	default:
+		close(c.done)
+	}
+	return nil
+}
+
+func (c *Controller) loopCleanup() {
+	ticker := time.NewTicker(cleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			c.cleanup(now)
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *Controller) cleanup(now time.Time) {
+	c.access.Lock()
+	defer c.access.Unlock()
+	for address, ban := range c.bans {
+		if now.After(ban.Expires) {
+			delete(c.bans, address)
+		}
+	}
+	for address, record := range c.failures {
+		if now.Sub(record.start) > c.authFailureWindow {
+			delete(c.failures, address)
+		}
+	}
+	for address, connectionBucket := range c.buckets {
+		if now.Sub(connectionBucket.last).Seconds()*c.connectionRate+connectionBucket.tokens >= c.connectionBurst {
+			delete(c.buckets, address)
+		}
+	}
+}
+
+// checkAddress must not modify the controller, as it is called with only the read lock held.
+// Expired bans are removed by cleanup.
+func (c *Controller) checkAddress(address netip.Addr) error {
+	if ban, banned := c.bans[address]; banned && time.Now().Before(ban.Expires) {
+		return E.New("banned until ", ban.Expires.Format(time.RFC3339))
+	}
+	if c.denyAddress != nil && c.denyAddress.Contains(address) {
+		return E.New("denied by deny_address")
+	}
+	if c.allowAddress != nil && !c.allowAddress.Contains(address) {
+		return E.New("not in allow_address")
+	}
+	return nil
+}
+
+func (c *Controller) AllowPacket(address netip.Addr) bool {
+	c.access.RLock()
+	defer c.access.RUnlock()
+	return c.checkAddress(address.Unmap()) == nil
+}
+
+// AcceptConnection checks a new connection from address, which must be released
+// with ReleaseConnection once closed.
+func (c *Controller) AcceptConnection(address netip.Addr) error {
+	c.access.Lock()
+	defer c.access.Unlock()
+	err := c.checkAddress(address)
+	if err != nil {
+		return err
+	}
+	if c.connectionRate > 0 {
+		now := time.Now()
+		connectionBucket := c.buckets[address]
+		if connectionBucket == nil {
+			connectionBucket = &bucket{tokens: c.connectionBurst, last: now}
+			c.buckets[address] = connectionBucket
+		} else {
+			connectionBucket.tokens += now.Sub(connectionBucket.last).Seconds() * c.connectionRate
+			if connectionBucket.tokens > c.connectionBurst {
+				connectionBucket.tokens = c.connectionBurst
+			}
+			connectionBucket.last = now
+		}
+		if connectionBucket.tokens < 1 {
+			return E.New("connection rate exceeded")
+		}
+		connectionBucket.tokens--
+	}
+	if c.maxConnectionsPerIP > 0 {
+		if c.ipConnections[address] >= c.maxConnectionsPerIP {
+			return E.New("too many connections")
+		}
+		c.ipConnections[address]++
+	}
+	return nil
+}
+
+func (c *Controller) ReleaseConnection(address netip.Addr) {
+	c.access.Lock()
+	defer c.access.Unlock()
+	c.ipConnections[address]--
+	if c.ipConnections[address] <= 0 {
+		delete(c.ipConnections, address)
+	}
+}
+
+func (c *Controller) Listener(listener net.Listener) net.Listener {
+	return &acceptListener{Listener: listener, controller: c}
+}
+
+// ConnectionFailed counts an authentication failure for the source address
+// if err is an AuthError.
+func (c *Controller) ConnectionFailed(address netip.Addr, err error) {
+	if c.authFailureLimit == 0 || !IsAuthError(err) {
+		return
+	}
+	address = address.Unmap()
+	c.access.Lock()
+	defer c.access.Unlock()
+	now := time.Now()
+	record := c.failures[address]
+	if record == nil || now.Sub(record.start) > c.authFailureWindow {
+		record = &failureRecord{start: now}
+		c.failures[address] = record
+	}
+	record.count++
+	if record.count < c.authFailureLimit {
+		return
+	}
+	delete(c.failures, address)
+	c.bans[address] = adapter.InboundBan{
+		Address:  address,
+		Failures: record.count,
+		Expires:  now.Add(c.banDuration),
+	}
+	c.logger.Warn("banned ", address, " for ", c.banDuration, " after ", record.count, " authentication failures")
+}
+
+func (c *Controller) RoutedConnection(ctx context.Context, metadata adapter.InboundContext) (adapter.Tracker, error) {
+	c.access.Lock()
+	defer c.access.Unlock()
+	if metadata.Source.IsIP() {
+		err := c.checkAddress(metadata.Source.Addr.Unmap())
+		if err != nil {
+			return nil, E.Cause(err, "reject connection from ", metadata.Source.Addr)
+		}
+	}
+	if c.maxConnectionsPerUser == 0 || metadata.User == "" {
+		return (*userTracker)(nil), nil
+	}
+	if c.userConnections[metadata.User] >= c.maxConnectionsPerUser {
+		return nil, E.New("too many connections for user ", metadata.User)
+	}
+	c.userConnections[metadata.User]++
+	return &userTracker{controller: c, user: metadata.User}, nil
+}
+
+func (c *Controller) Bans() []adapter.InboundBan {
+	c.access.RLock()
+	defer c.access.RUnlock()
+	now := time.Now()
+	var bans []adapter.InboundBan
+	for _, ban := range c.bans {
+		if now.Before(ban.Expires) {
+			bans = append(bans, ban)
+		}
+	}
+	slices.SortFunc(bans, func(a, b adapter.InboundBan) bool {
+		return a.Address.Less(b.Address)
+	})
+	return bans
+}
+
+func (c *Controller) Unban(address netip.Addr) bool {
+	address = address.Unmap()
+	c.access.Lock()
+	defer c.access.Unlock()
+	_, banned := c.bans[address]
+	delete(c.bans, address)
+	delete(c.failures, address)
+	return banned
+}
+
+var _ adapter.Tracker = (*userTracker)(nil)
+
+type userTracker struct {
+	controller *Controller
+	user       string
+}
+
+func (t *userTracker) Leave() {
+	if t == nil {
+		return
+	}
+	t.controller.access.Lock()
+	defer t.controller.access.Unlock()
+	t.controller.userConnections[t.user]--
+	if t.controller.userConnections[t.user] <= 0 {
+		delete(t.controller.userConnections, t.user)
+	}
+}
+
+type acceptListener struct {
+	net.Listener
+	controller *Controller
+}
+
+func (l *acceptListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+		address := M.AddrPortFromNet(conn.RemoteAddr()).Addr().Unmap()
+		if !address.IsValid() {
+			// unix socket connections have no source address to check
+			return conn, nil
+		}
+		err = l.controller.AcceptConnection(address)
+		if err != nil {
+			l.controller.logger.Debug("rejected connection from ", address, ": ", err)
+			conn.Close()
+			continue
+		}
+		if l.controller.maxConnectionsPerIP == 0 {
+			return conn, nil
+		}
+		return &trackedConn{Conn: conn, controller: l.controller, address: address}, nil
+	}
+}
+
+func (l *acceptListener) Upstream() any {
+	return l.Listener
+}
+
+// PacketConn drops packets from addresses not allowed by the controller,
+// for listeners that accept connections over UDP.
+func (c *Controller) PacketConn(conn net.PacketConn) net.PacketConn {
+	return &acceptPacketConn{PacketConn: conn, controller: c}
+}
+
+type acceptPacketConn struct {
+	net.PacketConn
+	controller *Controller
+}
+
+func (c *acceptPacketConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	for {
+		n, addr, err = c.PacketConn.ReadFrom(p)
+		if err != nil {
+			return
+		}
+		if c.controller.AllowPacket(M.AddrPortFromNet(addr).Addr()) {
+			return
+		}
+	}
+}
+
+func (c *acceptPacketConn) Upstream() any {
+	return c.PacketConn
+}
+
+type trackedConn struct {
+	net.Conn
+	controller *Controller
+	address    netip.Addr
+	closeOnce  sync.Once
+}
+
+func (c *trackedConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.controller.ReleaseConnection(c.address)
+	})
+	return c.Conn.Close()
+}
+
+func (c *trackedConn) Upstream() any {
+	return c.Conn
+}
+
+func (c *trackedConn) ReaderReplaceable() bool {
+	return true
+}
+
+func (c *trackedConn) WriterReplaceable() bool {
+	return true
+}
@@ -0,0 +1,319 @@
+package access
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testAuthError = NewAuthError(E.New("wrong password"))
+
+func newTestController(t *testing.T, options option.InboundAccessOptions) *Controller {
+	controller, err := NewController(log.NewNOPFactory().Logger(), options)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		controller.Close()
+	})
+	return controller
+}
+
+func TestControllerAddress(t *testing.T) {
+	t.Parallel()
+	controller := newTestController(t, option.InboundAccessOptions{
+		AllowAddress: []option.ListenPrefix{option.ListenPrefix(netip.MustParsePrefix("10.0.0.0/8"))},
+		DenyAddress:  []option.ListenPrefix{option.ListenPrefix(netip.MustParsePrefix("10.1.0.0/16"))},
+	})
+	require.True(t, controller.AllowPacket(netip.MustParseAddr("10.0.0.1")))
+	require.True(t, controller.AllowPacket(netip.MustParseAddr("::ffff:10.0.0.1")))
+	require.False(t, controller.AllowPacket(netip.MustParseAddr("10.1.0.1")))
+	require.False(t, controller.AllowPacket(netip.MustParseAddr("192.168.0.1")))
+	require.NoError(t, controller.AcceptConnection(netip.MustParseAddr("10.0.0.1")))
+	require.ErrorContains(t, controller.AcceptConnection(netip.MustParseAddr("10.1.0.1")), "deny_address")
+	require.ErrorContains(t, controller.AcceptConnection(netip.MustParseAddr("192.168.0.1")), "allow_address")
+	_, err := controller.RoutedConnection(context.Background(), adapter.InboundContext{
+		Source: M.ParseSocksaddrHostPort("192.168.0.1", 1234),
+	})
+	require.ErrorContains(t, err, "allow_address")
+	_, err = controller.RoutedConnection(context.Background(), adapter.InboundContext{})
+	require.NoError(t, err)
+}
+
+func TestControllerConnectionRate(t *testing.T) {
+	t.Parallel()
+	controller := newTestController(t, option.InboundAccessOptions{
+		ConnectionRate:  2,
+		ConnectionBurst: 3,
+	})
+	address := netip.MustParseAddr("192.168.0.1")
+	for i := 0; i < 3; i++ {
+		require.NoError(t, controller.AcceptConnection(address))
+	}
+	require.ErrorContains(t, controller.AcceptConnection(address), "rate exceeded")
+	require.NoError(t, controller.AcceptConnection(netip.MustParseAddr("192.168.0.2")))
+
+	// half a second refills one token at two connections per second
+	controller.access.Lock()
+	controller.buckets[address].last = controller.buckets[address].last.Add(-500 * time.Millisecond)
+	controller.access.Unlock()
+	require.NoError(t, controller.AcceptConnection(address))
+	require.ErrorContains(t, controller.AcceptConnection(address), "rate exceeded")
+
+	// refilled tokens are capped by the burst
+	controller.access.Lock()
+	controller.buckets[address].last = controller.buckets[address].last.Add(-time.Hour)
+	controller.access.Unlock()
+	for i := 0; i < 3; i++ {
+		require.NoError(t, controller.AcceptConnection(address))
+	}
+	require.Error(t, controller.AcceptConnection(address))
+
+	controller.cleanup(time.Now())
+	require.Contains(t, controller.buckets, address)
+	controller.cleanup(time.Now().Add(2 * time.Second))
+	require.NotContains(t, controller.buckets, address)
+}
+
+func TestControllerConnectionBurstDefault(t *testing.T) {
+	t.Parallel()
+	controller := newTestController(t, option.InboundAccessOptions{ConnectionRate: 2})
+	address := netip.MustParseAddr("192.168.0.1")
+	require.NoError(t, controller.AcceptConnection(address))
+	require.NoError(t, controller.AcceptConnection(address))
+	require.Error(t, controller.AcceptConnection(address))
+}
+
+func TestControllerMaxConnectionsPerIP(t *testing.T) {
+	t.Parallel()
+	controller := newTestController(t, option.InboundAccessOptions{MaxConnectionsPerIP: 2})
+	address := netip.MustParseAddr("192.168.0.1")
+	require.NoError(t, controller.AcceptConnection(address))
+	require.NoError(t, controller.AcceptConnection(address))
+	require.ErrorContains(t, controller.AcceptConnection(address), "too many connections")
+	require.NoError(t, controller.AcceptConnection(netip.MustParseAddr("192.168.0.2")))
+	controller.ReleaseConnection(address)
+	require.NoError(t, controller.AcceptConnection(address))
+	controller.ReleaseConnection(address)
+	controller.ReleaseConnection(address)
+	require.NotContains(t, controller.ipConnections, address)
+}
+
+func TestControllerMaxConnectionsPerUser(t *testing.T) {
+	t.Parallel()
+	controller := newTestController(t, option.InboundAccessOptions{MaxConnectionsPerUser: 1})
+	metadata := adapter.InboundContext{User: "user"}
+	tracker, err := controller.RoutedConnection(context.Background(), metadata)
+	require.NoError(t, err)
+	_, err = controller.RoutedConnection(context.Background(), metadata)
+	require.ErrorContains(t, err, "too many connections for user")
+	otherTracker, err := controller.RoutedConnection(context.Background(), adapter.InboundContext{User: "other"})
+	require.NoError(t, err)
+	anonymousTracker, err := controller.RoutedConnection(context.Background(), adapter.InboundContext{})
+	require.NoError(t, err)
+	tracker.Leave()
+	tracker, err = controller.RoutedConnection(context.Background(), metadata)
+	require.NoError(t, err)
+	tracker.Leave()
+	otherTracker.Leave()
+	anonymousTracker.Leave()
+	require.Empty(t, controller.userConnections)
+}
+
+func TestControllerBan(t *testing.T) {
+	t.Parallel()
+	controller := newTestController(t, option.InboundAccessOptions{
+		AuthFailureLimit: 2,
+		BanDuration:      option.Duration(time.Hour),
+	})
+	address := netip.MustParseAddr("192.168.0.1")
+
+	// only authentication failures are counted
+	controller.ConnectionFailed(address, io.EOF)
+	controller.ConnectionFailed(address, E.Cause(io.ErrUnexpectedEOF, "read request"))
+	require.Empty(t, controller.Bans())
+
+	controller.ConnectionFailed(address, E.Cause(NewAuthError(io.EOF), "process connection"))
+	require.Empty(t, controller.Bans())
+	controller.ConnectionFailed(netip.MustParseAddr("::ffff:192.168.0.1"), E.Errors(NewAuthError(io.EOF), io.ErrClosedPipe))
+	bans := controller.Bans()
+	require.Len(t, bans, 1)
+	require.Equal(t, address, bans[0].Address)
+	require.Equal(t, 2, bans[0].Failures)
+	require.WithinDuration(t, time.Now().Add(time.Hour), bans[0].Expires, time.Minute)
+	require.False(t, controller.AllowPacket(address))
+	require.ErrorContains(t, controller.AcceptConnection(address), "banned")
+	_, err := controller.RoutedConnection(context.Background(), adapter.InboundContext{Source: M.SocksaddrFrom(address, 1234)})
+	require.ErrorContains(t, err, "banned")
+
+	require.True(t, controller.Unban(address))
+	require.False(t, controller.Unban(address))
+	require.True(t, controller.AllowPacket(address))
+	require.Empty(t, controller.Bans())
+}
+
+func TestControllerBanExpiry(t *testing.T) {
+	t.Parallel()
+	controller := newTestController(t, option.InboundAccessOptions{AuthFailureLimit: 2})
+	require.Equal(t, time.Minute, controller.authFailureWindow)
+	require.Equal(t, 10*time.Minute, controller.banDuration)
+	address := netip.MustParseAddr("192.168.0.1")
+
+	// failures outside of the window start a new record
+	controller.ConnectionFailed(address, testAuthError)
+	controller.access.Lock()
+	controller.failures[address].start = time.Now().Add(-2 * time.Minute)
+	controller.access.Unlock()
+	controller.ConnectionFailed(address, testAuthError)
+	require.Empty(t, controller.Bans())
+	require.Equal(t, 1, controller.failures[address].count)
+	controller.cleanup(time.Now().Add(2 * time.Minute))
+	require.Empty(t, controller.failures)
+
+	controller.ConnectionFailed(address, testAuthError)
+	controller.ConnectionFailed(address, testAuthError)
+	require.Len(t, controller.Bans(), 1)
+	controller.access.Lock()
+	ban := controller.bans[address]
+	ban.Expires = time.Now().Add(-time.Second)
+	controller.bans[address] = ban
+	controller.access.Unlock()
+	require.Empty(t, controller.Bans())
+	require.True(t, controller.AllowPacket(address))
+	controller.cleanup(time.Now())
+	require.Empty(t, controller.bans)
+}
+
+func TestControllerAllowPacketConcurrent(t *testing.T) {
+	t.Parallel()
+	controller := newTestController(t, option.InboundAccessOptions{AuthFailureLimit: 1})
+	address := netip.MustParseAddr("192.168.0.1")
+	var group sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			for j := 0; j < 1000; j++ {
+				controller.AllowPacket(address)
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		controller.ConnectionFailed(address, testAuthError)
+		controller.Unban(address)
+	}
+	group.Wait()
+}
+
+func TestControllerListener(t *testing.T) {
+	t.Parallel()
+	controller := newTestController(t, option.InboundAccessOptions{
+		AllowAddress:        []option.ListenPrefix{option.ListenPrefix(netip.MustParsePrefix("127.0.0.0/8"))},
+		MaxConnectionsPerIP: 1,
+	})
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener := controller.Listener(tcpListener)
+	defer listener.Close()
+	accepted := make(chan net.Conn, 2)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				close(accepted)
+				return
+			}
+			accepted <- conn
+		}
+	}()
+	clientConn, err := net.Dial("tcp", tcpListener.Addr().String())
+	require.NoError(t, err)
+	defer clientConn.Close()
+	serverConn := <-accepted
+
+	// the second connection exceeds max_connections_per_ip and is closed by the listener
+	rejectedConn, err := net.Dial("tcp", tcpListener.Addr().String())
+	require.NoError(t, err)
+	rejectedConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = rejectedConn.Read(make([]byte, 1))
+	require.Error(t, err)
+	require.False(t, isTimeout(err))
+	rejectedConn.Close()
+
+	serverConn.Close()
+	serverConn.Close()
+	clientConn, err = net.Dial("tcp", tcpListener.Addr().String())
+	require.NoError(t, err)
+	defer clientConn.Close()
+	(<-accepted).Close()
+}
+
+func TestControllerPacketConn(t *testing.T) {
+	t.Parallel()
+	controller := newTestController(t, option.InboundAccessOptions{AuthFailureLimit: 1})
+	udpConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	packetConn := controller.PacketConn(udpConn)
+	defer packetConn.Close()
+	clientConn, err := net.Dial("udp", udpConn.LocalAddr().String())
+	require.NoError(t, err)
+	defer clientConn.Close()
+
+	// packets from the banned address are dropped until it is unbanned
+	controller.ConnectionFailed(netip.MustParseAddr("127.0.0.1"), testAuthError)
+	_, err = clientConn.Write([]byte("banned"))
+	require.NoError(t, err)
+	_, err = clientConn.Write([]byte("banned"))
+	require.NoError(t, err)
+	packetConn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, _, err = packetConn.ReadFrom(make([]byte, 16))
+	require.True(t, isTimeout(err))
+
+	require.True(t, controller.Unban(netip.MustParseAddr("127.0.0.1")))
+	_, err = clientConn.Write([]byte("allowed"))
+	require.NoError(t, err)
+	packetConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buffer := make([]byte, 16)
+	n, addr, err := packetConn.ReadFrom(buffer)
+	require.NoError(t, err)
+	require.Equal(t, "allowed", string(buffer[:n]))
+	require.Equal(t, clientConn.LocalAddr().String(), addr.String())
+}
+
+func TestControllerUnixListener(t *testing.T) {
+	t.Parallel()
+	controller := newTestController(t, option.InboundAccessOptions{
+		AllowAddress: []option.ListenPrefix{option.ListenPrefix(netip.MustParsePrefix("127.0.0.0/8"))},
+	})
+	path := filepath.Join(t.TempDir(), "test.sock")
+	unixListener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	listener := controller.Listener(unixListener)
+	defer listener.Close()
+	go func() {
+		conn, err := net.Dial("unix", path)
+		if err == nil {
+			defer conn.Close()
+			conn.Read(make([]byte, 1))
+		}
+	}()
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func isTimeout(err error) bool {
+	netErr, isNetErr := err.(net.Error)
+	return isNetErr && netErr.Timeout()
+}
@@ -0,0 +1,26 @@
+package access
+
+import "errors"
+
+// AuthError is returned by inbounds when a client fails to authenticate,
+// only these errors are counted by auth_failure_limit.
+type AuthError struct {
+	Cause error
+}
+
+func NewAuthError(cause error) error {
+	return &AuthError{cause}
+}
+
+func (e *AuthError) Error() string {
+	return "authentication failed: " + e.Cause.Error()
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Cause
+}
+
+func IsAuthError(err error) bool {
+	var authErr *AuthError
+	return errors.As(err, &authErr)
+}
@@ -0,0 +1,132 @@
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/json"
+	"github.com/sagernet/sing-box/common/rotate"
+	"github.com/sagernet/sing-box/common/traffic"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service"
+)
+
+var (
+	_ adapter.Service = (*Manager)(nil)
+	_ traffic.Hook    = (*Manager)(nil)
+)
+
+var csvHeader = []string{
+	"start", "end", "duration_ms", "id", "network", "inbound", "inbound_type", "user", "source", "destination",
+	"domain", "protocol", "rule", "chain", "upload", "download", "close_reason",
+}
+
+type Manager struct {
+	ctx     context.Context
+	logger  log.ContextLogger
+	options option.AccessLogOptions
+	writer  *rotate.Writer
+}
+
+func NewManager(ctx context.Context, logger log.ContextLogger, options option.AccessLogOptions) (*Manager, error) {
+	if options.Path == "" {
+		return nil, E.New("missing path")
+	}
+	switch options.Format {
+	case "":
+		options.Format = C.AccessLogFormatJSON
+	case C.AccessLogFormatJSON, C.AccessLogFormatCSV:
+	default:
+		return nil, E.New("unknown format: ", options.Format)
+	}
+	trafficManager := service.FromContext[*traffic.Manager](ctx)
+	if trafficManager == nil {
+		return nil, E.New("missing traffic manager")
+	}
+	manager := &Manager{
+		ctx:     ctx,
+		logger:  logger,
+		options: options,
+	}
+	trafficManager.AddHook(manager)
+	return manager, nil
+}
+
+func (m *Manager) Start() error {
+	writer, err := rotate.NewWriter(m.ctx, m.options.Path, m.options.RotateOptions)
+	if err != nil {
+		return err
+	}
+	if m.options.Format == C.AccessLogFormatCSV {
+		writer.SetHeader(formatCSV(csvHeader))
+	}
+	m.writer = writer
+	return nil
+}
+
+func (m *Manager) Close() error {
+	if m.writer == nil {
+		return nil
+	}
+	return m.writer.Close()
+}
+
+func (m *Manager) ConnectionJoined(connection *traffic.Connection) {
+}
+
+func (m *Manager) ConnectionClosed(connection *traffic.Connection, err error) {
+	m.write(connection.Context, newRecord(connection, err))
+}
+
+func (m *Manager) write(ctx context.Context, record *Record) {
+	var content []byte
+	if m.options.Format == C.AccessLogFormatCSV {
+		content = formatCSV([]string{
+			record.Start.Format(time.RFC3339Nano),
+			record.End.Format(time.RFC3339Nano),
+			strconv.FormatInt(record.Duration, 10),
+			strconv.FormatUint(uint64(record.ID), 10),
+			record.Network,
+			record.Inbound,
+			record.InboundType,
+			record.User,
+			record.Source,
+			record.Destination,
+			record.Domain,
+			record.Protocol,
+			record.Rule,
+			strings.Join(record.Chain, " > "),
+			strconv.FormatInt(record.Upload, 10),
+			strconv.FormatInt(record.Download, 10),
+			record.CloseReason,
+		})
+	} else {
+		var err error
+		content, err = json.Marshal(record)
+		if err != nil {
+			m.logger.ErrorContext(ctx, E.Cause(err, "encode access log"))
+			return
+		}
+		content = append(content, '\n')
+	}
+	_, err := m.writer.Write(content)
+	if err != nil {
+		m.logger.ErrorContext(ctx, E.Cause(err, "write access log"))
+	}
+}
+
+func formatCSV(record []string) []byte {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+	writer.Write(record)
+	writer.Flush()
+	return buffer.Bytes()
+}
@@ -0,0 +1,233 @@
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/json"
+	"github.com/sagernet/sing-box/common/traffic"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/sing/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testOutbound struct {
+	adapter.Outbound
+	tag string
+	now string
+}
+
+func (o *testOutbound) Tag() string {
+	return o.tag
+}
+
+type testGroup struct {
+	testOutbound
+}
+
+func (g *testGroup) Now() string {
+	return g.now
+}
+
+func (g *testGroup) All() []string {
+	return []string{g.now}
+}
+
+type testRouter struct {
+	adapter.Router
+	outbounds map[string]adapter.Outbound
+}
+
+func (r *testRouter) DefaultOutbound(network string) adapter.Outbound {
+	return r.outbounds["direct"]
+}
+
+func (r *testRouter) Outbound(tag string) (adapter.Outbound, bool) {
+	outbound, loaded := r.outbounds[tag]
+	return outbound, loaded
+}
+
+type testRule struct {
+	adapter.Rule
+}
+
+func (r *testRule) String() string {
+	return "domain=[example.com]"
+}
+
+func (r *testRule) Outbound() string {
+	return "select"
+}
+
+func newTestManager(t *testing.T, options option.AccessLogOptions) (*Manager, *traffic.Manager) {
+	trafficManager := traffic.NewManager()
+	ctx := service.ContextWith(context.Background(), trafficManager)
+	manager, err := NewManager(ctx, log.NewNOPFactory().Logger(), options)
+	require.NoError(t, err)
+	require.NoError(t, manager.Start())
+	t.Cleanup(func() {
+		manager.Close()
+	})
+	return manager, trafficManager
+}
+
+// trackConnection routes a connection through the traffic manager, sends
+// four bytes and closes it with err.
+func trackConnection(t *testing.T, trafficManager *traffic.Manager, rule adapter.Rule, err error) {
+	router := &testRouter{outbounds: map[string]adapter.Outbound{
+		"direct": &testOutbound{tag: "direct"},
+		"select": &testGroup{testOutbound{tag: "select", now: "direct"}},
+	}}
+	ctx := log.ContextWithNewID(context.Background())
+	metadata := adapter.InboundContext{
+		Inbound:     "mixed-in",
+		InboundType: C.TypeMixed,
+		User:        "user",
+		Source:      M.ParseSocksaddr("127.0.0.1:1234"),
+		Destination: M.ParseSocksaddr("example.com:443"),
+		Domain:      "example.com",
+		Protocol:    C.ProtocolTLS,
+	}
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	outbound := router.outbounds["direct"]
+	if rule != nil {
+		outbound = router.outbounds[rule.Outbound()]
+	}
+	trackerConn, connection := trafficManager.RoutedConnection(ctx, serverConn, metadata, rule, outbound, router)
+	go clientConn.Write([]byte("ping"))
+	_, readErr := io.ReadFull(trackerConn, make([]byte, 4))
+	require.NoError(t, readErr)
+	trackerConn.Close()
+	connection.Leave(err)
+}
+
+func TestManagerJSON(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "access.log")
+	_, trafficManager := newTestManager(t, option.AccessLogOptions{Path: path})
+	trackConnection(t, trafficManager, &testRule{}, nil)
+	trackConnection(t, trafficManager, nil, E.New("connection refused"))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := bytes.Split(bytes.TrimSuffix(content, []byte("\n")), []byte("\n"))
+	require.Len(t, lines, 2)
+	var records []Record
+	for _, line := range lines {
+		var record Record
+		require.NoError(t, json.Unmarshal(line, &record))
+		records = append(records, record)
+	}
+	require.NotZero(t, records[0].ID)
+	require.False(t, records[0].End.Before(records[0].Start))
+	require.Equal(t, "tcp", records[0].Network)
+	require.Equal(t, "mixed-in", records[0].Inbound)
+	require.Equal(t, C.TypeMixed, records[0].InboundType)
+	require.Equal(t, "user", records[0].User)
+	require.Equal(t, "127.0.0.1:1234", records[0].Source)
+	require.Equal(t, "example.com:443", records[0].Destination)
+	require.Equal(t, "example.com", records[0].Domain)
+	require.Equal(t, C.ProtocolTLS, records[0].Protocol)
+	require.Equal(t, "domain=[example.com]", records[0].Rule)
+	require.Equal(t, []string{"select", "direct"}, records[0].Chain)
+	require.Equal(t, int64(4), records[0].Upload)
+	require.Equal(t, "closed", records[0].CloseReason)
+
+	require.Equal(t, "final", records[1].Rule)
+	require.Equal(t, []string{"direct"}, records[1].Chain)
+	require.Equal(t, "connection refused", records[1].CloseReason)
+}
+
+func readCSV(t *testing.T, path string) [][]string {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	rows, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	require.NoError(t, err)
+	return rows
+}
+
+func TestManagerCSV(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "access.csv")
+	manager, trafficManager := newTestManager(t, option.AccessLogOptions{Path: path, Format: C.AccessLogFormatCSV})
+	trackConnection(t, trafficManager, &testRule{}, nil)
+	rows := readCSV(t, path)
+	require.Len(t, rows, 2)
+	require.Equal(t, csvHeader, rows[0])
+	row := rows[1]
+	require.Len(t, row, len(csvHeader))
+	require.Equal(t, "tcp", row[4])
+	require.Equal(t, "mixed-in", row[5])
+	require.Equal(t, "domain=[example.com]", row[12])
+	require.Equal(t, "select > direct", row[13])
+	require.Equal(t, "4", row[14])
+	require.Equal(t, "closed", row[16])
+	require.NoError(t, manager.Close())
+
+	// an existing file is appended to without repeating the header
+	_, trafficManager = newTestManager(t, option.AccessLogOptions{Path: path, Format: C.AccessLogFormatCSV})
+	trackConnection(t, trafficManager, nil, nil)
+	rows = readCSV(t, path)
+	require.Len(t, rows, 3)
+	require.Equal(t, csvHeader, rows[0])
+	require.Equal(t, "final", rows[2][12])
+}
+
+func TestManagerCSVEmptyFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "access.csv")
+	require.NoError(t, os.WriteFile(path, nil, 0o644))
+	_, trafficManager := newTestManager(t, option.AccessLogOptions{Path: path, Format: C.AccessLogFormatCSV})
+	trackConnection(t, trafficManager, nil, nil)
+	rows := readCSV(t, path)
+	require.Len(t, rows, 2)
+	require.Equal(t, csvHeader, rows[0])
+}
+
+func TestManagerCSVRotate(t *testing.T) {
+	t.Parallel()
+	directory := t.TempDir()
+	path := filepath.Join(directory, "access.csv")
+	_, trafficManager := newTestManager(t, option.AccessLogOptions{
+		Path:          path,
+		Format:        C.AccessLogFormatCSV,
+		RotateOptions: option.RotateOptions{MaxSize: 1},
+	})
+	trackConnection(t, trafficManager, nil, nil)
+	trackConnection(t, trafficManager, nil, nil)
+	// every rotated file starts with the header
+	rows := readCSV(t, path)
+	require.Len(t, rows, 2)
+	require.Equal(t, csvHeader, rows[0])
+	backups, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	rows = readCSV(t, backups[0])
+	require.Len(t, rows, 2)
+	require.Equal(t, csvHeader, rows[0])
+}
+
+func TestNewManager(t *testing.T) {
+	t.Parallel()
+	ctx := service.ContextWith(context.Background(), traffic.NewManager())
+	logger := log.NewNOPFactory().Logger()
+	_, err := NewManager(ctx, logger, option.AccessLogOptions{})
+	require.ErrorContains(t, err, "missing path")
+	_, err = NewManager(ctx, logger, option.AccessLogOptions{Path: "access.log", Format: "xml"})
+	require.ErrorContains(t, err, "unknown format")
+	_, err = NewManager(context.Background(), logger, option.AccessLogOptions{Path: "access.log"})
+	require.ErrorContains(t, err, "missing traffic manager")
+}
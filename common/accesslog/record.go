@@ -0,0 +1,60 @@
+package accesslog
+
+import (
+	"time"
+
+	"github.com/sagernet/sing-box/common/baderror"
+	"github.com/sagernet/sing-box/common/traffic"
+	"github.com/sagernet/sing-box/log"
+)
+
+type Record struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Duration    int64     `json:"duration_ms"`
+	ID          uint32    `json:"id,omitempty"`
+	Network     string    `json:"network"`
+	Inbound     string    `json:"inbound"`
+	InboundType string    `json:"inbound_type"`
+	User        string    `json:"user,omitempty"`
+	Source      string    `json:"source"`
+	Destination string    `json:"destination"`
+	Domain      string    `json:"domain,omitempty"`
+	Protocol    string    `json:"protocol,omitempty"`
+	Rule        string    `json:"rule"`
+	Chain       []string  `json:"chain"`
+	Upload      int64     `json:"upload"`
+	Download    int64     `json:"download"`
+	CloseReason string    `json:"close_reason"`
+}
+
+// newRecord describes a closed connection, err is the error returned by the outbound.
+func newRecord(connection *traffic.Connection, err error) *Record {
+	metadata := connection.Metadata
+	record := &Record{
+		Start:       connection.Start,
+		End:         time.Now(),
+		Network:     connection.Network,
+		Inbound:     metadata.Inbound,
+		InboundType: metadata.InboundType,
+		User:        metadata.User,
+		Source:      metadata.Source.String(),
+		Destination: metadata.Destination.String(),
+		Domain:      metadata.Domain,
+		Protocol:    metadata.Protocol,
+		Rule:        "final",
+		Chain:       connection.Chain,
+		Upload:      connection.Upload.Load(),
+		Download:    connection.Download.Load(),
+		CloseReason: baderror.CloseReason(err),
+	}
+	if id, loaded := log.IDFromContext(connection.Context); loaded {
+		record.ID = id.ID
+		record.Start = id.CreatedAt
+	}
+	if connection.Rule != nil {
+		record.Rule = connection.Rule.String()
+	}
+	record.Duration = record.End.Sub(record.Start).Milliseconds()
+	return record
+}
@@ -60,3 +60,17 @@ func WrapQUIC(err error) error {
 	}
 	return err
 }
+
+// CloseReason describes the error a connection finished with.
+func CloseReason(err error) string {
+	switch {
+	case err == nil, E.IsClosed(err):
+		return "closed"
+	case E.IsClosedOrCanceled(err):
+		return "canceled"
+	case E.IsTimeout(err):
+		return "timeout"
+	default:
+		return err.Error()
+	}
+}
@@ -4,18 +4,28 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/proxyproto"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-dns"
 	N "github.com/sagernet/sing/common/network"
 )
 
 func New(router adapter.Router, options option.DialerOptions) N.Dialer {
+	if options.UnixSocket != "" {
+		return NewUnix(options)
+	}
 	var dialer N.Dialer
 	if options.Detour == "" {
 		dialer = NewDefault(router, options)
 	} else {
 		dialer = NewDetour(router, options.Detour)
 	}
+	if options.ProxyProtocol > 0 {
+		dialer = &proxyproto.Dialer{Dialer: dialer, Version: options.ProxyProtocol}
+	}
+	if options.Detour == "" && options.TLSFragment != nil && options.TLSFragment.Enabled {
+		dialer = NewTLSFragment(dialer, *options.TLSFragment)
+	}
 	domainStrategy := dns.DomainStrategy(options.DomainStrategy)
 	if domainStrategy != dns.DomainStrategyAsIS || options.Detour == "" {
 		dialer = NewResolveDialer(router, dialer, domainStrategy, time.Duration(options.FallbackDelay))
@@ -0,0 +1,143 @@
+package dialer
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand"
+	"net"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const (
+	tlsRecordHeaderLength     = 5
+	tlsRecordTypeHandshake    = 0x16
+	tlsHandshakeClientHello   = 0x01
+	defaultTLSFragmentMinSize = 10
+	defaultTLSFragmentMaxSize = 50
+)
+
+type TLSFragmentDialer struct {
+	dialer  N.Dialer
+	record  bool
+	segment bool
+	minSize int
+	maxSize int
+	delay   time.Duration
+}
+
+func NewTLSFragment(dialer N.Dialer, options option.TLSFragmentOptions) *TLSFragmentDialer {
+	fragmentDialer := &TLSFragmentDialer{
+		dialer:  dialer,
+		record:  options.Record,
+		segment: options.Segment || !options.Record,
+		minSize: options.MinSize,
+		maxSize: options.MaxSize,
+		delay:   time.Duration(options.Delay),
+	}
+	if fragmentDialer.minSize <= 0 {
+		fragmentDialer.minSize = defaultTLSFragmentMinSize
+	}
+	if fragmentDialer.maxSize <= 0 {
+		fragmentDialer.maxSize = defaultTLSFragmentMaxSize
+	}
+	if fragmentDialer.maxSize < fragmentDialer.minSize {
+		fragmentDialer.maxSize = fragmentDialer.minSize
+	}
+	return fragmentDialer
+}
+
+func (d *TLSFragmentDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	conn, err := d.dialer.DialContext(ctx, network, destination)
+	if err != nil || N.NetworkName(network) != N.NetworkTCP {
+		return conn, err
+	}
+	return &tlsFragmentConn{Conn: conn, dialer: d}, nil
+}
+
+func (d *TLSFragmentDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return d.dialer.ListenPacket(ctx, destination)
+}
+
+func (d *TLSFragmentDialer) split(content []byte) [][]byte {
+	var chunks [][]byte
+	for len(content) > 0 {
+		size := d.minSize
+		if d.maxSize > d.minSize {
+			size += rand.Intn(d.maxSize - d.minSize + 1)
+		}
+		if size > len(content) {
+			size = len(content)
+		}
+		chunks = append(chunks, content[:size])
+		content = content[size:]
+	}
+	return chunks
+}
+
+type tlsFragmentConn struct {
+	net.Conn
+	dialer     *TLSFragmentDialer
+	fragmented bool
+}
+
+func (c *tlsFragmentConn) Write(b []byte) (n int, err error) {
+	if c.fragmented {
+		return c.Conn.Write(b)
+	}
+	c.fragmented = true
+	if len(b) <= tlsRecordHeaderLength || b[0] != tlsRecordTypeHandshake || b[tlsRecordHeaderLength] != tlsHandshakeClientHello {
+		return c.Conn.Write(b)
+	}
+	var packets [][]byte
+	recordLength := tlsRecordHeaderLength + int(binary.BigEndian.Uint16(b[3:5]))
+	if c.dialer.record && len(b) >= recordLength {
+		var records []byte
+		for _, chunk := range c.dialer.split(b[tlsRecordHeaderLength:recordLength]) {
+			record := make([]byte, tlsRecordHeaderLength, tlsRecordHeaderLength+len(chunk))
+			copy(record, b[:3])
+			binary.BigEndian.PutUint16(record[3:5], uint16(len(chunk)))
+			record = append(record, chunk...)
+			if c.dialer.segment {
+				packets = append(packets, record)
+			} else {
+				records = append(records, record...)
+			}
+		}
+		if !c.dialer.segment {
+			packets = append(packets, records)
+		}
+		if len(b) > recordLength {
+			packets = append(packets, b[recordLength:])
+		}
+	} else if c.dialer.segment {
+		packets = c.dialer.split(b)
+	} else {
+		return c.Conn.Write(b)
+	}
+	for i, packet := range packets {
+		if i > 0 && c.dialer.delay > 0 {
+			time.Sleep(c.dialer.delay)
+		}
+		_, err = c.Conn.Write(packet)
+		if err != nil {
+			return
+		}
+	}
+	return len(b), nil
+}
+
+func (c *tlsFragmentConn) Upstream() any {
+	return c.Conn
+}
+
+func (c *tlsFragmentConn) ReaderReplaceable() bool {
+	return true
+}
+
+func (c *tlsFragmentConn) WriterReplaceable() bool {
+	return c.fragmented
+}
@@ -0,0 +1,150 @@
+package dialer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testWriteConn struct {
+	net.Conn
+	writes [][]byte
+}
+
+func (c *testWriteConn) Write(b []byte) (n int, err error) {
+	c.writes = append(c.writes, append([]byte(nil), b...))
+	return len(b), nil
+}
+
+func testClientHello(bodyLength int) []byte {
+	record := []byte{tlsRecordTypeHandshake, 0x03, 0x01, 0, 0}
+	binary.BigEndian.PutUint16(record[3:5], uint16(bodyLength))
+	body := make([]byte, bodyLength)
+	body[0] = tlsHandshakeClientHello
+	for i := 1; i < bodyLength; i++ {
+		body[i] = byte(i)
+	}
+	return append(record, body...)
+}
+
+func newTestFragmentConn(options option.TLSFragmentOptions) *tlsFragmentConn {
+	return &tlsFragmentConn{
+		Conn:   &testWriteConn{},
+		dialer: NewTLSFragment(nil, options),
+	}
+}
+
+// parseTestRecords checks that content is a sequence of TLS records carrying
+// the header of the original record and returns the joined payload.
+func parseTestRecords(t *testing.T, content []byte, header []byte, maxSize int) []byte {
+	var payload []byte
+	for len(content) > 0 {
+		require.GreaterOrEqual(t, len(content), tlsRecordHeaderLength)
+		require.Equal(t, header[:3], content[:3])
+		length := int(binary.BigEndian.Uint16(content[3:5]))
+		require.LessOrEqual(t, length, maxSize)
+		require.GreaterOrEqual(t, len(content), tlsRecordHeaderLength+length)
+		payload = append(payload, content[tlsRecordHeaderLength:tlsRecordHeaderLength+length]...)
+		content = content[tlsRecordHeaderLength+length:]
+	}
+	return payload
+}
+
+func TestTLSFragmentSplit(t *testing.T) {
+	t.Parallel()
+	fragmentDialer := NewTLSFragment(nil, option.TLSFragmentOptions{MinSize: 3, MaxSize: 7})
+	content := bytes.Repeat([]byte("0123456789"), 10)
+	for i := 0; i < 100; i++ {
+		chunks := fragmentDialer.split(content)
+		for _, chunk := range chunks[:len(chunks)-1] {
+			require.GreaterOrEqual(t, len(chunk), 3)
+			require.LessOrEqual(t, len(chunk), 7)
+		}
+		require.LessOrEqual(t, len(chunks[len(chunks)-1]), 7)
+		require.Equal(t, content, bytes.Join(chunks, nil))
+	}
+}
+
+func TestTLSFragmentOptions(t *testing.T) {
+	t.Parallel()
+	fragmentDialer := NewTLSFragment(nil, option.TLSFragmentOptions{})
+	require.True(t, fragmentDialer.segment)
+	require.False(t, fragmentDialer.record)
+	require.Equal(t, defaultTLSFragmentMinSize, fragmentDialer.minSize)
+	require.Equal(t, defaultTLSFragmentMaxSize, fragmentDialer.maxSize)
+	fragmentDialer = NewTLSFragment(nil, option.TLSFragmentOptions{Record: true, MinSize: 80})
+	require.False(t, fragmentDialer.segment)
+	require.Equal(t, 80, fragmentDialer.maxSize)
+}
+
+func TestTLSFragmentSegment(t *testing.T) {
+	t.Parallel()
+	conn := newTestFragmentConn(option.TLSFragmentOptions{Segment: true, MinSize: 10, MaxSize: 20})
+	clientHello := testClientHello(200)
+	n, err := conn.Write(clientHello)
+	require.NoError(t, err)
+	require.Equal(t, len(clientHello), n)
+	writes := conn.Conn.(*testWriteConn).writes
+	require.Greater(t, len(writes), 1)
+	for _, write := range writes {
+		require.LessOrEqual(t, len(write), 20)
+	}
+	require.Equal(t, clientHello, bytes.Join(writes, nil))
+	require.True(t, conn.WriterReplaceable())
+}
+
+func TestTLSFragmentRecord(t *testing.T) {
+	t.Parallel()
+	clientHello := testClientHello(200)
+	trailing := []byte("early data")
+	for _, segment := range []bool{false, true} {
+		conn := newTestFragmentConn(option.TLSFragmentOptions{Record: true, Segment: segment, MinSize: 10, MaxSize: 20})
+		n, err := conn.Write(append(append([]byte(nil), clientHello...), trailing...))
+		require.NoError(t, err)
+		require.Equal(t, len(clientHello)+len(trailing), n)
+		writes := conn.Conn.(*testWriteConn).writes
+		require.Equal(t, trailing, writes[len(writes)-1])
+		writes = writes[:len(writes)-1]
+		if segment {
+			require.Greater(t, len(writes), 1)
+			for _, write := range writes {
+				require.LessOrEqual(t, len(write), tlsRecordHeaderLength+20)
+			}
+		} else {
+			require.Len(t, writes, 1)
+		}
+		payload := parseTestRecords(t, bytes.Join(writes, nil), clientHello, 20)
+		require.Equal(t, clientHello[tlsRecordHeaderLength:], payload)
+	}
+}
+
+func TestTLSFragmentRecordPartial(t *testing.T) {
+	t.Parallel()
+	conn := newTestFragmentConn(option.TLSFragmentOptions{Record: true})
+	clientHello := testClientHello(200)
+	_, err := conn.Write(clientHello[:100])
+	require.NoError(t, err)
+	require.Equal(t, [][]byte{clientHello[:100]}, conn.Conn.(*testWriteConn).writes)
+}
+
+func TestTLSFragmentPassthrough(t *testing.T) {
+	t.Parallel()
+	for _, content := range [][]byte{
+		[]byte("GET / HTTP/1.1\r\n\r\n"),
+		{tlsRecordTypeHandshake, 0x03, 0x03, 0x00, 0x01},
+		append([]byte{tlsRecordTypeHandshake, 0x03, 0x03, 0x00, 0x01}, 0x02),
+	} {
+		conn := newTestFragmentConn(option.TLSFragmentOptions{Record: true, Segment: true})
+		_, err := conn.Write(content)
+		require.NoError(t, err)
+		clientHello := testClientHello(200)
+		_, err = conn.Write(clientHello)
+		require.NoError(t, err)
+		require.Equal(t, [][]byte{content, clientHello}, conn.Conn.(*testWriteConn).writes)
+	}
+}
@@ -0,0 +1,42 @@
+package dialer
+
+import (
+	"context"
+	"net"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+var _ N.Dialer = (*UnixDialer)(nil)
+
+// UnixDialer connects every TCP connection to a local unix socket, ignoring the destination.
+type UnixDialer struct {
+	dialer net.Dialer
+	path   string
+}
+
+func NewUnix(options option.DialerOptions) *UnixDialer {
+	var dialer net.Dialer
+	if options.ConnectTimeout != 0 {
+		dialer.Timeout = time.Duration(options.ConnectTimeout)
+	} else {
+		dialer.Timeout = C.TCPTimeout
+	}
+	return &UnixDialer{dialer, options.UnixSocket}
+}
+
+func (d *UnixDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	if N.NetworkName(network) != N.NetworkTCP {
+		return nil, E.New("network ", network, " is not supported on unix socket")
+	}
+	return d.dialer.DialContext(ctx, "unix", d.path)
+}
+
+func (d *UnixDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return nil, E.New("UDP is not supported on unix socket")
+}
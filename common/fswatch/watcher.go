@@ -0,0 +1,140 @@
+package fswatch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const watcherDelay = time.Second
+
+// Watcher watches the parent directories of the given files, so that
+// files replaced by rename or symlink swap (cert-manager, Kubernetes secrets)
+// are picked up as well as in-place writes. Events for other files in
+// those directories are ignored.
+type Watcher struct {
+	logger         log.Logger
+	callback       func()
+	watcher        *fsnotify.Watcher
+	names          []string
+	access         sync.Mutex
+	callbackAccess sync.Mutex
+	timer          *time.Timer
+	closed         bool
+}
+
+func New(logger log.Logger, paths []string, callback func()) (*Watcher, error) {
+	paths = common.FilterNotDefault(paths)
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	var directories []string
+	var names []string
+	for _, path := range paths {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			watcher.Close()
+			return nil, err
+		}
+		directory := filepath.Dir(absPath)
+		names = append(names, absPath)
+		if linkName := symlinkName(absPath); linkName != "" {
+			names = append(names, linkName)
+		}
+		if common.Contains(directories, directory) {
+			continue
+		}
+		err = watcher.Add(directory)
+		if err != nil {
+			watcher.Close()
+			return nil, E.Cause(err, "watch ", directory)
+		}
+		directories = append(directories, directory)
+	}
+	fileWatcher := &Watcher{
+		logger:   logger,
+		callback: callback,
+		watcher:  watcher,
+		names:    names,
+	}
+	go fileWatcher.loopUpdate()
+	return fileWatcher, nil
+}
+
+func (w *Watcher) loopUpdate() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod || !common.Contains(w.names, filepath.Clean(event.Name)) {
+				continue
+			}
+			w.schedule()
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			w.logger.Error(E.Cause(err, "fsnotify error"))
+		}
+	}
+}
+
+// schedule debounces bursts of events, e.g. a certificate and its key being
+// written one after another.
+func (w *Watcher) schedule() {
+	w.access.Lock()
+	defer w.access.Unlock()
+	if w.closed {
+		return
+	}
+	if w.timer != nil {
+		w.timer.Reset(watcherDelay)
+		return
+	}
+	w.timer = time.AfterFunc(watcherDelay, w.runCallback)
+}
+
+func (w *Watcher) runCallback() {
+	w.callbackAccess.Lock()
+	defer w.callbackAccess.Unlock()
+	w.callback()
+}
+
+// symlinkName returns the entry in the same directory that a relative symlink
+// resolves through, e.g. ..data for Kubernetes secret volumes, whose update
+// replaces that entry instead of the file itself.
+func symlinkName(path string) string {
+	target, err := os.Readlink(path)
+	if err != nil || filepath.IsAbs(target) {
+		return ""
+	}
+	firstElem := strings.Split(filepath.Clean(target), string(filepath.Separator))[0]
+	if firstElem == ".." {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(path), firstElem)
+}
+
+func (w *Watcher) Close() error {
+	w.access.Lock()
+	w.closed = true
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.access.Unlock()
+	return w.watcher.Close()
+}
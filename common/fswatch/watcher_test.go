@@ -0,0 +1,75 @@
+package fswatch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWatcher(t *testing.T, paths []string) chan struct{} {
+	done := make(chan struct{}, 1)
+	watcher, err := New(log.NewNOPFactory().Logger(), paths, func() {
+		select {
+		case done <- struct{}{}:
+		default:
+		}
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		watcher.Close()
+	})
+	return done
+}
+
+func requireCallback(t *testing.T, done chan struct{}, expected bool) {
+	select {
+	case <-done:
+		require.True(t, expected, "unexpected callback")
+	case <-time.After(watcherDelay + 500*time.Millisecond):
+		require.False(t, expected, "missing callback")
+	}
+}
+
+func TestWatcherFilter(t *testing.T) {
+	t.Parallel()
+	directory := t.TempDir()
+	path := filepath.Join(directory, "cert.pem")
+	require.NoError(t, os.WriteFile(path, []byte("a"), 0o644))
+	done := newTestWatcher(t, []string{path})
+
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "other.pem"), []byte("a"), 0o644))
+	requireCallback(t, done, false)
+
+	require.NoError(t, os.WriteFile(path, []byte("b"), 0o644))
+	requireCallback(t, done, true)
+
+	temporaryPath := filepath.Join(directory, "cert.pem.tmp")
+	require.NoError(t, os.WriteFile(temporaryPath, []byte("c"), 0o644))
+	require.NoError(t, os.Rename(temporaryPath, path))
+	requireCallback(t, done, true)
+}
+
+func TestWatcherSymlinkSwap(t *testing.T) {
+	t.Parallel()
+	directory := t.TempDir()
+	for _, version := range []string{"..v1", "..v2"} {
+		require.NoError(t, os.Mkdir(filepath.Join(directory, version), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(directory, version, "tls.crt"), []byte(version), 0o644))
+	}
+	require.NoError(t, os.Symlink("..v1", filepath.Join(directory, "..data")))
+	path := filepath.Join(directory, "tls.crt")
+	require.NoError(t, os.Symlink(filepath.Join("..data", "tls.crt"), path))
+	done := newTestWatcher(t, []string{path})
+
+	require.NoError(t, os.Symlink("..v2", filepath.Join(directory, "..data_tmp")))
+	require.NoError(t, os.Rename(filepath.Join(directory, "..data_tmp"), filepath.Join(directory, "..data")))
+	requireCallback(t, done, true)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "..v2", string(content))
+}
@@ -0,0 +1,166 @@
+package pac
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const (
+	ContentType = "application/x-ns-proxy-autoconfig"
+	WPADPath    = "/wpad.dat"
+	DefaultPath = "/proxy.pac"
+)
+
+const helpers = `function domainMatch(host, domains, suffixes) {
+	for (var i = 0; i < domains.length; i++) {
+		if (host == domains[i]) return true;
+	}
+	for (var i = 0; i < suffixes.length; i++) {
+		var suffix = suffixes[i];
+		if (host.length >= suffix.length && host.substring(host.length - suffix.length) == suffix) return true;
+	}
+	return false;
+}
+
+function keywordMatch(host, keywords) {
+	for (var i = 0; i < keywords.length; i++) {
+		if (host.indexOf(keywords[i]) != -1) return true;
+	}
+	return false;
+}
+
+function regexMatch(host, expressions) {
+	for (var i = 0; i < expressions.length; i++) {
+		try {
+			if (new RegExp(expressions[i]).test(host)) return true;
+		} catch (e) {
+		}
+	}
+	return false;
+}
+
+function ipv4Number(host) {
+	var parts = /^(\d+)\.(\d+)\.(\d+)\.(\d+)$/.exec(host);
+	if (!parts) return null;
+	return ((parts[1] * 256 + +parts[2]) * 256 + +parts[3]) * 256 + +parts[4];
+}
+
+function ipv6Hex(host) {
+	if (host.charAt(0) == "[" && host.charAt(host.length - 1) == "]") host = host.substring(1, host.length - 1);
+	if (host.indexOf(":") == -1) return null;
+	var halves = host.split("::");
+	if (halves.length > 2) return null;
+	var groups = [];
+	for (var i = 0; i < halves.length; i++) {
+		var parts = halves[i] ? halves[i].split(":") : [];
+		if (parts.length > 0 && parts[parts.length - 1].indexOf(".") != -1) {
+			var address = ipv4Number(parts[parts.length - 1]);
+			if (address == null) return null;
+			parts[parts.length - 1] = Math.floor(address / 65536).toString(16);
+			parts.push((address % 65536).toString(16));
+		}
+		groups.push(parts);
+	}
+	var parts = groups[0];
+	if (groups.length == 2) {
+		var missing = 8 - groups[0].length - groups[1].length;
+		if (missing < 1) return null;
+		for (var i = 0; i < missing; i++) parts.push("0");
+		parts = parts.concat(groups[1]);
+	}
+	if (parts.length != 8) return null;
+	var hex = "";
+	for (var i = 0; i < parts.length; i++) {
+		if (!/^[0-9a-f]{1,4}$/.test(parts[i])) return null;
+		hex += ("000" + parts[i]).slice(-4);
+	}
+	return hex;
+}
+
+// IPv4 addresses are compared as numbers, IPv6 addresses as fixed length hex strings.
+function ipMatch(host, ranges4, ranges6) {
+	var address = ipv4Number(host);
+	var ranges = ranges4;
+	if (address == null) {
+		address = ipv6Hex(host);
+		ranges = ranges6;
+	}
+	if (address == null) return false;
+	for (var i = 0; i < ranges.length; i++) {
+		if (address >= ranges[i][0] && address <= ranges[i][1]) return true;
+	}
+	return false;
+}
+
+function portMatch(port, ranges) {
+	for (var i = 0; i < ranges.length; i++) {
+		if (port >= ranges[i][0] && port <= ranges[i][1]) return true;
+	}
+	return false;
+}
+
+function urlPort(url) {
+	var parts = /^([a-z][a-z0-9+.-]*):\/\/(?:[^@\/]*@)?(?:\[[^\]]*\]|[^:\/]*)(?::(\d+))?/i.exec(url);
+	if (!parts) return 0;
+	if (parts[2]) return parseInt(parts[2], 10);
+	switch (parts[1].toLowerCase()) {
+	case "http":
+	case "ws":
+		return 80;
+	case "ftp":
+		return 21;
+	default:
+		return 443;
+	}
+}
+`
+
+// Generate builds a PAC file from the current route rules. Connections matching a rule
+// that routes to a direct outbound are sent DIRECT, everything else is sent to proxy.
+// Rules that cannot be evaluated from a URL may shadow the rules after them, so
+// everything from the first such rule on is sent to proxy and routed by sing-box.
+func Generate(router adapter.Router, metadata *adapter.InboundContext, proxy string) string {
+	var builder strings.Builder
+	builder.WriteString("// Generated by sing-box\n\n")
+	builder.WriteString(helpers)
+	builder.WriteString("\nfunction FindProxyForURL(url, host) {\n")
+	builder.WriteString("\thost = host.toLowerCase();\n")
+	builder.WriteString("\tvar port = urlPort(url);\n")
+	for i, rule := range router.Rules() {
+		description := strings.ReplaceAll(rule.String(), "\n", " ")
+		var (
+			condition string
+			loaded    bool
+		)
+		if pacRule, isPAC := rule.(adapter.PACRule); isPAC {
+			condition, loaded = pacRule.PACCondition(metadata)
+		}
+		if !loaded {
+			builder.WriteString("\t// rule[" + strconv.Itoa(i) + "] not supported in PAC: " + description + "\n")
+			builder.WriteString("\treturn \"" + proxy + "\";\n")
+			builder.WriteString("}\n")
+			return builder.String()
+		}
+		builder.WriteString("\t// rule[" + strconv.Itoa(i) + "] " + description + " => " + rule.Outbound() + "\n")
+		builder.WriteString("\tif (" + condition + ") return \"" + action(router, rule.Outbound(), proxy) + "\";\n")
+	}
+	defaultAction := proxy
+	if defaultOutbound := router.DefaultOutbound(N.NetworkTCP); defaultOutbound != nil && defaultOutbound.Type() == C.TypeDirect {
+		defaultAction = "DIRECT"
+	}
+	builder.WriteString("\treturn \"" + defaultAction + "\";\n")
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+func action(router adapter.Router, tag string, proxy string) string {
+	outbound, loaded := router.Outbound(tag)
+	if loaded && outbound.Type() == C.TypeDirect {
+		return "DIRECT"
+	}
+	return proxy
+}
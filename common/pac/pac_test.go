@@ -0,0 +1,169 @@
+package pac_test
+
+import (
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/json"
+	"github.com/sagernet/sing-box/common/pac"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/route"
+
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+const testProxy = "PROXY 127.0.0.1:8080"
+
+type testOutbound struct {
+	adapter.Outbound
+	tag          string
+	outboundType string
+}
+
+func (o *testOutbound) Tag() string {
+	return o.tag
+}
+
+func (o *testOutbound) Type() string {
+	return o.outboundType
+}
+
+type testRouter struct {
+	adapter.Router
+	rules     []adapter.Rule
+	outbounds []adapter.Outbound
+}
+
+func (r *testRouter) Rules() []adapter.Rule {
+	return r.rules
+}
+
+func (r *testRouter) Outbound(tag string) (adapter.Outbound, bool) {
+	for _, outbound := range r.outbounds {
+		if outbound.Tag() == tag {
+			return outbound, true
+		}
+	}
+	return nil, false
+}
+
+func (r *testRouter) DefaultOutbound(network string) adapter.Outbound {
+	return r.outbounds[0]
+}
+
+func newTestRouter(t *testing.T, defaultOutbound string, rules ...option.Rule) *testRouter {
+	router := &testRouter{}
+	for _, outbound := range []*testOutbound{{tag: "direct", outboundType: C.TypeDirect}, {tag: "proxy", outboundType: C.TypeSocks}} {
+		if outbound.tag == defaultOutbound {
+			router.outbounds = append([]adapter.Outbound{outbound}, router.outbounds...)
+		} else {
+			router.outbounds = append(router.outbounds, outbound)
+		}
+	}
+	for _, options := range rules {
+		rule, err := route.NewRule(router, log.NewNOPFactory().Logger(), options)
+		require.NoError(t, err)
+		router.rules = append(router.rules, rule)
+	}
+	return router
+}
+
+func checkGolden(t *testing.T, name string, content string) {
+	path := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		require.NoError(t, os.MkdirAll("testdata", 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return
+	}
+	expected, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), content, name)
+}
+
+// evaluatePAC runs FindProxyForURL for each url and host pair with node, if available.
+func evaluatePAC(t *testing.T, content string, requests [][2]string) []string {
+	nodePath, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not found in PATH")
+	}
+	requestsJSON, err := json.Marshal(requests)
+	require.NoError(t, err)
+	script := content + "\nconsole.log(JSON.stringify(" + string(requestsJSON) + ".map(function (r) { return FindProxyForURL(r[0], r[1]); })));\n"
+	output, err := exec.Command(nodePath, "-e", script).CombinedOutput()
+	require.NoError(t, err, string(output))
+	var results []string
+	require.NoError(t, json.Unmarshal(output, &results))
+	return results
+}
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, "direct",
+		option.Rule{DefaultOptions: option.DefaultRule{DomainSuffix: []string{"example.org"}, Outbound: "direct"}},
+		option.Rule{DefaultOptions: option.DefaultRule{IPCIDR: []string{"10.0.0.0/8", "2001:db8::/32"}, Outbound: "direct"}},
+		option.Rule{DefaultOptions: option.DefaultRule{IPCIDR: []string{"fc00::/7"}, Invert: true, Outbound: "proxy"}},
+		option.Rule{DefaultOptions: option.DefaultRule{Port: []uint16{8443}, Outbound: "direct"}},
+	)
+	content := pac.Generate(router, &adapter.InboundContext{}, testProxy)
+	checkGolden(t, "generate", content)
+	require.Equal(t, []string{
+		"DIRECT",
+		testProxy,
+		"DIRECT",
+		"DIRECT",
+		"DIRECT",
+		testProxy,
+		testProxy,
+		"DIRECT",
+		"DIRECT",
+	}, evaluatePAC(t, content, [][2]string{
+		{"https://www.example.org/", "WWW.Example.org"},
+		{"https://example.com/", "example.com"},
+		{"http://10.1.2.3/", "10.1.2.3"},
+		{"http://[2001:db8::1]/", "2001:db8::1"},
+		{"http://[2001:db8::1]/", "[2001:db8::1]"},
+		{"http://[2001:db9::1]/", "2001:db9::1"},
+		{"http://192.168.1.1/", "192.168.1.1"},
+		{"https://[fd00::1]:8443/", "fd00::1"},
+		{"http://[fd00::1]/", "fd00:0:0:0:0:0:0:1"},
+	}))
+}
+
+func TestGenerateUnsupportedRule(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, "direct",
+		option.Rule{DefaultOptions: option.DefaultRule{Domain: []string{"direct.example.com"}, Outbound: "direct"}},
+		option.Rule{DefaultOptions: option.DefaultRule{SourceIPCIDR: []string{"192.168.0.0/16"}, Outbound: "proxy"}},
+		option.Rule{DefaultOptions: option.DefaultRule{Domain: []string{"shadowed.example.com"}, Outbound: "direct"}},
+	)
+	content := pac.Generate(router, &adapter.InboundContext{}, testProxy)
+	checkGolden(t, "generate_unsupported", content)
+	require.NotContains(t, content, "shadowed.example.com")
+	require.Equal(t, []string{"DIRECT", testProxy, testProxy}, evaluatePAC(t, content, [][2]string{
+		{"https://direct.example.com/", "direct.example.com"},
+		{"https://shadowed.example.com/", "shadowed.example.com"},
+		{"https://example.com/", "example.com"},
+	}))
+}
+
+func TestGenerateDefaultProxy(t *testing.T) {
+	t.Parallel()
+	router := newTestRouter(t, "proxy",
+		option.Rule{DefaultOptions: option.DefaultRule{Domain: []string{"example.com"}, Outbound: "direct"}},
+	)
+	content := pac.Generate(router, &adapter.InboundContext{}, testProxy)
+	require.True(t, strings.HasSuffix(content, "\treturn \""+testProxy+"\";\n}\n"))
+	require.Equal(t, []string{"DIRECT", testProxy}, evaluatePAC(t, content, [][2]string{
+		{"https://example.com/", "example.com"},
+		{"https://example.org/", "example.org"},
+	}))
+}
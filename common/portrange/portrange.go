@@ -0,0 +1,43 @@
+package portrange
+
+import (
+	"strconv"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+var ErrBadPortRange = E.New("bad port range")
+
+// Parse expands a list of ports and port ranges in the form of start:end
+// into a list of distinct ports, keeping the order of first appearance.
+func Parse(portList []string) ([]uint16, error) {
+	var ports []uint16
+	seen := make(map[uint16]bool)
+	for _, portRange := range portList {
+		var start, end uint64
+		var err error
+		if subIndex := strings.Index(portRange, ":"); subIndex > 0 {
+			start, err = strconv.ParseUint(portRange[:subIndex], 10, 16)
+			if err == nil {
+				end, err = strconv.ParseUint(portRange[subIndex+1:], 10, 16)
+			}
+		} else {
+			start, err = strconv.ParseUint(portRange, 10, 16)
+			end = start
+		}
+		if err != nil {
+			return nil, E.Extend(ErrBadPortRange, portRange, ": ", err)
+		}
+		if start == 0 || start > end {
+			return nil, E.Extend(ErrBadPortRange, portRange)
+		}
+		for port := start; port <= end; port++ {
+			if !seen[uint16(port)] {
+				seen[uint16(port)] = true
+				ports = append(ports, uint16(port))
+			}
+		}
+	}
+	return ports, nil
+}
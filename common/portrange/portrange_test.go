@@ -0,0 +1,40 @@
+package portrange
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name     string
+		portList []string
+		expected []uint16
+		error    bool
+	}{
+		{name: "empty"},
+		{name: "single", portList: []string{"443"}, expected: []uint16{443}},
+		{name: "range", portList: []string{"1000:1003"}, expected: []uint16{1000, 1001, 1002, 1003}},
+		{name: "single port range", portList: []string{"8080:8080"}, expected: []uint16{8080}},
+		{name: "deduplicate", portList: []string{"3:5", "1:4", "443"}, expected: []uint16{3, 4, 5, 1, 2, 443}},
+		{name: "max port", portList: []string{"65534:65535"}, expected: []uint16{65534, 65535}},
+		{name: "zero", portList: []string{"0"}, error: true},
+		{name: "zero start", portList: []string{"0:10"}, error: true},
+		{name: "reversed", portList: []string{"10:5"}, error: true},
+		{name: "overflow", portList: []string{"65536"}, error: true},
+		{name: "missing end", portList: []string{"10:"}, error: true},
+		{name: "missing start", portList: []string{":10"}, error: true},
+		{name: "dash", portList: []string{"10-20"}, error: true},
+		{name: "not a number", portList: []string{"http"}, error: true},
+	} {
+		ports, err := Parse(testCase.portList)
+		if testCase.error {
+			require.ErrorIs(t, err, ErrBadPortRange, testCase.name)
+			continue
+		}
+		require.NoError(t, err, testCase.name)
+		require.Equal(t, testCase.expected, ports, testCase.name)
+	}
+}
@@ -2,11 +2,13 @@ package proxyproto
 
 import (
 	"context"
+	"io"
 	"net"
 	"net/netip"
 
 	"github.com/sagernet/sing-box/adapter"
 	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 
@@ -15,13 +17,26 @@ import (
 
 var _ N.Dialer = (*Dialer)(nil)
 
+// CheckVersion rejects header versions other than 1 and 2, 0 disables the header.
+func CheckVersion(version uint8) error {
+	if version > 2 {
+		return E.New("unknown proxy_protocol version: ", version, ", expected 1 or 2")
+	}
+	return nil
+}
+
 type Dialer struct {
 	N.Dialer
+	Version uint8
 }
 
 func (d *Dialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
 	switch N.NetworkName(network) {
 	case N.NetworkTCP:
+		err := CheckVersion(d.Version)
+		if err != nil {
+			return nil, err
+		}
 		conn, err := d.Dialer.DialContext(ctx, network, destination)
 		if err != nil {
 			return nil, err
@@ -34,17 +49,61 @@ func (d *Dialer) DialContext(ctx context.Context, network string, destination M.
 		if !source.IsValid() {
 			source = M.SocksaddrFromNet(conn.LocalAddr())
 		}
-		if destination.Addr.Is6() {
-			source = M.SocksaddrFrom(netip.AddrFrom16(source.Addr.As16()), source.Port)
+		if !destination.IsIP() {
+			destination = M.SocksaddrFromNet(conn.RemoteAddr())
 		}
-		h := proxyproto.HeaderProxyFromAddrs(1, source.TCPAddr(), destination.TCPAddr())
-		_, err = h.WriteTo(conn)
+		err = WriteHeader(conn, d.Version, source, destination, metadata)
 		if err != nil {
 			conn.Close()
-			return nil, E.Cause(err, "write proxy protocol header")
+			return nil, err
 		}
 		return conn, nil
 	default:
 		return d.Dialer.DialContext(ctx, network, destination)
 	}
 }
+
+// WriteHeader writes a PROXY protocol header for a TCP connection from source to destination.
+// An unspecified header is written if either address is not an IP address.
+// For version 2, TLVs received by the inbound are forwarded.
+func WriteHeader(writer io.Writer, version uint8, source M.Socksaddr, destination M.Socksaddr, metadata *adapter.InboundContext) error {
+	header := &proxyproto.Header{
+		Version:           version,
+		Command:           proxyproto.LOCAL,
+		TransportProtocol: proxyproto.UNSPEC,
+	}
+	source, destination = source.Unwrap(), destination.Unwrap()
+	if source.IsIP() && destination.IsIP() {
+		header.Command = proxyproto.PROXY
+		if source.Addr.Is4() && destination.Addr.Is4() {
+			header.TransportProtocol = proxyproto.TCPv4
+		} else {
+			header.TransportProtocol = proxyproto.TCPv6
+			source.Addr = netip.AddrFrom16(source.Addr.As16())
+			destination.Addr = netip.AddrFrom16(destination.Addr.As16())
+			if version == 1 && (source.Addr.Is4In6() || destination.Addr.Is4In6()) {
+				// the library formats mapped addresses as IPv4, which is invalid for TCP6
+				_, err := io.WriteString(writer, "PROXY TCP6 "+source.Addr.String()+" "+destination.Addr.String()+" "+F.ToString(source.Port)+" "+F.ToString(destination.Port)+"\r\n")
+				if err != nil {
+					return E.Cause(err, "write proxy protocol header")
+				}
+				return nil
+			}
+		}
+		header.SourceAddr, header.DestinationAddr = source.TCPAddr(), destination.TCPAddr()
+	}
+	if header.Version == 2 && metadata != nil && metadata.ProxyProtocol != nil && len(metadata.ProxyProtocol.RawTLVs) > 0 {
+		tlvs, err := proxyproto.SplitTLVs(metadata.ProxyProtocol.RawTLVs)
+		if err == nil {
+			err = header.SetTLVs(tlvs)
+		}
+		if err != nil {
+			return E.Cause(err, "forward proxy protocol TLVs")
+		}
+	}
+	_, err := header.WriteTo(writer)
+	if err != nil {
+		return E.Cause(err, "write proxy protocol header")
+	}
+	return nil
+}
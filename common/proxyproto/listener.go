@@ -4,11 +4,13 @@ import (
 	std_bufio "bufio"
 	"net"
 
+	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing/common/buf"
 	"github.com/sagernet/sing/common/bufio"
 	M "github.com/sagernet/sing/common/metadata"
 
 	"github.com/pires/go-proxyproto"
+	"github.com/pires/go-proxyproto/tlvparse"
 )
 
 type Listener struct {
@@ -35,14 +37,54 @@ func (l *Listener) Accept() (net.Conn, error) {
 		conn = bufio.NewCachedConn(conn, cache)
 	}
 	if header != nil {
-		return &bufio.AddrConn{Conn: conn, Metadata: M.Metadata{
-			Source:      M.SocksaddrFromNet(header.SourceAddr).Unwrap(),
-			Destination: M.SocksaddrFromNet(header.DestinationAddr).Unwrap(),
-		}}, nil
+		return &Conn{
+			AddrConn: &bufio.AddrConn{Conn: conn, Metadata: M.Metadata{
+				Source:      M.SocksaddrFromNet(header.SourceAddr).Unwrap(),
+				Destination: M.SocksaddrFromNet(header.DestinationAddr).Unwrap(),
+			}},
+			info: parseInfo(header),
+		}, nil
 	}
 	return conn, nil
 }
 
+type Conn struct {
+	*bufio.AddrConn
+	info *adapter.ProxyProtocolInfo
+}
+
+// Info returns the TLVs of the received header, or nil if there are none.
+func (c *Conn) Info() *adapter.ProxyProtocolInfo {
+	return c.info
+}
+
+func (c *Conn) Upstream() any {
+	return c.AddrConn
+}
+
+func parseInfo(header *proxyproto.Header) *adapter.ProxyProtocolInfo {
+	tlvs, err := header.TLVs()
+	if err != nil || len(tlvs) == 0 {
+		return nil
+	}
+	info := &adapter.ProxyProtocolInfo{}
+	for _, tlv := range tlvs {
+		switch tlv.Type {
+		case proxyproto.PP2_TYPE_AUTHORITY:
+			info.Authority = string(tlv.Value)
+		case proxyproto.PP2_TYPE_UNIQUE_ID:
+			info.UniqueID = string(tlv.Value)
+		}
+	}
+	if ssl, loaded := tlvparse.FindSSL(tlvs); loaded {
+		info.SSL = ssl.ClientSSL()
+		info.SSLVersion, _ = ssl.SSLVersion()
+		info.SSLCN, _ = ssl.ClientCN()
+	}
+	info.RawTLVs, _ = proxyproto.JoinTLVs(tlvs)
+	return info
+}
+
 var _ net.Error = (*Error)(nil)
 
 type Error struct {
@@ -0,0 +1,278 @@
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/pires/go-proxyproto"
+	"github.com/pires/go-proxyproto/tlvparse"
+	"github.com/stretchr/testify/require"
+)
+
+func testTLVs(t *testing.T) []proxyproto.TLV {
+	ssl, err := tlvparse.PP2SSL{
+		Client: tlvparse.PP2_BITFIELD_CLIENT_SSL | tlvparse.PP2_BITFIELD_CLIENT_CERT_CONN,
+		TLV: []proxyproto.TLV{
+			{Type: proxyproto.PP2_SUBTYPE_SSL_VERSION, Value: []byte("TLSv1.3")},
+			{Type: proxyproto.PP2_SUBTYPE_SSL_CN, Value: []byte("client.example.com")},
+		},
+	}.Marshal()
+	require.NoError(t, err)
+	return []proxyproto.TLV{
+		{Type: proxyproto.PP2_TYPE_AUTHORITY, Value: []byte("example.com")},
+		{Type: proxyproto.PP2_TYPE_UNIQUE_ID, Value: []byte("unique")},
+		ssl,
+		{Type: proxyproto.PP2_TYPE_MIN_CUSTOM, Value: []byte{1, 2, 3}},
+	}
+}
+
+func readHeader(t *testing.T, content []byte) *proxyproto.Header {
+	header, err := proxyproto.Read(bufio.NewReader(bytes.NewReader(content)))
+	require.NoError(t, err)
+	return header
+}
+
+func TestParseInfo(t *testing.T) {
+	t.Parallel()
+	header := proxyproto.HeaderProxyFromAddrs(2, M.ParseSocksaddr("192.168.0.1:1234").TCPAddr(), M.ParseSocksaddr("10.0.0.1:443").TCPAddr())
+	require.Nil(t, parseInfo(header))
+	require.NoError(t, header.SetTLVs(testTLVs(t)))
+	var buffer bytes.Buffer
+	_, err := header.WriteTo(&buffer)
+	require.NoError(t, err)
+	info := parseInfo(readHeader(t, buffer.Bytes()))
+	require.NotNil(t, info)
+	require.Equal(t, "example.com", info.Authority)
+	require.Equal(t, "unique", info.UniqueID)
+	require.True(t, info.SSL)
+	require.Equal(t, "TLSv1.3", info.SSLVersion)
+	require.Equal(t, "client.example.com", info.SSLCN)
+	tlvs, err := proxyproto.SplitTLVs(info.RawTLVs)
+	require.NoError(t, err)
+	require.Equal(t, testTLVs(t), tlvs)
+}
+
+func TestWriteHeader(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name        string
+		version     uint8
+		source      M.Socksaddr
+		destination M.Socksaddr
+		protocol    proxyproto.AddressFamilyAndProtocol
+		expected    [2]string
+	}{
+		{
+			name:        "v1 ipv4",
+			version:     1,
+			source:      M.ParseSocksaddr("192.168.0.1:1234"),
+			destination: M.ParseSocksaddr("10.0.0.1:443"),
+			protocol:    proxyproto.TCPv4,
+			expected:    [2]string{"192.168.0.1:1234", "10.0.0.1:443"},
+		},
+		{
+			name:        "v2 ipv6",
+			version:     2,
+			source:      M.ParseSocksaddr("[2001:db8::1]:1234"),
+			destination: M.ParseSocksaddr("[2001:db8::2]:443"),
+			protocol:    proxyproto.TCPv6,
+			expected:    [2]string{"[2001:db8::1]:1234", "[2001:db8::2]:443"},
+		},
+		{
+			name:        "v2 mixed",
+			version:     2,
+			source:      M.ParseSocksaddr("192.168.0.1:1234"),
+			destination: M.ParseSocksaddr("[2001:db8::2]:443"),
+			protocol:    proxyproto.TCPv6,
+			expected:    [2]string{"192.168.0.1:1234", "[2001:db8::2]:443"},
+		},
+		{
+			name:        "v1 mixed",
+			version:     1,
+			source:      M.ParseSocksaddr("[2001:db8::1]:1234"),
+			destination: M.ParseSocksaddr("10.0.0.1:443"),
+			protocol:    proxyproto.TCPv6,
+			expected:    [2]string{"[2001:db8::1]:1234", "10.0.0.1:443"},
+		},
+		{
+			name:        "v1 mapped",
+			version:     1,
+			source:      M.ParseSocksaddr("[::ffff:192.168.0.1]:1234"),
+			destination: M.ParseSocksaddr("10.0.0.1:443"),
+			protocol:    proxyproto.TCPv4,
+			expected:    [2]string{"192.168.0.1:1234", "10.0.0.1:443"},
+		},
+		{
+			name:        "v1 domain",
+			version:     1,
+			source:      M.ParseSocksaddr("192.168.0.1:1234"),
+			destination: M.ParseSocksaddr("example.com:443"),
+			protocol:    proxyproto.UNSPEC,
+		},
+		{
+			name:        "v2 domain",
+			version:     2,
+			source:      M.ParseSocksaddr("192.168.0.1:1234"),
+			destination: M.ParseSocksaddr("example.com:443"),
+			protocol:    proxyproto.UNSPEC,
+		},
+	} {
+		var buffer bytes.Buffer
+		require.NoError(t, WriteHeader(&buffer, testCase.version, testCase.source, testCase.destination, nil), testCase.name)
+		header := readHeader(t, buffer.Bytes())
+		require.Equal(t, testCase.version, header.Version, testCase.name)
+		require.Equal(t, testCase.protocol, header.TransportProtocol, testCase.name)
+		if testCase.protocol != proxyproto.UNSPEC {
+			require.Equal(t, testCase.expected[0], header.SourceAddr.String(), testCase.name)
+			require.Equal(t, testCase.expected[1], header.DestinationAddr.String(), testCase.name)
+		}
+	}
+}
+
+func TestWriteHeaderTLVs(t *testing.T) {
+	t.Parallel()
+	rawTLVs, err := proxyproto.JoinTLVs(testTLVs(t))
+	require.NoError(t, err)
+	metadata := &adapter.InboundContext{ProxyProtocol: &adapter.ProxyProtocolInfo{RawTLVs: rawTLVs}}
+	source, destination := M.ParseSocksaddr("192.168.0.1:1234"), M.ParseSocksaddr("10.0.0.1:443")
+
+	var buffer bytes.Buffer
+	require.NoError(t, WriteHeader(&buffer, 2, source, destination, metadata))
+	tlvs, err := readHeader(t, buffer.Bytes()).TLVs()
+	require.NoError(t, err)
+	require.Equal(t, testTLVs(t), tlvs)
+
+	// version 1 headers have no TLVs
+	buffer.Reset()
+	require.NoError(t, WriteHeader(&buffer, 1, source, destination, metadata))
+	require.Equal(t, "PROXY TCP4 192.168.0.1 10.0.0.1 1234 443\r\n", buffer.String())
+
+	buffer.Reset()
+	metadata.ProxyProtocol.RawTLVs = []byte{1, 0}
+	require.ErrorContains(t, WriteHeader(&buffer, 2, source, destination, metadata), "forward proxy protocol TLVs")
+	require.Zero(t, buffer.Len())
+}
+
+// TestListenerForward receives a header with TLVs and forwards it through Dialer.
+func TestListenerForward(t *testing.T) {
+	t.Parallel()
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	listener := &Listener{Listener: tcpListener}
+	defer listener.Close()
+	go func() {
+		conn, err := net.Dial("tcp", tcpListener.Addr().String())
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		header := proxyproto.HeaderProxyFromAddrs(2, M.ParseSocksaddr("192.168.0.1:1234").TCPAddr(), M.ParseSocksaddr("10.0.0.1:443").TCPAddr())
+		header.SetTLVs(testTLVs(t))
+		header.WriteTo(conn)
+		conn.Write([]byte("payload"))
+		io.Copy(io.Discard, conn)
+	}()
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	proxyConn, isProxyConn := conn.(*Conn)
+	require.True(t, isProxyConn)
+	require.Equal(t, "192.168.0.1:1234", proxyConn.RemoteAddr().String())
+	require.Equal(t, "10.0.0.1:443", proxyConn.LocalAddr().String())
+	info := proxyConn.Info()
+	require.NotNil(t, info)
+	require.Equal(t, "example.com", info.Authority)
+	payload := make([]byte, 7)
+	_, err = io.ReadFull(conn, payload)
+	require.NoError(t, err)
+	require.Equal(t, "payload", string(payload))
+
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	dialer := &Dialer{Dialer: &testDialer{clientConn}, Version: 2}
+	ctx := adapter.WithContext(context.Background(), &adapter.InboundContext{
+		Source:        M.SocksaddrFromNet(proxyConn.RemoteAddr()),
+		ProxyProtocol: info,
+	})
+	go func() {
+		forwardConn, err := dialer.DialContext(ctx, N.NetworkTCP, M.ParseSocksaddr("10.0.0.2:443"))
+		if err == nil {
+			forwardConn.Write([]byte("payload"))
+			forwardConn.Close()
+		}
+	}()
+	reader := bufio.NewReader(serverConn)
+	header, err := proxyproto.Read(reader)
+	require.NoError(t, err)
+	require.Equal(t, "192.168.0.1:1234", header.SourceAddr.String())
+	require.Equal(t, "10.0.0.2:443", header.DestinationAddr.String())
+	tlvs, err := header.TLVs()
+	require.NoError(t, err)
+	require.Equal(t, testTLVs(t), tlvs)
+	forwarded, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, "payload", string(forwarded))
+}
+
+func TestListenerNoHeader(t *testing.T) {
+	t.Parallel()
+	for _, acceptNoHeader := range []bool{false, true} {
+		tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		listener := &Listener{Listener: tcpListener, AcceptNoHeader: acceptNoHeader}
+		go func() {
+			conn, err := net.Dial("tcp", tcpListener.Addr().String())
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			conn.Write([]byte("GET / HTTP/1.1\r\n\r\n"))
+			io.Copy(io.Discard, conn)
+		}()
+		conn, err := listener.Accept()
+		if !acceptNoHeader {
+			require.Error(t, err)
+			var proxyErr *Error
+			require.ErrorAs(t, err, &proxyErr)
+			require.True(t, proxyErr.Temporary())
+			listener.Close()
+			continue
+		}
+		require.NoError(t, err)
+		_, isProxyConn := conn.(*Conn)
+		require.False(t, isProxyConn)
+		line, err := bufio.NewReader(conn).ReadString('\n')
+		require.NoError(t, err)
+		require.Equal(t, "GET / HTTP/1.1\r\n", line)
+		conn.Close()
+		listener.Close()
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	t.Parallel()
+	for _, version := range []uint8{0, 1, 2} {
+		require.NoError(t, CheckVersion(version))
+	}
+	require.ErrorContains(t, CheckVersion(3), "unknown proxy_protocol version")
+}
+
+type testDialer struct {
+	conn net.Conn
+}
+
+func (d *testDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	return d.conn, nil
+}
+
+func (d *testDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return nil, net.ErrClosed
+}
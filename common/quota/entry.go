@@ -0,0 +1,201 @@
+package quota
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/ratelimit"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/atomic"
+)
+
+type entry struct {
+	manager  *Manager
+	name     string
+	limit    int64
+	period   string
+	resetDay int
+	action   string
+	throttle *ratelimit.Limiter
+
+	access      sync.Mutex
+	periodStart time.Time
+	periodEnd   atomic.Int64
+	connections map[*connTracker]struct{}
+	upload      atomic.Int64
+	download    atomic.Int64
+	exceeded    atomic.Bool
+	changed     atomic.Bool
+}
+
+func newEntry(manager *Manager, name string, options option.QuotaOptions) *entry {
+	action := options.Action
+	if action == "" {
+		action = C.QuotaActionBlock
+	}
+	resetDay := options.ResetDay
+	if resetDay == 0 {
+		resetDay = 1
+	}
+	var throttle *ratelimit.Limiter
+	if action == C.QuotaActionThrottle {
+		throttle = ratelimit.NewLimiter(uint64(options.ThrottleRate))
+	}
+	return &entry{
+		manager:     manager,
+		name:        name,
+		limit:       int64(options.Limit),
+		period:      options.Period,
+		resetDay:    resetDay,
+		action:      action,
+		throttle:    throttle,
+		connections: make(map[*connTracker]struct{}),
+	}
+}
+
+func (e *entry) calculatePeriodStart(now time.Time) time.Time {
+	year, month, day := now.Date()
+	switch e.period {
+	case C.QuotaPeriodDaily:
+		return time.Date(year, month, day, 0, 0, 0, 0, now.Location())
+	case C.QuotaPeriodMonthly:
+		if day < e.resetDay {
+			month--
+		}
+		return time.Date(year, month, e.resetDay, 0, 0, 0, 0, now.Location())
+	default:
+		return e.periodStart
+	}
+}
+
+// nextPeriodStart returns the start of the period after periodStart, or zero if usage is never reset.
+func (e *entry) nextPeriodStart(periodStart time.Time) time.Time {
+	year, month, day := periodStart.Date()
+	switch e.period {
+	case C.QuotaPeriodDaily:
+		return time.Date(year, month, day+1, 0, 0, 0, 0, periodStart.Location())
+	case C.QuotaPeriodMonthly:
+		return time.Date(year, month+1, e.resetDay, 0, 0, 0, 0, periodStart.Location())
+	default:
+		return time.Time{}
+	}
+}
+
+func (e *entry) setPeriodStart(periodStart time.Time) {
+	e.periodStart = periodStart
+	if periodEnd := e.nextPeriodStart(periodStart); !periodEnd.IsZero() {
+		e.periodEnd.Store(periodEnd.UnixNano())
+	} else {
+		e.periodEnd.Store(0)
+	}
+}
+
+// checkPeriod resets the usage if now has passed the end of the current period.
+func (e *entry) checkPeriod(now time.Time) {
+	periodEnd := e.periodEnd.Load()
+	if periodEnd == 0 || now.UnixNano() < periodEnd {
+		return
+	}
+	e.access.Lock()
+	defer e.access.Unlock()
+	e.resetPeriod(now)
+}
+
+func (e *entry) checkCurrentPeriod() {
+	if e.manager.timeFunc != nil {
+		e.checkPeriod(e.manager.timeFunc())
+	}
+}
+
+func (e *entry) resetPeriod(now time.Time) {
+	periodStart := e.calculatePeriodStart(now)
+	if !periodStart.After(e.periodStart) {
+		return
+	}
+	e.setPeriodStart(periodStart)
+	e.upload.Store(0)
+	e.download.Store(0)
+	if e.exceeded.Swap(false) {
+		e.manager.logger.Info("quota reset for ", e.name)
+	}
+	e.changed.Store(true)
+}
+
+func (e *entry) load(usage *adapter.QuotaUsage, now time.Time) {
+	e.access.Lock()
+	defer e.access.Unlock()
+	if usage != nil {
+		e.periodStart = usage.PeriodStart
+	}
+	periodStart := e.calculatePeriodStart(now)
+	if usage != nil && periodStart.Equal(usage.PeriodStart) {
+		e.upload.Store(int64(usage.Upload))
+		e.download.Store(int64(usage.Download))
+		e.setPeriodStart(periodStart)
+	} else if periodStart.IsZero() {
+		e.setPeriodStart(now)
+	} else {
+		e.setPeriodStart(periodStart)
+	}
+	if e.upload.Load()+e.download.Load() >= e.limit {
+		e.exceeded.Store(true)
+		e.manager.logger.Warn("quota exceeded for ", e.name)
+	}
+}
+
+func (e *entry) snapshot(now time.Time) (*adapter.QuotaUsage, bool) {
+	e.access.Lock()
+	defer e.access.Unlock()
+	e.resetPeriod(now)
+	changed := e.changed.Swap(false)
+	return &adapter.QuotaUsage{
+		PeriodStart: e.periodStart,
+		Upload:      uint64(e.upload.Load()),
+		Download:    uint64(e.download.Load()),
+	}, changed
+}
+
+func (e *entry) join(tracker *connTracker) {
+	e.access.Lock()
+	e.connections[tracker] = struct{}{}
+	e.access.Unlock()
+}
+
+func (e *entry) leave(tracker *connTracker) {
+	e.access.Lock()
+	delete(e.connections, tracker)
+	e.access.Unlock()
+}
+
+func (e *entry) uploadCounter(n int64) {
+	e.checkCurrentPeriod()
+	e.count(e.upload.Add(n) + e.download.Load())
+}
+
+func (e *entry) downloadCounter(n int64) {
+	e.checkCurrentPeriod()
+	e.count(e.upload.Load() + e.download.Add(n))
+}
+
+func (e *entry) count(total int64) {
+	e.changed.Store(true)
+	if total < e.limit || !e.exceeded.CompareAndSwap(false, true) {
+		return
+	}
+	e.manager.logger.Warn("quota exceeded for ", e.name)
+	if e.action != C.QuotaActionClose {
+		return
+	}
+	e.access.Lock()
+	connections := make([]*connTracker, 0, len(e.connections))
+	for tracker := range e.connections {
+		connections = append(connections, tracker)
+	}
+	e.access.Unlock()
+	for _, tracker := range connections {
+		common.Close(tracker.closer)
+	}
+}
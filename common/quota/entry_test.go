@@ -0,0 +1,188 @@
+package quota
+
+import (
+	"testing"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestManager(t *testing.T, options option.QuotaOptions, now *time.Time) *Manager {
+	manager, err := NewManager(nil, log.NewNOPFactory().Logger(), []option.QuotaOptions{options})
+	require.NoError(t, err)
+	manager.timeFunc = func() time.Time {
+		return *now
+	}
+	for _, quotaEntry := range manager.entries {
+		quotaEntry.load(nil, *now)
+	}
+	return manager
+}
+
+func TestCalculatePeriodStart(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name     string
+		period   string
+		resetDay int
+		now      time.Time
+		start    time.Time
+		next     time.Time
+	}{
+		{
+			name:   "daily",
+			period: C.QuotaPeriodDaily,
+			now:    time.Date(2024, 3, 10, 15, 4, 5, 0, time.UTC),
+			start:  time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC),
+			next:   time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:   "daily end of year",
+			period: C.QuotaPeriodDaily,
+			now:    time.Date(2024, 12, 31, 23, 59, 59, 0, time.UTC),
+			start:  time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC),
+			next:   time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "monthly after reset day",
+			period:   C.QuotaPeriodMonthly,
+			resetDay: 15,
+			now:      time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC),
+			start:    time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC),
+			next:     time.Date(2024, 4, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "monthly before reset day",
+			period:   C.QuotaPeriodMonthly,
+			resetDay: 15,
+			now:      time.Date(2024, 3, 14, 23, 0, 0, 0, time.UTC),
+			start:    time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC),
+			next:     time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "monthly year rollover",
+			period:   C.QuotaPeriodMonthly,
+			resetDay: 15,
+			now:      time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+			start:    time.Date(2023, 12, 15, 0, 0, 0, 0, time.UTC),
+			next:     time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "monthly december",
+			period:   C.QuotaPeriodMonthly,
+			resetDay: 1,
+			now:      time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC),
+			start:    time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC),
+			next:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			quotaEntry := &entry{period: testCase.period, resetDay: testCase.resetDay}
+			start := quotaEntry.calculatePeriodStart(testCase.now)
+			require.Equal(t, testCase.start, start)
+			require.Equal(t, testCase.next, quotaEntry.nextPeriodStart(start))
+		})
+	}
+}
+
+type testCloser struct {
+	closed bool
+}
+
+func (c *testCloser) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestQuotaBlock(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	manager := newTestManager(t, option.QuotaOptions{
+		Limit:  100,
+		Period: C.QuotaPeriodDaily,
+		Users:  []string{"user"},
+	}, &now)
+	quotaEntry := manager.userEntries["user"]
+	require.NoError(t, manager.checkEntries([]*entry{quotaEntry}))
+	quotaEntry.uploadCounter(60)
+	quotaEntry.downloadCounter(40)
+	require.True(t, quotaEntry.exceeded.Load())
+	require.Error(t, manager.checkEntries([]*entry{quotaEntry}))
+
+	now = time.Date(2024, 3, 11, 0, 0, 1, 0, time.UTC)
+	require.NoError(t, manager.checkEntries([]*entry{quotaEntry}))
+	require.Zero(t, quotaEntry.upload.Load()+quotaEntry.download.Load())
+}
+
+func TestQuotaResetOnCount(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	manager := newTestManager(t, option.QuotaOptions{
+		Limit:  100,
+		Period: C.QuotaPeriodDaily,
+		Users:  []string{"user"},
+	}, &now)
+	quotaEntry := manager.userEntries["user"]
+	quotaEntry.uploadCounter(100)
+	require.True(t, quotaEntry.exceeded.Load())
+
+	now = now.Add(24 * time.Hour)
+	quotaEntry.downloadCounter(10)
+	require.False(t, quotaEntry.exceeded.Load())
+	require.Equal(t, int64(10), quotaEntry.download.Load())
+	require.Zero(t, quotaEntry.upload.Load())
+	usage, changed := quotaEntry.snapshot(now)
+	require.True(t, changed)
+	require.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), usage.PeriodStart)
+}
+
+func TestQuotaClose(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	manager := newTestManager(t, option.QuotaOptions{
+		Limit:    100,
+		Action:   C.QuotaActionClose,
+		Inbounds: []string{"in"},
+	}, &now)
+	quotaEntry := manager.inboundEntry["in"]
+	closer := &testCloser{}
+	tracker := &connTracker{closer: closer, entries: []*entry{quotaEntry}}
+	quotaEntry.join(tracker)
+	quotaEntry.uploadCounter(99)
+	require.False(t, closer.closed)
+	quotaEntry.uploadCounter(1)
+	require.True(t, closer.closed)
+	tracker.Leave()
+	require.Empty(t, quotaEntry.connections)
+
+	now = now.Add(365 * 24 * time.Hour)
+	require.Error(t, manager.checkEntries([]*entry{quotaEntry}))
+}
+
+func TestQuotaThrottle(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	manager := newTestManager(t, option.QuotaOptions{
+		Limit:        100,
+		Action:       C.QuotaActionThrottle,
+		ThrottleRate: 1000,
+		Users:        []string{"user"},
+	}, &now)
+	quotaEntry := manager.userEntries["user"]
+	connThrottle := newThrottle([]*entry{quotaEntry})
+	require.Zero(t, connThrottle.reserve(1000))
+	require.Zero(t, connThrottle.reserve(0))
+
+	quotaEntry.uploadCounter(100)
+	require.Zero(t, connThrottle.reserve(500))
+	require.Zero(t, connThrottle.reserve(500))
+	delay := connThrottle.reserve(500)
+	require.Greater(t, delay, 400*time.Millisecond)
+	require.LessOrEqual(t, delay, 500*time.Millisecond)
+}
@@ -0,0 +1,245 @@
+package quota
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const saveInterval = time.Minute
+
+var _ adapter.Service = (*Manager)(nil)
+
+type Manager struct {
+	router       adapter.Router
+	logger       log.ContextLogger
+	entries      []*entry
+	userEntries  map[string]*entry
+	inboundEntry map[string]*entry
+	storage      adapter.QuotaStorage
+	ticker       *time.Ticker
+	done         chan struct{}
+	closeOnce    sync.Once
+	timeFunc     func() time.Time
+}
+
+func NewManager(router adapter.Router, logger log.ContextLogger, options []option.QuotaOptions) (*Manager, error) {
+	manager := &Manager{
+		router:       router,
+		logger:       logger,
+		userEntries:  make(map[string]*entry),
+		inboundEntry: make(map[string]*entry),
+		done:         make(chan struct{}),
+	}
+	for i, quotaOptions := range options {
+		if quotaOptions.Limit <= 0 {
+			return nil, E.New("parse quota[", i, "]: missing limit")
+		}
+		if len(quotaOptions.Users) == 0 && len(quotaOptions.Inbounds) == 0 {
+			return nil, E.New("parse quota[", i, "]: missing users or inbounds")
+		}
+		switch quotaOptions.Period {
+		case "", C.QuotaPeriodDaily:
+			if quotaOptions.ResetDay != 0 {
+				return nil, E.New("parse quota[", i, "]: reset_day is only available for monthly period")
+			}
+		case C.QuotaPeriodMonthly:
+			if quotaOptions.ResetDay < 0 || quotaOptions.ResetDay > 28 {
+				return nil, E.New("parse quota[", i, "]: reset_day must be between 1 and 28")
+			}
+		default:
+			return nil, E.New("parse quota[", i, "]: unknown period: ", quotaOptions.Period)
+		}
+		switch quotaOptions.Action {
+		case "", C.QuotaActionBlock, C.QuotaActionClose:
+		case C.QuotaActionThrottle:
+			if quotaOptions.ThrottleRate <= 0 {
+				return nil, E.New("parse quota[", i, "]: missing throttle_rate")
+			}
+		default:
+			return nil, E.New("parse quota[", i, "]: unknown action: ", quotaOptions.Action)
+		}
+		for _, user := range quotaOptions.Users {
+			if manager.userEntries[user] != nil {
+				return nil, E.New("parse quota[", i, "]: duplicate user: ", user)
+			}
+			quotaEntry := newEntry(manager, "user>>>"+user, quotaOptions)
+			manager.userEntries[user] = quotaEntry
+			manager.entries = append(manager.entries, quotaEntry)
+		}
+		for _, inbound := range quotaOptions.Inbounds {
+			if manager.inboundEntry[inbound] != nil {
+				return nil, E.New("parse quota[", i, "]: duplicate inbound: ", inbound)
+			}
+			quotaEntry := newEntry(manager, "inbound>>>"+inbound, quotaOptions)
+			manager.inboundEntry[inbound] = quotaEntry
+			manager.entries = append(manager.entries, quotaEntry)
+		}
+	}
+	return manager, nil
+}
+
+func (m *Manager) Start() error {
+	if clashServer := m.router.ClashServer(); clashServer != nil && clashServer.StoreQuota() {
+		if cacheFile := clashServer.CacheFile(); cacheFile != nil {
+			m.storage = cacheFile
+		}
+	}
+	m.timeFunc = m.router.TimeFunc()
+	if m.timeFunc == nil {
+		m.timeFunc = time.Now
+	}
+	now := m.timeFunc()
+	for _, quotaEntry := range m.entries {
+		var usage *adapter.QuotaUsage
+		if m.storage != nil {
+			usage = m.storage.LoadQuotaUsage(quotaEntry.name)
+		}
+		quotaEntry.load(usage, now)
+	}
+	m.ticker = time.NewTicker(saveInterval)
+	go m.loopSave()
+	return nil
+}
+
+func (m *Manager) Close() error {
+	var err error
+	m.closeOnce.Do(func() {
+		if m.ticker != nil {
+			m.ticker.Stop()
+			close(m.done)
+		}
+		err = m.save()
+	})
+	return err
+}
+
+func (m *Manager) loopSave() {
+	for {
+		select {
+		case <-m.ticker.C:
+			err := m.save()
+			if err != nil {
+				m.logger.Error("save quota usage: ", err)
+			}
+		case <-m.done:
+			return
+		}
+	}
+}
+
+func (m *Manager) save() error {
+	if m.timeFunc == nil {
+		return nil
+	}
+	now := m.timeFunc()
+	var err error
+	for _, quotaEntry := range m.entries {
+		usage, changed := quotaEntry.snapshot(now)
+		if !changed || m.storage == nil {
+			continue
+		}
+		err = E.Append(err, m.storage.StoreQuotaUsage(quotaEntry.name, usage), func(err error) error {
+			return E.Cause(err, "store ", quotaEntry.name)
+		})
+	}
+	return err
+}
+
+func (m *Manager) matchEntries(metadata adapter.InboundContext) []*entry {
+	var entries []*entry
+	if metadata.User != "" {
+		if quotaEntry := m.userEntries[metadata.User]; quotaEntry != nil {
+			entries = append(entries, quotaEntry)
+		}
+	}
+	if metadata.Inbound != "" {
+		if quotaEntry := m.inboundEntry[metadata.Inbound]; quotaEntry != nil {
+			entries = append(entries, quotaEntry)
+		}
+	}
+	return entries
+}
+
+func (m *Manager) checkEntries(entries []*entry) error {
+	for _, quotaEntry := range entries {
+		quotaEntry.checkCurrentPeriod()
+		if quotaEntry.exceeded.Load() && quotaEntry.action != C.QuotaActionThrottle {
+			return E.New("quota exceeded for ", quotaEntry.name)
+		}
+	}
+	return nil
+}
+
+func (m *Manager) RoutedConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) (net.Conn, adapter.Tracker, error) {
+	entries := m.matchEntries(metadata)
+	if len(entries) == 0 {
+		return conn, (*connTracker)(nil), nil
+	}
+	err := m.checkEntries(entries)
+	if err != nil {
+		return nil, nil, err
+	}
+	tracker := &connTracker{closer: conn, entries: entries}
+	var readCounter, writeCounter []N.CountFunc
+	for _, quotaEntry := range entries {
+		quotaEntry.join(tracker)
+		readCounter = append(readCounter, quotaEntry.uploadCounter)
+		writeCounter = append(writeCounter, quotaEntry.downloadCounter)
+	}
+	conn = bufio.NewCounterConn(conn, readCounter, writeCounter)
+	if connThrottle := newThrottle(entries); connThrottle != nil {
+		conn = &throttleConn{bufio.NewExtendedConn(conn), connThrottle}
+	}
+	return conn, tracker, nil
+}
+
+func (m *Manager) RoutedPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) (N.PacketConn, adapter.Tracker, error) {
+	entries := m.matchEntries(metadata)
+	if len(entries) == 0 {
+		return conn, (*connTracker)(nil), nil
+	}
+	err := m.checkEntries(entries)
+	if err != nil {
+		return nil, nil, err
+	}
+	tracker := &connTracker{closer: conn, entries: entries}
+	var readCounter, writeCounter []N.CountFunc
+	for _, quotaEntry := range entries {
+		quotaEntry.join(tracker)
+		readCounter = append(readCounter, quotaEntry.uploadCounter)
+		writeCounter = append(writeCounter, quotaEntry.downloadCounter)
+	}
+	conn = bufio.NewCounterPacketConn(conn, readCounter, writeCounter)
+	if connThrottle := newThrottle(entries); connThrottle != nil {
+		conn = &throttlePacketConn{conn, connThrottle}
+	}
+	return conn, tracker, nil
+}
+
+var _ adapter.Tracker = (*connTracker)(nil)
+
+type connTracker struct {
+	closer  io.Closer
+	entries []*entry
+}
+
+func (t *connTracker) Leave() {
+	if t == nil {
+		return
+	}
+	for _, quotaEntry := range t.entries {
+		quotaEntry.leave(t)
+	}
+}
@@ -0,0 +1,104 @@
+package quota
+
+import (
+	"time"
+
+	"github.com/sagernet/sing/common/buf"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// throttle paces a connection to the throttle limiters of its exceeded entries.
+type throttle struct {
+	entries []*entry
+}
+
+func newThrottle(entries []*entry) *throttle {
+	var throttled []*entry
+	for _, quotaEntry := range entries {
+		if quotaEntry.throttle != nil {
+			throttled = append(throttled, quotaEntry)
+		}
+	}
+	if len(throttled) == 0 {
+		return nil
+	}
+	return &throttle{throttled}
+}
+
+// reserve books n bytes and returns how long to wait before transferring them,
+// zero bytes only waits for the transfers booked before.
+func (t *throttle) reserve(n int) time.Duration {
+	var delay time.Duration
+	for _, quotaEntry := range t.entries {
+		if !quotaEntry.exceeded.Load() {
+			continue
+		}
+		if entryDelay := quotaEntry.throttle.Reserve(int64(n)); entryDelay > delay {
+			delay = entryDelay
+		}
+	}
+	return delay
+}
+
+func (t *throttle) wait(n int) {
+	if delay := t.reserve(n); delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+// throttleConn delays reads and writes once a throttle quota is exceeded: writes
+// wait for their own size, reads wait for the data read before them.
+type throttleConn struct {
+	N.ExtendedConn
+	throttle *throttle
+}
+
+func (c *throttleConn) Read(p []byte) (n int, err error) {
+	c.throttle.wait(0)
+	n, err = c.ExtendedConn.Read(p)
+	c.throttle.reserve(n)
+	return
+}
+
+func (c *throttleConn) ReadBuffer(buffer *buf.Buffer) error {
+	c.throttle.wait(0)
+	err := c.ExtendedConn.ReadBuffer(buffer)
+	c.throttle.reserve(buffer.Len())
+	return err
+}
+
+func (c *throttleConn) Write(p []byte) (n int, err error) {
+	c.throttle.wait(len(p))
+	return c.ExtendedConn.Write(p)
+}
+
+func (c *throttleConn) WriteBuffer(buffer *buf.Buffer) error {
+	c.throttle.wait(buffer.Len())
+	return c.ExtendedConn.WriteBuffer(buffer)
+}
+
+func (c *throttleConn) Upstream() any {
+	return c.ExtendedConn
+}
+
+type throttlePacketConn struct {
+	N.PacketConn
+	throttle *throttle
+}
+
+func (c *throttlePacketConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	c.throttle.wait(0)
+	destination, err = c.PacketConn.ReadPacket(buffer)
+	c.throttle.reserve(buffer.Len())
+	return
+}
+
+func (c *throttlePacketConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	c.throttle.wait(buffer.Len())
+	return c.PacketConn.WritePacket(buffer, destination)
+}
+
+func (c *throttlePacketConn) Upstream() any {
+	return c.PacketConn
+}
@@ -0,0 +1,85 @@
+package ratelimit
+
+import (
+	"time"
+
+	"github.com/sagernet/sing/common/buf"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+func wait(limiters []*Limiter, n int) {
+	var delay time.Duration
+	for _, limiter := range limiters {
+		if limiterDelay := limiter.Reserve(int64(n)); limiterDelay > delay {
+			delay = limiterDelay
+		}
+	}
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+}
+
+func reserve(limiters []*Limiter, n int) {
+	for _, limiter := range limiters {
+		limiter.Reserve(int64(n))
+	}
+}
+
+// limitedConn waits before writing, and before reading until the data read before is paid off,
+// since the size of a read is only known after it returns.
+type limitedConn struct {
+	N.ExtendedConn
+	readLimiters  []*Limiter
+	writeLimiters []*Limiter
+}
+
+func (c *limitedConn) Read(p []byte) (n int, err error) {
+	wait(c.readLimiters, 0)
+	n, err = c.ExtendedConn.Read(p)
+	reserve(c.readLimiters, n)
+	return
+}
+
+func (c *limitedConn) ReadBuffer(buffer *buf.Buffer) error {
+	wait(c.readLimiters, 0)
+	err := c.ExtendedConn.ReadBuffer(buffer)
+	reserve(c.readLimiters, buffer.Len())
+	return err
+}
+
+func (c *limitedConn) Write(p []byte) (n int, err error) {
+	wait(c.writeLimiters, len(p))
+	return c.ExtendedConn.Write(p)
+}
+
+func (c *limitedConn) WriteBuffer(buffer *buf.Buffer) error {
+	wait(c.writeLimiters, buffer.Len())
+	return c.ExtendedConn.WriteBuffer(buffer)
+}
+
+func (c *limitedConn) Upstream() any {
+	return c.ExtendedConn
+}
+
+type limitedPacketConn struct {
+	N.PacketConn
+	readLimiters  []*Limiter
+	writeLimiters []*Limiter
+}
+
+func (c *limitedPacketConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	wait(c.readLimiters, 0)
+	destination, err = c.PacketConn.ReadPacket(buffer)
+	reserve(c.readLimiters, buffer.Len())
+	return
+}
+
+func (c *limitedPacketConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	wait(c.writeLimiters, buffer.Len())
+	return c.PacketConn.WritePacket(buffer, destination)
+}
+
+func (c *limitedPacketConn) Upstream() any {
+	return c.PacketConn
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Limiter is a token bucket that may be overdrawn, since the size of a read is
+// only known after it returns: the next caller then waits until the debt is paid off.
+type Limiter struct {
+	rate     int64
+	burst    float64
+	timeFunc func() time.Time
+	access   sync.Mutex
+	tokens   float64
+	last     time.Time
+}
+
+func NewLimiter(rate uint64) *Limiter {
+	return newLimiter(rate, time.Now)
+}
+
+func newLimiter(rate uint64, timeFunc func() time.Time) *Limiter {
+	return &Limiter{
+		rate:     int64(rate),
+		burst:    float64(rate),
+		timeFunc: timeFunc,
+		tokens:   float64(rate),
+		last:     timeFunc(),
+	}
+}
+
+func (l *Limiter) Rate() uint64 {
+	return uint64(l.rate)
+}
+
+func (l *Limiter) Reserve(n int64) time.Duration {
+	l.access.Lock()
+	defer l.access.Unlock()
+	now := l.timeFunc()
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens += elapsed.Seconds() * float64(l.rate)
+		l.last = now
+	}
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / float64(l.rate) * float64(time.Second))
+}
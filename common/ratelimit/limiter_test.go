@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing/common/bufio"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time {
+	return c.now
+}
+
+func (c *testClock) Advance(duration time.Duration) {
+	c.now = c.now.Add(duration)
+}
+
+func TestLimiterReserve(t *testing.T) {
+	t.Parallel()
+	clock := &testClock{now: time.Unix(0, 0)}
+	limiter := newLimiter(1000, clock.Now)
+	require.Zero(t, limiter.Reserve(1000))
+	require.Equal(t, 500*time.Millisecond, limiter.Reserve(500))
+	require.Equal(t, time.Second, limiter.Reserve(500))
+	clock.Advance(time.Second)
+	require.Equal(t, 500*time.Millisecond, limiter.Reserve(500))
+}
+
+func TestLimiterBurst(t *testing.T) {
+	t.Parallel()
+	clock := &testClock{now: time.Unix(0, 0)}
+	limiter := newLimiter(1000, clock.Now)
+	clock.Advance(time.Hour)
+	require.Zero(t, limiter.Reserve(1000))
+	require.Equal(t, time.Second, limiter.Reserve(1000))
+}
+
+func TestLimiterFractionalRefill(t *testing.T) {
+	t.Parallel()
+	clock := &testClock{now: time.Unix(0, 0)}
+	limiter := newLimiter(1000, clock.Now)
+	require.Zero(t, limiter.Reserve(1000))
+	for i := 0; i < 2000; i++ {
+		clock.Advance(500 * time.Microsecond)
+		limiter.Reserve(0)
+	}
+	require.Zero(t, limiter.Reserve(1000))
+	require.Equal(t, time.Millisecond, limiter.Reserve(1))
+}
+
+func TestParseSpeed(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		speed string
+		bps   uint64
+	}{
+		{"8 bps", 1},
+		{"1 Bps", 1},
+		{"8 Kbps", 1000},
+		{"1 KBps", 1000},
+		{"1 Mbps", mbpsToBps},
+		{"100 Mbps", 100 * mbpsToBps},
+		{"2.5 Mbps", 312500},
+		{"0.5MBps", 500000},
+		{"1 MBps", 1000000},
+		{"2Gbps", 250000000},
+		{"1 TBps", 1000000000000},
+		{"18446744 TBps", 18446744000000000000},
+	} {
+		bps, err := ParseSpeed(testCase.speed)
+		require.NoError(t, err, testCase.speed)
+		require.Equal(t, testCase.bps, bps, testCase.speed)
+	}
+	for _, speed := range []string{"1 mbps", "1. Mbps", "-1 Mbps", "Mbps", "18446745 TBps"} {
+		_, err := ParseSpeed(speed)
+		require.Error(t, err, speed)
+	}
+}
+
+func TestLimitedConnWaitsBeforeWrite(t *testing.T) {
+	t.Parallel()
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+	go io.Copy(io.Discard, serverConn)
+	limiter := NewLimiter(100000)
+	conn := &limitedConn{bufio.NewExtendedConn(clientConn), nil, []*Limiter{limiter}}
+	_, err := conn.Write(make([]byte, 100000))
+	require.NoError(t, err)
+	start := time.Now()
+	_, err = conn.Write(make([]byte, 10000))
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+}
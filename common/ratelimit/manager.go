@@ -0,0 +1,169 @@
+package ratelimit
+
+import (
+	"math"
+	"net"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+)
+
+type Manager struct {
+	router           adapter.Router
+	ruleLimiters     map[string]*limiterPair
+	userLimiters     map[string]*limiterPair
+	inboundLimiters  map[string]*limiterPair
+	outboundLimiters map[string]*limiterPair
+}
+
+type limiterPair struct {
+	up   *Limiter
+	down *Limiter
+}
+
+func newLimiterPair(up uint64, down uint64) *limiterPair {
+	var pair limiterPair
+	if up > 0 {
+		pair.up = NewLimiter(up)
+	}
+	if down > 0 {
+		pair.down = NewLimiter(down)
+	}
+	return &pair
+}
+
+func rateFromOptions(rate string, mbps int) (uint64, error) {
+	if rate != "" {
+		return ParseSpeed(rate)
+	}
+	if mbps < 0 {
+		return 0, E.New("negative speed: ", mbps)
+	} else if uint64(mbps) > math.MaxUint64/mbpsToBps {
+		return 0, E.New("speed too large: ", mbps)
+	}
+	return uint64(mbps) * mbpsToBps, nil
+}
+
+func NewManager(router adapter.Router, options []option.RateLimitOptions) (*Manager, error) {
+	manager := &Manager{
+		router:           router,
+		ruleLimiters:     make(map[string]*limiterPair),
+		userLimiters:     make(map[string]*limiterPair),
+		inboundLimiters:  make(map[string]*limiterPair),
+		outboundLimiters: make(map[string]*limiterPair),
+	}
+	for i, limitOptions := range options {
+		up, err := rateFromOptions(limitOptions.Up, limitOptions.UpMbps)
+		if err != nil {
+			return nil, E.Cause(err, "parse rate_limit[", i, "]: up")
+		}
+		down, err := rateFromOptions(limitOptions.Down, limitOptions.DownMbps)
+		if err != nil {
+			return nil, E.Cause(err, "parse rate_limit[", i, "]: down")
+		}
+		if up == 0 && down == 0 {
+			return nil, E.New("parse rate_limit[", i, "]: missing up or down speed")
+		}
+		if limitOptions.Tag != "" {
+			if manager.ruleLimiters[limitOptions.Tag] != nil {
+				return nil, E.New("parse rate_limit[", i, "]: duplicate tag: ", limitOptions.Tag)
+			}
+			manager.ruleLimiters[limitOptions.Tag] = newLimiterPair(up, down)
+		}
+		for _, user := range limitOptions.Users {
+			if manager.userLimiters[user] != nil {
+				return nil, E.New("parse rate_limit[", i, "]: duplicate user: ", user)
+			}
+			manager.userLimiters[user] = newLimiterPair(up, down)
+		}
+		for _, inbound := range limitOptions.Inbounds {
+			if manager.inboundLimiters[inbound] != nil {
+				return nil, E.New("parse rate_limit[", i, "]: duplicate inbound: ", inbound)
+			}
+			manager.inboundLimiters[inbound] = newLimiterPair(up, down)
+		}
+		for _, outbound := range limitOptions.Outbounds {
+			if manager.outboundLimiters[outbound] != nil {
+				return nil, E.New("parse rate_limit[", i, "]: duplicate outbound: ", outbound)
+			}
+			manager.outboundLimiters[outbound] = newLimiterPair(up, down)
+		}
+	}
+	return manager, nil
+}
+
+func (m *Manager) HasTag(tag string) bool {
+	return m.ruleLimiters[tag] != nil
+}
+
+func (m *Manager) limiters(metadata adapter.InboundContext, matchedRule adapter.RouteRule, outbound adapter.Outbound) (readLimiters []*Limiter, writeLimiters []*Limiter) {
+	var pairs []*limiterPair
+	if matchedRule != nil && matchedRule.RateLimit() != "" {
+		if pair := m.ruleLimiters[matchedRule.RateLimit()]; pair != nil {
+			pairs = append(pairs, pair)
+		}
+	}
+	if metadata.User != "" {
+		if pair := m.userLimiters[metadata.User]; pair != nil {
+			pairs = append(pairs, pair)
+		}
+	}
+	if metadata.Inbound != "" {
+		if pair := m.inboundLimiters[metadata.Inbound]; pair != nil {
+			pairs = append(pairs, pair)
+		}
+	}
+	if len(m.outboundLimiters) > 0 {
+		for _, tag := range m.outboundChain(outbound) {
+			if pair := m.outboundLimiters[tag]; pair != nil {
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	for _, pair := range pairs {
+		if pair.up != nil {
+			readLimiters = append(readLimiters, pair.up)
+		}
+		if pair.down != nil {
+			writeLimiters = append(writeLimiters, pair.down)
+		}
+	}
+	return
+}
+
+// outboundChain returns the tags of the outbound and, for groups, of the outbounds selected by it.
+func (m *Manager) outboundChain(outbound adapter.Outbound) []string {
+	chain := []string{outbound.Tag()}
+	for {
+		group, isGroup := outbound.(adapter.OutboundGroup)
+		if !isGroup {
+			return chain
+		}
+		next, loaded := m.router.Outbound(group.Now())
+		if !loaded || common.Contains(chain, next.Tag()) {
+			return chain
+		}
+		chain = append(chain, next.Tag())
+		outbound = next
+	}
+}
+
+func (m *Manager) RoutedConnection(conn net.Conn, metadata adapter.InboundContext, matchedRule adapter.RouteRule, outbound adapter.Outbound) net.Conn {
+	readLimiters, writeLimiters := m.limiters(metadata, matchedRule, outbound)
+	if len(readLimiters) == 0 && len(writeLimiters) == 0 {
+		return conn
+	}
+	return &limitedConn{bufio.NewExtendedConn(conn), readLimiters, writeLimiters}
+}
+
+func (m *Manager) RoutedPacketConnection(conn N.PacketConn, metadata adapter.InboundContext, matchedRule adapter.RouteRule, outbound adapter.Outbound) N.PacketConn {
+	readLimiters, writeLimiters := m.limiters(metadata, matchedRule, outbound)
+	if len(readLimiters) == 0 && len(writeLimiters) == 0 {
+		return conn
+	}
+	return &limitedPacketConn{conn, readLimiters, writeLimiters}
+}
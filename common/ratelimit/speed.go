@@ -0,0 +1,40 @@
+package ratelimit
+
+import (
+	"math/big"
+	"regexp"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const mbpsToBps = 125000
+
+var speedRegexp = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([KMGT]?)([Bb])ps$`)
+
+// ParseSpeed parses speed strings like `5 Mbps`, `2.5 Mbps` or `1 MBps` into bytes per second.
+// Units are decimal, so `1 Mbps` is the same as `up_mbps: 1`.
+func ParseSpeed(s string) (uint64, error) {
+	m := speedRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return 0, E.New("invalid speed format: ", s)
+	}
+	speed, _ := new(big.Rat).SetString(m[1])
+	switch m[2] {
+	case "K":
+		speed.Mul(speed, big.NewRat(1e3, 1))
+	case "M":
+		speed.Mul(speed, big.NewRat(1e6, 1))
+	case "G":
+		speed.Mul(speed, big.NewRat(1e9, 1))
+	case "T":
+		speed.Mul(speed, big.NewRat(1e12, 1))
+	}
+	if m[3] == "b" {
+		speed.Quo(speed, big.NewRat(8, 1))
+	}
+	bps := new(big.Int).Quo(speed.Num(), speed.Denom())
+	if !bps.IsUint64() {
+		return 0, E.New("speed too large: ", s)
+	}
+	return bps.Uint64(), nil
+}
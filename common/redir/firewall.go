@@ -0,0 +1,42 @@
+package redir
+
+import (
+	"net/netip"
+
+	"github.com/sagernet/sing/common/ranges"
+)
+
+type FirewallOptions struct {
+	Mode         string
+	Name         string
+	Address      netip.Addr
+	Port         uint16
+	Network      []string
+	DefaultMark  int
+	TProxyMark   uint32
+	TableIndex   int
+	RouteAddress []netip.Prefix
+	IncludeUID   []ranges.Range[uint32]
+	ExcludeUID   []ranges.Range[uint32]
+}
+
+var (
+	reservedPrefix4 = []string{
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.168.0.0/16",
+		"224.0.0.0/4",
+		"240.0.0.0/4",
+	}
+	reservedPrefix6 = []string{
+		"::/128",
+		"::1/128",
+		"fc00::/7",
+		"fe80::/10",
+		"ff00::/8",
+	}
+)
@@ -0,0 +1,404 @@
+package redir
+
+import (
+	"net"
+	"net/netip"
+	"os/exec"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/ranges"
+	"github.com/sagernet/sing/common/shell"
+
+	"github.com/sagernet/netlink"
+	"go4.org/netipx"
+	"golang.org/x/sys/unix"
+)
+
+type firewall struct {
+	FirewallOptions
+	inet4    bool
+	inet6    bool
+	loopback bool
+	useNFT   bool
+}
+
+func ConfigureFirewall(options FirewallOptions) (func() error, error) {
+	if options.DefaultMark == 0 {
+		return nil, E.New("route.default_mark is required to prevent routing loops")
+	}
+	f := &firewall{FirewallOptions: options}
+	f.Address = options.Address.Unmap()
+	if f.Mode == C.TypeTun {
+		for _, prefix := range f.RouteAddress {
+			if prefix.Addr().Is4() {
+				f.inet4 = true
+			} else {
+				f.inet6 = true
+			}
+		}
+	} else if f.Address.Is6() && f.Address.IsUnspecified() {
+		f.inet4 = true
+		f.inet6 = true
+	} else if f.Address.Is4() {
+		f.inet4 = true
+	} else {
+		f.inet6 = true
+	}
+	f.loopback = f.Address.IsLoopback()
+	if common.Error(exec.LookPath("nft")) == nil {
+		f.useNFT = true
+	} else if f.Mode == C.TypeTun {
+		return nil, E.New("nft not found in PATH")
+	} else if common.Error(exec.LookPath("iptables")) != nil {
+		return nil, E.New("neither nft nor iptables found in PATH")
+	}
+	// remove rules left behind by an unclean exit
+	_ = f.cleanup()
+	err := f.setup()
+	if err != nil {
+		_ = f.cleanup()
+		return nil, err
+	}
+	return f.cleanup, nil
+}
+
+func (f *firewall) families() []int {
+	var families []int
+	if f.inet4 {
+		families = append(families, unix.AF_INET)
+	}
+	if f.inet6 {
+		families = append(families, unix.AF_INET6)
+	}
+	return families
+}
+
+func (f *firewall) name() string {
+	if f.Name != "" {
+		return f.Name
+	}
+	return F.ToString("sing-box-", f.Mode, "-", f.Port)
+}
+
+func (f *firewall) setup() error {
+	for _, family := range f.families() {
+		var err error
+		if f.useNFT {
+			err = f.setupNFTables(family)
+		} else {
+			err = f.setupIPTables(family)
+		}
+		if err != nil {
+			return err
+		}
+		if f.Mode == C.TypeTProxy {
+			err = f.setupRoute(family)
+			if err != nil {
+				return E.Cause(err, "setup tproxy route")
+			}
+		}
+	}
+	return nil
+}
+
+func (f *firewall) cleanup() error {
+	var errors []error
+	for _, family := range f.families() {
+		if f.useNFT {
+			errors = append(errors, f.cleanupNFTables(family))
+		} else {
+			errors = append(errors, f.cleanupIPTables(family)...)
+		}
+		if f.Mode == C.TypeTProxy {
+			errors = append(errors, f.cleanupRoute(family))
+		}
+	}
+	return E.Errors(errors...)
+}
+
+func (f *firewall) setupNFTables(family int) error {
+	command := shell.Exec("nft", "-f", "-")
+	command.Stdin = strings.NewReader(f.nftablesScript(family))
+	output, err := command.Read()
+	if err != nil {
+		return E.Cause(err, strings.TrimSpace(output))
+	}
+	return nil
+}
+
+func (f *firewall) cleanupNFTables(family int) error {
+	return shell.Exec("nft", f.nftablesCleanupArguments(family)...).Run()
+}
+
+func (f *firewall) nftablesCleanupArguments(family int) []string {
+	return []string{"delete", "table", nftablesFamily(family), f.name()}
+}
+
+func (f *firewall) nftablesScript(family int) string {
+	var (
+		daddr    string
+		reserved []string
+	)
+	if family == unix.AF_INET {
+		daddr = "ip daddr"
+		reserved = reservedPrefix4
+	} else {
+		daddr = "ip6 daddr"
+		reserved = reservedPrefix6
+	}
+	bypassMark := F.ToString("meta mark ", f.DefaultMark, " return")
+	bypassLocal := "fib daddr type local return"
+	bypassReserved := F.ToString(daddr, " { ", strings.Join(reserved, ", "), " } return")
+	var script []string
+	writeChain := func(name string, hook string, rules ...string) {
+		script = append(script, "\tchain "+name+" {", "\t\t"+hook)
+		for _, rule := range rules {
+			script = append(script, "\t\t"+rule)
+		}
+		script = append(script, "\t}")
+	}
+	switch f.Mode {
+	case C.TypeRedirect:
+		redirect := F.ToString("meta l4proto tcp redirect to :", f.Port)
+		writeChain("output", "type nat hook output priority -100; policy accept;", bypassMark, bypassLocal, bypassReserved, redirect)
+		if !f.loopback {
+			writeChain("prerouting", "type nat hook prerouting priority -100; policy accept;", bypassLocal, bypassReserved, redirect)
+		}
+	case C.TypeTProxy:
+		var l4proto string
+		if len(f.Network) == 1 {
+			l4proto = "meta l4proto " + f.Network[0]
+		} else {
+			l4proto = "meta l4proto { tcp, udp }"
+		}
+		var target string
+		if f.Address.IsUnspecified() {
+			target = F.ToString(":", f.Port)
+		} else {
+			target = netip.AddrPortFrom(f.Address, f.Port).String()
+		}
+		mark := F.ToString("meta mark set ", f.TProxyMark)
+		writeChain("prerouting", "type filter hook prerouting priority -150; policy accept;", bypassLocal, bypassReserved, F.ToString(l4proto, " tproxy to ", target, " ", mark, " accept"))
+		writeChain("output", "type route hook output priority -150; policy accept;", bypassMark, bypassLocal, bypassReserved, F.ToString(l4proto, " ", mark))
+	case C.TypeTun:
+		redirect := F.ToString("meta l4proto tcp redirect to :", f.Port)
+		var bypassRoute []string
+		if routeAddress := f.routeAddress(family); len(routeAddress) > 0 {
+			bypassRoute = append(bypassRoute, F.ToString(daddr, " != { ", strings.Join(routeAddress, ", "), " } return"))
+		}
+		outputRules := []string{bypassMark, bypassLocal, bypassReserved}
+		if len(f.ExcludeUID) > 0 {
+			outputRules = append(outputRules, F.ToString("meta skuid { ", uidSet(f.ExcludeUID), " } return"))
+		}
+		if len(f.IncludeUID) > 0 {
+			outputRules = append(outputRules, F.ToString("meta skuid != { ", uidSet(f.IncludeUID), " } return"))
+		}
+		outputRules = append(append(outputRules, bypassRoute...), redirect)
+		writeChain("output", "type nat hook output priority -100; policy accept;", outputRules...)
+		writeChain("prerouting", "type nat hook prerouting priority -100; policy accept;", append(append([]string{bypassLocal, bypassReserved}, bypassRoute...), redirect)...)
+		// only accept connections redirected by the rules above
+		writeChain("input", "type filter hook input priority 0; policy accept;", F.ToString("tcp dport ", f.Port, " ct status dnat accept"), F.ToString("tcp dport ", f.Port, " drop"))
+	}
+	return F.ToString("table ", nftablesFamily(family), " ", f.name(), " {\n", strings.Join(script, "\n"), "\n}\n")
+}
+
+func (f *firewall) routeAddress(family int) []string {
+	var builder netipx.IPSetBuilder
+	for _, prefix := range f.RouteAddress {
+		if prefix.Addr().Is4() != (family == unix.AF_INET) {
+			continue
+		}
+		if prefix.Bits() == 0 {
+			return nil
+		}
+		builder.AddPrefix(prefix)
+	}
+	ipSet, err := builder.IPSet()
+	if err != nil {
+		return nil
+	}
+	return common.Map(ipSet.Prefixes(), netip.Prefix.String)
+}
+
+func uidSet(uidRanges []ranges.Range[uint32]) string {
+	return strings.Join(common.Map(uidRanges, func(uidRange ranges.Range[uint32]) string {
+		if uidRange.Start == uidRange.End {
+			return F.ToString(uidRange.Start)
+		}
+		return F.ToString(uidRange.Start, "-", uidRange.End)
+	}), ", ")
+}
+
+func nftablesFamily(family int) string {
+	if family == unix.AF_INET {
+		return "ip"
+	} else {
+		return "ip6"
+	}
+}
+
+func (f *firewall) setupIPTables(family int) error {
+	for _, arguments := range f.iptablesSetupCommands(family) {
+		output, err := shell.Exec(iptablesCommand(family), append([]string{"-w"}, arguments...)...).Read()
+		if err != nil {
+			return E.Cause(err, strings.TrimSpace(output))
+		}
+	}
+	return nil
+}
+
+func (f *firewall) cleanupIPTables(family int) []error {
+	var errors []error
+	for _, arguments := range f.iptablesCleanupCommands() {
+		errors = append(errors, shell.Exec(iptablesCommand(family), append([]string{"-w"}, arguments...)...).Run())
+	}
+	return errors
+}
+
+func (f *firewall) iptablesChain() string {
+	return strings.ToUpper(strings.ReplaceAll(f.name(), "-", "_"))
+}
+
+func (f *firewall) iptablesSetupCommands(family int) [][]string {
+	var reserved []string
+	if family == unix.AF_INET {
+		reserved = reservedPrefix4
+	} else {
+		reserved = reservedPrefix6
+	}
+	chain := f.iptablesChain()
+	var commands [][]string
+	appendBypass := func(table string, chain string, bypassMark bool) {
+		if bypassMark {
+			commands = append(commands, []string{"-t", table, "-A", chain, "-m", "mark", "--mark", F.ToString(f.DefaultMark), "-j", "RETURN"})
+		}
+		commands = append(commands, []string{"-t", table, "-A", chain, "-m", "addrtype", "--dst-type", "LOCAL", "-j", "RETURN"})
+		for _, prefix := range reserved {
+			commands = append(commands, []string{"-t", table, "-A", chain, "-d", prefix, "-j", "RETURN"})
+		}
+	}
+	switch f.Mode {
+	case C.TypeRedirect:
+		commands = append(commands, []string{"-t", "nat", "-N", chain})
+		appendBypass("nat", chain, true)
+		commands = append(commands,
+			[]string{"-t", "nat", "-A", chain, "-p", "tcp", "-j", "REDIRECT", "--to-ports", F.ToString(f.Port)},
+			[]string{"-t", "nat", "-A", "OUTPUT", "-p", "tcp", "-j", chain},
+		)
+		if !f.loopback {
+			commands = append(commands, []string{"-t", "nat", "-A", "PREROUTING", "-p", "tcp", "-j", chain})
+		}
+	case C.TypeTProxy:
+		outputChain := chain + "_OUT"
+		mark := F.ToString(f.TProxyMark)
+		commands = append(commands, []string{"-t", "mangle", "-N", chain})
+		appendBypass("mangle", chain, false)
+		for _, network := range f.Network {
+			command := []string{"-t", "mangle", "-A", chain, "-p", network, "-j", "TPROXY", "--on-port", F.ToString(f.Port)}
+			if !f.Address.IsUnspecified() {
+				command = append(command, "--on-ip", f.Address.String())
+			}
+			commands = append(commands, append(command, "--tproxy-mark", mark))
+		}
+		commands = append(commands, []string{"-t", "mangle", "-N", outputChain})
+		appendBypass("mangle", outputChain, true)
+		for _, network := range f.Network {
+			commands = append(commands, []string{"-t", "mangle", "-A", outputChain, "-p", network, "-j", "MARK", "--set-mark", mark})
+		}
+		commands = append(commands,
+			[]string{"-t", "mangle", "-A", "PREROUTING", "-j", chain},
+			[]string{"-t", "mangle", "-A", "OUTPUT", "-j", outputChain},
+		)
+	}
+	return commands
+}
+
+func (f *firewall) iptablesCleanupCommands() [][]string {
+	chain := f.iptablesChain()
+	var commands [][]string
+	switch f.Mode {
+	case C.TypeRedirect:
+		commands = append(commands, []string{"-t", "nat", "-D", "OUTPUT", "-p", "tcp", "-j", chain})
+		if !f.loopback {
+			commands = append(commands, []string{"-t", "nat", "-D", "PREROUTING", "-p", "tcp", "-j", chain})
+		}
+		commands = append(commands,
+			[]string{"-t", "nat", "-F", chain},
+			[]string{"-t", "nat", "-X", chain},
+		)
+	case C.TypeTProxy:
+		outputChain := chain + "_OUT"
+		commands = append(commands,
+			[]string{"-t", "mangle", "-D", "PREROUTING", "-j", chain},
+			[]string{"-t", "mangle", "-D", "OUTPUT", "-j", outputChain},
+			[]string{"-t", "mangle", "-F", chain},
+			[]string{"-t", "mangle", "-X", chain},
+			[]string{"-t", "mangle", "-F", outputChain},
+			[]string{"-t", "mangle", "-X", outputChain},
+		)
+	}
+	return commands
+}
+
+func iptablesCommand(family int) string {
+	if family == unix.AF_INET {
+		return "iptables"
+	} else {
+		return "ip6tables"
+	}
+}
+
+func (f *firewall) tproxyRule(family int) *netlink.Rule {
+	rule := netlink.NewRule()
+	rule.Family = family
+	rule.Mark = int(f.TProxyMark)
+	rule.Table = f.TableIndex
+	return rule
+}
+
+func (f *firewall) tproxyRoute(family int) (*netlink.Route, error) {
+	loopback, err := netlink.LinkByName("lo")
+	if err != nil {
+		return nil, err
+	}
+	var destination *net.IPNet
+	if family == unix.AF_INET {
+		destination = &net.IPNet{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)}
+	} else {
+		destination = &net.IPNet{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)}
+	}
+	return &netlink.Route{
+		LinkIndex: loopback.Attrs().Index,
+		Dst:       destination,
+		Table:     f.TableIndex,
+		Type:      unix.RTN_LOCAL,
+		Scope:     netlink.SCOPE_HOST,
+	}, nil
+}
+
+func (f *firewall) setupRoute(family int) error {
+	route, err := f.tproxyRoute(family)
+	if err != nil {
+		return err
+	}
+	err = netlink.RouteReplace(route)
+	if err != nil {
+		return err
+	}
+	return netlink.RuleAdd(f.tproxyRule(family))
+}
+
+func (f *firewall) cleanupRoute(family int) error {
+	rule := f.tproxyRule(family)
+	for netlink.RuleDel(rule) == nil {
+	}
+	route, err := f.tproxyRoute(family)
+	if err != nil {
+		return err
+	}
+	return netlink.RouteDel(route)
+}
@@ -0,0 +1,194 @@
+package redir
+
+import (
+	"flag"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/ranges"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+func checkGolden(t *testing.T, name string, content string) {
+	path := filepath.Join("testdata", name+".golden")
+	if *updateGolden {
+		require.NoError(t, os.MkdirAll("testdata", 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+		return
+	}
+	expected, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, string(expected), content, name)
+}
+
+func formatCommands(commands [][]string) string {
+	return strings.Join(common.Map(commands, func(arguments []string) string {
+		return strings.Join(arguments, " ") + "\n"
+	}), "")
+}
+
+var testFirewalls = []struct {
+	name     string
+	firewall *firewall
+	families []int
+}{
+	{
+		name: "redirect_loopback",
+		firewall: &firewall{FirewallOptions: FirewallOptions{
+			Mode:        C.TypeRedirect,
+			Address:     netip.MustParseAddr("127.0.0.1"),
+			Port:        7892,
+			DefaultMark: 255,
+		}, loopback: true},
+		families: []int{unix.AF_INET},
+	},
+	{
+		name: "redirect",
+		firewall: &firewall{FirewallOptions: FirewallOptions{
+			Mode:        C.TypeRedirect,
+			Name:        "custom",
+			Address:     netip.IPv6Unspecified(),
+			Port:        7892,
+			DefaultMark: 255,
+		}},
+		families: []int{unix.AF_INET, unix.AF_INET6},
+	},
+	{
+		name: "tproxy",
+		firewall: &firewall{FirewallOptions: FirewallOptions{
+			Mode:        C.TypeTProxy,
+			Address:     netip.IPv6Unspecified(),
+			Port:        7893,
+			Network:     []string{"tcp", "udp"},
+			DefaultMark: 255,
+			TProxyMark:  1,
+			TableIndex:  100,
+		}},
+		families: []int{unix.AF_INET, unix.AF_INET6},
+	},
+	{
+		name: "tproxy_udp_address",
+		firewall: &firewall{FirewallOptions: FirewallOptions{
+			Mode:        C.TypeTProxy,
+			Address:     netip.MustParseAddr("192.168.1.1"),
+			Port:        7893,
+			Network:     []string{"udp"},
+			DefaultMark: 255,
+			TProxyMark:  1,
+			TableIndex:  100,
+		}},
+		families: []int{unix.AF_INET},
+	},
+}
+
+func TestFirewallNFTables(t *testing.T) {
+	testFirewalls := append(testFirewalls, []struct {
+		name     string
+		firewall *firewall
+		families []int
+	}{
+		{
+			name: "tun",
+			firewall: &firewall{FirewallOptions: FirewallOptions{
+				Mode:         C.TypeTun,
+				Name:         "sing-box-tun",
+				Port:         37891,
+				DefaultMark:  255,
+				RouteAddress: []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0"), netip.MustParsePrefix("2001:db8::/33"), netip.MustParsePrefix("2001:db8:8000::/33")},
+			}},
+			families: []int{unix.AF_INET, unix.AF_INET6},
+		},
+		{
+			name: "tun_uid",
+			firewall: &firewall{FirewallOptions: FirewallOptions{
+				Mode:         C.TypeTun,
+				Name:         "sing-box-tun",
+				Port:         37891,
+				DefaultMark:  255,
+				RouteAddress: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/9"), netip.MustParsePrefix("10.128.0.0/9"), netip.MustParsePrefix("8.8.8.8/32")},
+				IncludeUID:   []ranges.Range[uint32]{ranges.New[uint32](1000, 1000), ranges.New[uint32](2000, 2999)},
+				ExcludeUID:   []ranges.Range[uint32]{ranges.New[uint32](0, 0)},
+			}},
+			families: []int{unix.AF_INET},
+		},
+	}...)
+	for _, testCase := range testFirewalls {
+		var content []string
+		for _, family := range testCase.families {
+			content = append(content,
+				testCase.firewall.nftablesScript(family),
+				"# cleanup\nnft "+strings.Join(testCase.firewall.nftablesCleanupArguments(family), " ")+"\n",
+			)
+		}
+		checkGolden(t, "nftables_"+testCase.name, strings.Join(content, "\n"))
+	}
+}
+
+func TestFirewallIPTables(t *testing.T) {
+	for _, testCase := range testFirewalls {
+		var content []string
+		for _, family := range testCase.families {
+			content = append(content,
+				"# setup "+iptablesCommand(family)+"\n"+formatCommands(testCase.firewall.iptablesSetupCommands(family)),
+				"# cleanup "+iptablesCommand(family)+"\n"+formatCommands(testCase.firewall.iptablesCleanupCommands()),
+			)
+		}
+		checkGolden(t, "iptables_"+testCase.name, strings.Join(content, "\n"))
+	}
+}
+
+func TestFirewallDefaultMark(t *testing.T) {
+	for _, testCase := range testFirewalls {
+		for _, family := range testCase.families {
+			// traffic sent by sing-box itself must never be redirected back to it
+			script := testCase.firewall.nftablesScript(family)
+			require.Contains(t, script, "chain output {\n\t\ttype ", testCase.name)
+			output := script[strings.Index(script, "chain output"):]
+			require.Contains(t, output[:strings.Index(output, "\t}")], "meta mark 255 return", testCase.name)
+			var markBypass bool
+			for _, arguments := range testCase.firewall.iptablesSetupCommands(family) {
+				if strings.Join(arguments, " ") == "-t "+arguments[1]+" -A "+arguments[3]+" -m mark --mark 255 -j RETURN" {
+					markBypass = true
+				}
+			}
+			require.True(t, markBypass, testCase.name)
+		}
+	}
+	_, err := ConfigureFirewall(FirewallOptions{Mode: C.TypeRedirect, Port: 7892})
+	require.ErrorContains(t, err, "default_mark")
+}
+
+// TestFirewallIPTablesCleanup checks that cleanup, which also runs at startup
+// to remove rules left behind by a crash, reverts everything setup creates.
+func TestFirewallIPTablesCleanup(t *testing.T) {
+	for _, testCase := range testFirewalls {
+		cleanup := common.Map(testCase.firewall.iptablesCleanupCommands(), func(arguments []string) string {
+			return strings.Join(arguments, " ")
+		})
+		for _, family := range testCase.families {
+			for _, arguments := range testCase.firewall.iptablesSetupCommands(family) {
+				table, action, chain := arguments[1], arguments[2], arguments[3]
+				switch action {
+				case "-N":
+					require.Contains(t, cleanup, strings.Join([]string{"-t", table, "-F", chain}, " "), testCase.name)
+					require.Contains(t, cleanup, strings.Join([]string{"-t", table, "-X", chain}, " "), testCase.name)
+				case "-A":
+					if chain != "OUTPUT" && chain != "PREROUTING" {
+						continue
+					}
+					deleteArguments := append([]string{"-t", table, "-D"}, arguments[3:]...)
+					require.Contains(t, cleanup, strings.Join(deleteArguments, " "), testCase.name)
+				}
+			}
+		}
+	}
+}
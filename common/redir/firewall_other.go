@@ -0,0 +1,13 @@
+//go:build !linux
+
+package redir
+
+import (
+	"runtime"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func ConfigureFirewall(options FirewallOptions) (func() error, error) {
+	return nil, E.New("auto_configure is not supported on ", runtime.GOOS)
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+
+package rotate
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchReopen reopens the file on SIGUSR1.
+func watchReopen(writer *Writer) func() {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, syscall.SIGUSR1)
+	go func() {
+		for {
+			select {
+			case <-signals:
+				writer.Reopen()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+	}
+}
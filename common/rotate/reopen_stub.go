@@ -0,0 +1,7 @@
+//go:build windows
+
+package rotate
+
+func watchReopen(writer *Writer) func() {
+	return func() {}
+}
@@ -0,0 +1,243 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service/filemanager"
+)
+
+const (
+	backupTimeFormat = "20060102-150405.000"
+	compressSuffix   = ".gz"
+)
+
+// Writer is a file writer that renames the file to a timestamped backup and
+// starts a new one once it exceeds the configured size or age.
+type Writer struct {
+	ctx        context.Context
+	path       string
+	maxSize    int64
+	interval   time.Duration
+	maxBackups int
+	maxAge     time.Duration
+	compress   bool
+
+	access   sync.Mutex
+	closed   bool
+	header   []byte
+	file     *os.File
+	size     int64
+	openedAt time.Time
+
+	cleanupAccess sync.Mutex
+	stopReopen    func()
+}
+
+func NewWriter(ctx context.Context, path string, options option.RotateOptions) (*Writer, error) {
+	if options.MaxSize < 0 || options.RotateInterval < 0 || options.MaxBackups < 0 || options.MaxAge < 0 {
+		return nil, E.New("invalid rotate options")
+	}
+	writer := &Writer{
+		ctx:        ctx,
+		path:       filemanager.BasePath(ctx, path),
+		maxSize:    int64(options.MaxSize),
+		interval:   time.Duration(options.RotateInterval),
+		maxBackups: options.MaxBackups,
+		maxAge:     time.Duration(options.MaxAge),
+		compress:   options.Compress,
+	}
+	err := writer.open()
+	if err != nil {
+		return nil, err
+	}
+	writer.stopReopen = watchReopen(writer)
+	return writer, nil
+}
+
+func (w *Writer) open() error {
+	file, err := filemanager.OpenFile(w.ctx, w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file = file
+	w.size = info.Size()
+	w.openedAt = time.Now()
+	return nil
+}
+
+func (w *Writer) Write(p []byte) (n int, err error) {
+	w.access.Lock()
+	defer w.access.Unlock()
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+	if w.size > 0 && (w.maxSize > 0 && w.size+int64(len(p)) > w.maxSize || w.interval > 0 && time.Since(w.openedAt) >= w.interval) {
+		err = w.rotate()
+		if err != nil {
+			return
+		}
+	}
+	if w.size == 0 && len(w.header) > 0 {
+		var headerN int
+		headerN, err = w.file.Write(w.header)
+		w.size += int64(headerN)
+		if err != nil {
+			return
+		}
+	}
+	n, err = w.file.Write(p)
+	w.size += int64(n)
+	return
+}
+
+// SetHeader sets the content written at the beginning of each new file.
+func (w *Writer) SetHeader(header []byte) {
+	w.access.Lock()
+	defer w.access.Unlock()
+	w.header = header
+}
+
+// Reopen closes and reopens the file, for use after the file is moved by an external tool.
+func (w *Writer) Reopen() error {
+	w.access.Lock()
+	defer w.access.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	if w.file != nil {
+		w.file.Close()
+		w.file = nil
+	}
+	return w.open()
+}
+
+func (w *Writer) rotate() error {
+	err := w.file.Close()
+	w.file = nil
+	if err != nil {
+		return err
+	}
+	backup := w.path + "." + time.Now().Format(backupTimeFormat)
+	err = os.Rename(w.path, backup)
+	if err != nil {
+		return E.Cause(err, "rotate ", w.path)
+	}
+	err = w.open()
+	if err != nil {
+		return err
+	}
+	go w.cleanup(backup)
+	return nil
+}
+
+func (w *Writer) cleanup(backup string) {
+	w.cleanupAccess.Lock()
+	defer w.cleanupAccess.Unlock()
+	if w.compress {
+		compressFile(backup)
+	}
+	backups := w.backups()
+	var expired []backupFile
+	if w.maxBackups > 0 && len(backups) > w.maxBackups {
+		expired = backups[:len(backups)-w.maxBackups]
+		backups = backups[len(backups)-w.maxBackups:]
+	}
+	if w.maxAge > 0 {
+		for _, backup := range backups {
+			if time.Since(backup.createdAt) > w.maxAge {
+				expired = append(expired, backup)
+			}
+		}
+	}
+	for _, backup := range expired {
+		os.Remove(backup.path)
+	}
+}
+
+func compressFile(path string) error {
+	source, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	destination, err := os.OpenFile(path+compressSuffix, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	gzipWriter := gzip.NewWriter(destination)
+	_, err = io.Copy(gzipWriter, source)
+	if err == nil {
+		err = gzipWriter.Close()
+	}
+	if err == nil {
+		err = destination.Close()
+	} else {
+		destination.Close()
+	}
+	if err != nil {
+		os.Remove(path + compressSuffix)
+		return err
+	}
+	return os.Remove(path)
+}
+
+type backupFile struct {
+	path      string
+	createdAt time.Time
+}
+
+// backups returns the backup files from the oldest to the newest.
+func (w *Writer) backups() []backupFile {
+	entries, err := os.ReadDir(filepath.Dir(w.path))
+	if err != nil {
+		return nil
+	}
+	prefix := filepath.Base(w.path) + "."
+	var backups []backupFile
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		timestamp := strings.TrimSuffix(strings.TrimPrefix(entry.Name(), prefix), compressSuffix)
+		createdAt, err := time.ParseInLocation(backupTimeFormat, timestamp, time.Local)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{filepath.Join(filepath.Dir(w.path), entry.Name()), createdAt})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].createdAt.Before(backups[j].createdAt)
+	})
+	return backups
+}
+
+func (w *Writer) Close() error {
+	w.access.Lock()
+	defer w.access.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	w.stopReopen()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
@@ -0,0 +1,192 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWriter(t *testing.T, options option.RotateOptions) (*Writer, string) {
+	path := filepath.Join(t.TempDir(), "test.log")
+	writer, err := NewWriter(context.Background(), path, options)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		writer.Close()
+	})
+	return writer, path
+}
+
+func writeString(t *testing.T, writer *Writer, content string) {
+	_, err := writer.Write([]byte(content))
+	require.NoError(t, err)
+}
+
+func readFile(t *testing.T, path string) string {
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(content)
+}
+
+func listBackups(t *testing.T, path string) []string {
+	backups, err := filepath.Glob(path + ".*")
+	require.NoError(t, err)
+	sort.Strings(backups)
+	return backups
+}
+
+// createBackup creates a backup file as if it was rotated at createdAt.
+func createBackup(t *testing.T, path string, createdAt time.Time) string {
+	backup := path + "." + createdAt.Format(backupTimeFormat)
+	require.NoError(t, os.WriteFile(backup, []byte("backup\n"), 0o644))
+	return backup
+}
+
+func TestWriterSize(t *testing.T) {
+	t.Parallel()
+	writer, path := newTestWriter(t, option.RotateOptions{MaxSize: 10})
+	writer.SetHeader([]byte("header\n"))
+	writeString(t, writer, "a\n")
+	writeString(t, writer, "b\n")
+	backups := listBackups(t, path)
+	require.Len(t, backups, 1)
+	require.Equal(t, "header\na\n", readFile(t, backups[0]))
+	require.Equal(t, "header\nb\n", readFile(t, path))
+}
+
+func TestWriterInterval(t *testing.T) {
+	t.Parallel()
+	writer, path := newTestWriter(t, option.RotateOptions{RotateInterval: option.Duration(100 * time.Millisecond)})
+	writeString(t, writer, "a\n")
+	writeString(t, writer, "b\n")
+	require.Empty(t, listBackups(t, path))
+	time.Sleep(150 * time.Millisecond)
+	writeString(t, writer, "c\n")
+	backups := listBackups(t, path)
+	require.Len(t, backups, 1)
+	require.Equal(t, "a\nb\n", readFile(t, backups[0]))
+	require.Equal(t, "c\n", readFile(t, path))
+}
+
+func TestWriterExistingFile(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "test.log")
+	require.NoError(t, os.WriteFile(path, []byte("existing\n"), 0o644))
+	writer, err := NewWriter(context.Background(), path, option.RotateOptions{MaxSize: 12})
+	require.NoError(t, err)
+	defer writer.Close()
+	writer.SetHeader([]byte("header\n"))
+	// the size of the existing file counts and it does not get the header
+	writeString(t, writer, "a\n")
+	require.Equal(t, "existing\na\n", readFile(t, path))
+	writeString(t, writer, "b\n")
+	require.Equal(t, "header\nb\n", readFile(t, path))
+}
+
+func TestWriterMaxBackups(t *testing.T) {
+	t.Parallel()
+	writer, path := newTestWriter(t, option.RotateOptions{MaxSize: 1, MaxBackups: 2})
+	now := time.Now()
+	oldest := createBackup(t, path, now.Add(-3*time.Hour))
+	older := createBackup(t, path, now.Add(-2*time.Hour))
+	newer := createBackup(t, path, now.Add(-time.Hour))
+	// files that only share the prefix are kept
+	unrelated := path + ".old"
+	require.NoError(t, os.WriteFile(unrelated, nil, 0o644))
+
+	writeString(t, writer, "a\n")
+	writeString(t, writer, "b\n")
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(older)
+		return os.IsNotExist(err)
+	}, time.Second, 10*time.Millisecond)
+	backups := listBackups(t, path)
+	require.Len(t, backups, 3)
+	require.NotContains(t, backups, oldest)
+	require.Contains(t, backups, newer)
+	require.Contains(t, backups, unrelated)
+}
+
+func TestWriterMaxAge(t *testing.T) {
+	t.Parallel()
+	writer, path := newTestWriter(t, option.RotateOptions{MaxSize: 1, MaxAge: option.Duration(24 * time.Hour)})
+	expired := createBackup(t, path, time.Now().Add(-48*time.Hour))
+	recent := createBackup(t, path, time.Now().Add(-time.Hour))
+
+	writeString(t, writer, "a\n")
+	writeString(t, writer, "b\n")
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(expired)
+		return os.IsNotExist(err)
+	}, time.Second, 10*time.Millisecond)
+	backups := listBackups(t, path)
+	require.Len(t, backups, 2)
+	require.Contains(t, backups, recent)
+}
+
+func TestWriterCompress(t *testing.T) {
+	t.Parallel()
+	writer, path := newTestWriter(t, option.RotateOptions{MaxSize: 1, MaxBackups: 1, Compress: true})
+	compressed := createBackup(t, path, time.Now().Add(-time.Hour)) + compressSuffix
+	require.NoError(t, os.Rename(compressed[:len(compressed)-len(compressSuffix)], compressed))
+
+	writeString(t, writer, "a\n")
+	writeString(t, writer, "b\n")
+	// compressed backups count towards max_backups
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(compressed)
+		return os.IsNotExist(err)
+	}, time.Second, 10*time.Millisecond)
+	backups := listBackups(t, path)
+	require.Len(t, backups, 1)
+	require.Equal(t, compressSuffix, filepath.Ext(backups[0]))
+	file, err := os.Open(backups[0])
+	require.NoError(t, err)
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	require.NoError(t, err)
+	content, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, "a\n", string(content))
+}
+
+func TestWriterReopen(t *testing.T) {
+	t.Parallel()
+	writer, path := newTestWriter(t, option.RotateOptions{})
+	writer.SetHeader([]byte("header\n"))
+	writeString(t, writer, "a\n")
+	moved := path + ".moved"
+	require.NoError(t, os.Rename(path, moved))
+	writeString(t, writer, "b\n")
+	require.NoError(t, writer.Reopen())
+	writeString(t, writer, "c\n")
+	require.Equal(t, "header\na\nb\n", readFile(t, moved))
+	require.Equal(t, "header\nc\n", readFile(t, path))
+
+	require.NoError(t, writer.Close())
+	require.NoError(t, writer.Close())
+	require.ErrorIs(t, writer.Reopen(), os.ErrClosed)
+	_, err := writer.Write([]byte("d\n"))
+	require.ErrorIs(t, err, os.ErrClosed)
+}
+
+func TestWriterInvalidOptions(t *testing.T) {
+	t.Parallel()
+	for _, options := range []option.RotateOptions{
+		{MaxSize: -1},
+		{RotateInterval: -1},
+		{MaxBackups: -1},
+		{MaxAge: -1},
+	} {
+		_, err := NewWriter(context.Background(), filepath.Join(t.TempDir(), "test.log"), options)
+		require.ErrorContains(t, err, "invalid rotate options")
+	}
+}
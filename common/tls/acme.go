@@ -7,8 +7,10 @@ import (
 	"crypto/tls"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
 	E "github.com/sagernet/sing/common/exceptions"
 
@@ -76,6 +78,13 @@ func startACME(ctx context.Context, options option.InboundACMEOptions) (*tls.Con
 	if options.ExternalAccount != nil && options.ExternalAccount.KeyID != "" {
 		acmeConfig.ExternalAccount = (*acme.EAB)(options.ExternalAccount)
 	}
+	if options.DNS01Challenge != nil {
+		dnsSolver, err := newDNS01Solver(*options.DNS01Challenge)
+		if err != nil {
+			return nil, nil, E.Cause(err, "create dns01 challenge solver")
+		}
+		acmeConfig.DNS01Solver = dnsSolver
+	}
 	config.Issuers = []certmagic.Issuer{certmagic.NewACMEIssuer(config, acmeConfig)}
 	config = certmagic.New(certmagic.NewCache(certmagic.CacheOptions{
 		GetConfigForCert: func(certificate certmagic.Certificate) (*certmagic.Config, error) {
@@ -84,3 +93,36 @@ func startACME(ctx context.Context, options option.InboundACMEOptions) (*tls.Con
 	}), *config)
 	return config.TLSConfig(), &acmeWrapper{ctx, config, options.Domain}, nil
 }
+
+func newDNS01Solver(options option.ACMEDNS01ChallengeOptions) (*certmagic.DNS01Solver, error) {
+	var (
+		provider certmagic.ACMEDNSProvider
+		err      error
+	)
+	switch options.Provider {
+	case C.DNSProviderRFC2136:
+		provider, err = newACMERFC2136Provider(options.RFC2136Options)
+	case C.DNSProviderWebhook:
+		provider, err = newACMEWebhookProvider(options.WebhookOptions)
+	default:
+		return nil, E.New("unknown dns provider: ", options.Provider)
+	}
+	if err != nil {
+		return nil, E.Cause(err, options.Provider)
+	}
+	ttl := time.Duration(options.TTL)
+	if ttl == 0 {
+		ttl = 2 * time.Minute
+	}
+	propagationTimeout := time.Duration(options.PropagationTimeout)
+	if propagationTimeout < 0 {
+		propagationTimeout = -1
+	}
+	return &certmagic.DNS01Solver{
+		DNSProvider:        provider,
+		TTL:                ttl,
+		PropagationDelay:   time.Duration(options.PropagationDelay),
+		PropagationTimeout: propagationTimeout,
+		Resolvers:          options.Resolvers,
+	}, nil
+}
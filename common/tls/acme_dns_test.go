@@ -0,0 +1,280 @@
+//go:build with_acme
+
+package tls
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/common/json"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/libdns/libdns"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNS01SolverOptions(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name    string
+		options option.ACMEDNS01ChallengeOptions
+		error   string
+	}{
+		{"unknown provider", option.ACMEDNS01ChallengeOptions{Provider: "unknown"}, "unknown dns provider"},
+		{"missing server", option.ACMEDNS01ChallengeOptions{Provider: C.DNSProviderRFC2136}, "missing server"},
+		{"unknown network", option.ACMEDNS01ChallengeOptions{
+			Provider:       C.DNSProviderRFC2136,
+			RFC2136Options: option.ACMEDNSRFC2136Options{Server: "127.0.0.1", Network: "quic"},
+		}, "unknown network"},
+		{"missing tsig secret", option.ACMEDNS01ChallengeOptions{
+			Provider:       C.DNSProviderRFC2136,
+			RFC2136Options: option.ACMEDNSRFC2136Options{Server: "127.0.0.1", TSIGKeyName: "key"},
+		}, "missing tsig_secret"},
+		{"unknown tsig algorithm", option.ACMEDNS01ChallengeOptions{
+			Provider:       C.DNSProviderRFC2136,
+			RFC2136Options: option.ACMEDNSRFC2136Options{Server: "127.0.0.1", TSIGKeyName: "key", TSIGSecret: "c2VjcmV0", TSIGAlgorithm: "hmac-md5"},
+		}, "unknown tsig_algorithm"},
+		{"missing url", option.ACMEDNS01ChallengeOptions{Provider: C.DNSProviderWebhook}, "missing url"},
+		{"unsupported scheme", option.ACMEDNS01ChallengeOptions{
+			Provider:       C.DNSProviderWebhook,
+			WebhookOptions: option.ACMEDNSWebhookOptions{URL: "ftp://example.org"},
+		}, "unsupported url scheme"},
+	} {
+		_, err := newDNS01Solver(testCase.options)
+		require.ErrorContains(t, err, testCase.error, testCase.name)
+	}
+	solver, err := newDNS01Solver(option.ACMEDNS01ChallengeOptions{
+		Provider:           C.DNSProviderRFC2136,
+		PropagationTimeout: option.Duration(-time.Second),
+		RFC2136Options:     option.ACMEDNSRFC2136Options{Server: "127.0.0.1"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Minute, solver.TTL)
+	require.Equal(t, time.Duration(-1), solver.PropagationTimeout)
+	require.Equal(t, "127.0.0.1:53", solver.DNSProvider.(*acmeRFC2136Provider).server)
+}
+
+type testDNSUpdateServer struct {
+	access   sync.Mutex
+	updates  []*dns.Msg
+	tsigErrs []error
+}
+
+func (s *testDNSUpdateServer) ServeDNS(w dns.ResponseWriter, request *dns.Msg) {
+	s.access.Lock()
+	s.updates = append(s.updates, request)
+	s.tsigErrs = append(s.tsigErrs, w.TsigStatus())
+	s.access.Unlock()
+	response := new(dns.Msg)
+	if tsig := request.IsTsig(); tsig != nil {
+		if w.TsigStatus() != nil {
+			response.SetRcode(request, dns.RcodeNotAuth)
+			w.WriteMsg(response)
+			return
+		}
+		response.SetReply(request)
+		response.SetTsig(tsig.Hdr.Name, tsig.Algorithm, 300, time.Now().Unix())
+	} else {
+		response.SetRcode(request, dns.RcodeRefused)
+	}
+	w.WriteMsg(response)
+}
+
+func (s *testDNSUpdateServer) Updates() ([]*dns.Msg, []error) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	return append([]*dns.Msg(nil), s.updates...), append([]error(nil), s.tsigErrs...)
+}
+
+func startTestDNSUpdateServer(t *testing.T, network string, tsigSecret map[string]string) (*testDNSUpdateServer, string) {
+	handler := &testDNSUpdateServer{}
+	server := &dns.Server{
+		Handler:    handler,
+		TsigSecret: tsigSecret,
+		MsgAcceptFunc: func(dh dns.Header) dns.MsgAcceptAction {
+			return dns.MsgAccept
+		},
+	}
+	var address string
+	if network == "udp" {
+		packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		server.PacketConn = packetConn
+		address = packetConn.LocalAddr().String()
+	} else {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		server.Listener = listener
+		address = listener.Addr().String()
+	}
+	started := make(chan struct{})
+	server.NotifyStartedFunc = func() {
+		close(started)
+	}
+	go server.ActivateAndServe()
+	<-started
+	t.Cleanup(func() {
+		server.Shutdown()
+	})
+	return handler, address
+}
+
+func TestACMERFC2136Provider(t *testing.T) {
+	t.Parallel()
+	secret := base64.StdEncoding.EncodeToString([]byte("test secret"))
+	records := []libdns.Record{{Type: "TXT", Name: "_acme-challenge.www", Value: "token", TTL: 2 * time.Minute}}
+	for _, network := range []string{"udp", "tcp"} {
+		handler, address := startTestDNSUpdateServer(t, network, map[string]string{"update-key.": secret})
+		provider, err := newACMERFC2136Provider(option.ACMEDNSRFC2136Options{
+			Server:        address,
+			Network:       network,
+			TSIGKeyName:   "update-key",
+			TSIGSecret:    secret,
+			TSIGAlgorithm: "HMAC-SHA256",
+		})
+		require.NoError(t, err)
+		_, err = provider.AppendRecords(context.Background(), "example.org.", records)
+		require.NoError(t, err)
+		_, err = provider.DeleteRecords(context.Background(), "example.org.", records)
+		require.NoError(t, err)
+
+		updates, tsigErrs := handler.Updates()
+		require.Len(t, updates, 2)
+		for i, class := range []uint16{dns.ClassINET, dns.ClassNONE} {
+			update := updates[i]
+			require.NoError(t, tsigErrs[i])
+			require.Equal(t, dns.OpcodeUpdate, update.Opcode)
+			require.Equal(t, "example.org.", update.Question[0].Name)
+			require.Equal(t, dns.TypeSOA, update.Question[0].Qtype)
+			require.Len(t, update.Ns, 1)
+			txt := update.Ns[0].(*dns.TXT)
+			require.Equal(t, "_acme-challenge.www.example.org.", txt.Hdr.Name)
+			require.Equal(t, class, txt.Hdr.Class)
+			require.Equal(t, []string{"token"}, txt.Txt)
+			tsig := update.IsTsig()
+			require.NotNil(t, tsig)
+			require.Equal(t, "update-key.", tsig.Hdr.Name)
+			require.Equal(t, dns.HmacSHA256, tsig.Algorithm)
+		}
+		require.Equal(t, uint32(120), updates[0].Ns[0].Header().Ttl)
+		require.Zero(t, updates[1].Ns[0].Header().Ttl)
+	}
+}
+
+func TestACMERFC2136ProviderFailure(t *testing.T) {
+	t.Parallel()
+	secret := base64.StdEncoding.EncodeToString([]byte("test secret"))
+	_, address := startTestDNSUpdateServer(t, "udp", map[string]string{"update-key.": secret})
+	records := []libdns.Record{{Type: "TXT", Name: "_acme-challenge", Value: "token"}}
+
+	provider, err := newACMERFC2136Provider(option.ACMEDNSRFC2136Options{
+		Server:      address,
+		TSIGKeyName: "update-key",
+		TSIGSecret:  base64.StdEncoding.EncodeToString([]byte("wrong secret")),
+	})
+	require.NoError(t, err)
+	_, err = provider.AppendRecords(context.Background(), "example.org.", records)
+	require.ErrorContains(t, err, "NOTAUTH")
+
+	provider, err = newACMERFC2136Provider(option.ACMEDNSRFC2136Options{Server: address})
+	require.NoError(t, err)
+	_, err = provider.AppendRecords(context.Background(), "example.org.", records)
+	require.ErrorContains(t, err, "REFUSED")
+
+	_, err = provider.AppendRecords(context.Background(), "example.org.", []libdns.Record{{Type: "A", Name: "www", Value: "127.0.0.1"}})
+	require.ErrorContains(t, err, "unsupported record type")
+}
+
+func TestACMEWebhookProvider(t *testing.T) {
+	t.Parallel()
+	type webhookRequest struct {
+		path          string
+		authorization string
+		header        string
+		contentType   string
+		body          acmeWebhookRequest
+	}
+	requests := make(chan webhookRequest, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		var body acmeWebhookRequest
+		content, err := io.ReadAll(request.Body)
+		if err == nil {
+			err = json.Unmarshal(content, &body)
+		}
+		if err != nil || request.Method != http.MethodPost {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests <- webhookRequest{
+			path:          request.URL.Path,
+			authorization: request.Header.Get("Authorization"),
+			header:        request.Header.Get("X-Test"),
+			contentType:   request.Header.Get("Content-Type"),
+			body:          body,
+		}
+		writer.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	provider, err := newACMEWebhookProvider(option.ACMEDNSWebhookOptions{
+		URL:      server.URL + "/dns/",
+		Username: "user",
+		Password: "pass",
+		Headers:  map[string]option.Listable[string]{"X-Test": {"value"}},
+	})
+	require.NoError(t, err)
+	records := []libdns.Record{{Type: "TXT", Name: "_acme-challenge", Value: "token", TTL: time.Minute}}
+	_, err = provider.AppendRecords(context.Background(), "example.org.", records)
+	require.NoError(t, err)
+	_, err = provider.DeleteRecords(context.Background(), "example.org.", records)
+	require.NoError(t, err)
+	for _, action := range []string{"present", "cleanup"} {
+		request := <-requests
+		require.Equal(t, "/dns/"+action, request.path)
+		require.Equal(t, "Basic "+base64.StdEncoding.EncodeToString([]byte("user:pass")), request.authorization)
+		require.Equal(t, "value", request.header)
+		require.Equal(t, "application/json", request.contentType)
+		require.Equal(t, acmeWebhookRequest{
+			Zone:  "example.org.",
+			Name:  "_acme-challenge",
+			FQDN:  "_acme-challenge.example.org.",
+			Type:  "TXT",
+			Value: "token",
+			TTL:   60,
+		}, request.body)
+	}
+}
+
+func TestACMEWebhookProviderFailure(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Authorization") != "" {
+			writer.WriteHeader(http.StatusInternalServerError)
+			writer.Write([]byte("zone not found\n"))
+			return
+		}
+		writer.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+	records := []libdns.Record{{Type: "TXT", Name: "_acme-challenge", Value: "token"}}
+
+	provider, err := newACMEWebhookProvider(option.ACMEDNSWebhookOptions{URL: server.URL})
+	require.NoError(t, err)
+	_, err = provider.AppendRecords(context.Background(), "example.org.", records)
+	require.ErrorContains(t, err, "401")
+
+	provider, err = newACMEWebhookProvider(option.ACMEDNSWebhookOptions{URL: server.URL, Username: "user"})
+	require.NoError(t, err)
+	_, err = provider.DeleteRecords(context.Background(), "example.org.", records)
+	require.ErrorContains(t, err, "webhook cleanup")
+	require.ErrorContains(t, err, "500")
+	require.ErrorContains(t, err, "zone not found")
+}
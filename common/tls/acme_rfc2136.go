@@ -0,0 +1,139 @@
+//go:build with_acme
+
+package tls
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/libdns/libdns"
+	"github.com/miekg/dns"
+)
+
+type acmeRFC2136Provider struct {
+	server        string
+	network       string
+	tsigKeyName   string
+	tsigSecret    string
+	tsigAlgorithm string
+}
+
+func newACMERFC2136Provider(options option.ACMEDNSRFC2136Options) (*acmeRFC2136Provider, error) {
+	if options.Server == "" {
+		return nil, E.New("missing server")
+	}
+	serverAddress := M.ParseSocksaddr(options.Server)
+	if serverAddress.Port == 0 {
+		serverAddress.Port = 53
+	}
+	provider := &acmeRFC2136Provider{
+		server: serverAddress.String(),
+	}
+	switch options.Network {
+	case "", N.NetworkUDP:
+		provider.network = N.NetworkUDP
+	case N.NetworkTCP:
+		provider.network = N.NetworkTCP
+	default:
+		return nil, E.New("unknown network: ", options.Network)
+	}
+	if options.TSIGKeyName != "" {
+		if options.TSIGSecret == "" {
+			return nil, E.New("missing tsig_secret")
+		}
+		switch strings.ToLower(options.TSIGAlgorithm) {
+		case "", "hmac-sha256":
+			provider.tsigAlgorithm = dns.HmacSHA256
+		case "hmac-sha1":
+			provider.tsigAlgorithm = dns.HmacSHA1
+		case "hmac-sha224":
+			provider.tsigAlgorithm = dns.HmacSHA224
+		case "hmac-sha384":
+			provider.tsigAlgorithm = dns.HmacSHA384
+		case "hmac-sha512":
+			provider.tsigAlgorithm = dns.HmacSHA512
+		default:
+			return nil, E.New("unknown tsig_algorithm: ", options.TSIGAlgorithm)
+		}
+		provider.tsigKeyName = dns.CanonicalName(options.TSIGKeyName)
+		provider.tsigSecret = options.TSIGSecret
+	}
+	return provider, nil
+}
+
+func (p *acmeRFC2136Provider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	resourceRecords, err := p.resourceRecords(zone, records)
+	if err != nil {
+		return nil, err
+	}
+	message := new(dns.Msg)
+	message.SetUpdate(dns.Fqdn(zone))
+	message.Insert(resourceRecords)
+	err = p.exchange(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (p *acmeRFC2136Provider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	resourceRecords, err := p.resourceRecords(zone, records)
+	if err != nil {
+		return nil, err
+	}
+	message := new(dns.Msg)
+	message.SetUpdate(dns.Fqdn(zone))
+	message.Remove(resourceRecords)
+	err = p.exchange(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (p *acmeRFC2136Provider) resourceRecords(zone string, records []libdns.Record) ([]dns.RR, error) {
+	resourceRecords := make([]dns.RR, 0, len(records))
+	for _, record := range records {
+		if record.Type != "TXT" {
+			return nil, E.New("unsupported record type: ", record.Type)
+		}
+		resourceRecords = append(resourceRecords, &dns.TXT{
+			Hdr: dns.RR_Header{
+				Name:   dns.Fqdn(libdns.AbsoluteName(record.Name, zone)),
+				Rrtype: dns.TypeTXT,
+				Class:  dns.ClassINET,
+				Ttl:    uint32(record.TTL / time.Second),
+			},
+			Txt: []string{record.Value},
+		})
+	}
+	return resourceRecords, nil
+}
+
+func (p *acmeRFC2136Provider) exchange(ctx context.Context, message *dns.Msg) error {
+	client := &dns.Client{
+		Net: p.network,
+		Dialer: &net.Dialer{
+			Timeout: 10 * time.Second,
+		},
+	}
+	if p.tsigKeyName != "" {
+		message.SetTsig(p.tsigKeyName, p.tsigAlgorithm, 300, time.Now().Unix())
+		client.TsigSecret = map[string]string{p.tsigKeyName: p.tsigSecret}
+	}
+	response, _, err := client.ExchangeContext(ctx, message, p.server)
+	if err != nil {
+		return E.Cause(err, "exchange dns update")
+	}
+	if response.Rcode != dns.RcodeSuccess {
+		return E.New("dns update failed: ", dns.RcodeToString[response.Rcode])
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+//go:build with_acme
+
+package tls
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/common/json"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/libdns/libdns"
+)
+
+type acmeWebhookProvider struct {
+	client   *http.Client
+	url      string
+	username string
+	password string
+	headers  http.Header
+}
+
+type acmeWebhookRequest struct {
+	Zone  string `json:"zone"`
+	Name  string `json:"name"`
+	FQDN  string `json:"fqdn"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+	TTL   int    `json:"ttl"`
+}
+
+func newACMEWebhookProvider(options option.ACMEDNSWebhookOptions) (*acmeWebhookProvider, error) {
+	if options.URL == "" {
+		return nil, E.New("missing url")
+	}
+	webhookURL, err := url.Parse(options.URL)
+	if err != nil {
+		return nil, E.Cause(err, "parse url")
+	}
+	if webhookURL.Scheme != "http" && webhookURL.Scheme != "https" {
+		return nil, E.New("unsupported url scheme: ", webhookURL.Scheme)
+	}
+	headers := make(http.Header)
+	for key, values := range options.Headers {
+		for _, value := range values {
+			headers.Add(key, value)
+		}
+	}
+	return &acmeWebhookProvider{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		url:      strings.TrimSuffix(options.URL, "/"),
+		username: options.Username,
+		password: options.Password,
+		headers:  headers,
+	}, nil
+}
+
+func (p *acmeWebhookProvider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	for _, record := range records {
+		err := p.request(ctx, "present", zone, record)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+func (p *acmeWebhookProvider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	for _, record := range records {
+		err := p.request(ctx, "cleanup", zone, record)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+func (p *acmeWebhookProvider) request(ctx context.Context, action string, zone string, record libdns.Record) error {
+	content, err := json.Marshal(acmeWebhookRequest{
+		Zone:  zone,
+		Name:  record.Name,
+		FQDN:  libdns.AbsoluteName(record.Name, zone),
+		Type:  record.Type,
+		Value: record.Value,
+		TTL:   int(record.TTL / time.Second),
+	})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url+"/"+action, bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	for key, values := range p.headers {
+		request.Header[key] = values
+	}
+	request.Header.Set("Content-Type", "application/json")
+	if p.username != "" {
+		request.SetBasicAuth(p.username, p.password)
+	}
+	response, err := p.client.Do(request)
+	if err != nil {
+		return E.Cause(err, "webhook ", action)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return E.New("webhook ", action, ": unexpected status: ", response.Status, " ", strings.TrimSpace(string(message)))
+	}
+	return nil
+}
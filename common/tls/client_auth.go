@@ -0,0 +1,103 @@
+package tls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func parseClientAuthentication(options option.InboundTLSOptions, clientCertificate []byte) (tls.ClientAuthType, error) {
+	var clientAuth tls.ClientAuthType
+	switch options.ClientAuthentication {
+	case "":
+		if clientCertificate != nil {
+			clientAuth = tls.RequireAndVerifyClientCert
+		}
+	case "no":
+		clientAuth = tls.NoClientCert
+	case "request":
+		clientAuth = tls.RequestClientCert
+	case "require-any":
+		clientAuth = tls.RequireAnyClientCert
+	case "verify-if-given":
+		clientAuth = tls.VerifyClientCertIfGiven
+	case "require-and-verify":
+		clientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return 0, E.New("unknown client_authentication: ", options.ClientAuthentication)
+	}
+	if clientAuth >= tls.VerifyClientCertIfGiven && clientCertificate == nil {
+		return 0, E.New("missing client_certificate")
+	} else if clientAuth < tls.VerifyClientCertIfGiven && clientCertificate != nil {
+		return 0, E.New("client_certificate is unused with client_authentication=", options.ClientAuthentication)
+	}
+	return clientAuth, nil
+}
+
+func readClientCertificate(certificate []string, certificatePath []string) ([]byte, error) {
+	var content []byte
+	if len(certificate) > 0 {
+		content = []byte(strings.Join(certificate, "\n"))
+	}
+	for _, path := range certificatePath {
+		fileContent, err := os.ReadFile(path)
+		if err != nil {
+			return nil, E.Cause(err, "read client certificate from ", path)
+		}
+		if content != nil {
+			content = append(content, '\n')
+		}
+		content = append(content, fileContent...)
+	}
+	return content, nil
+}
+
+func parseClientCertificate(content []byte) (*x509.CertPool, error) {
+	certPool := x509.NewCertPool()
+	if !certPool.AppendCertsFromPEM(content) {
+		return nil, E.New("failed to parse client certificate:\n\n", content)
+	}
+	return certPool, nil
+}
+
+func readClientKeyPair(options option.OutboundTLSOptions) (certificate []byte, key []byte, err error) {
+	if options.ClientCertificate != "" {
+		certificate = []byte(options.ClientCertificate)
+	} else if options.ClientCertificatePath != "" {
+		certificate, err = os.ReadFile(options.ClientCertificatePath)
+		if err != nil {
+			return nil, nil, E.Cause(err, "read client certificate")
+		}
+	}
+	if options.ClientKey != "" {
+		key = []byte(options.ClientKey)
+	} else if options.ClientKeyPath != "" {
+		key, err = os.ReadFile(options.ClientKeyPath)
+		if err != nil {
+			return nil, nil, E.Cause(err, "read client key")
+		}
+	}
+	if certificate == nil && key != nil {
+		return nil, nil, E.New("missing client certificate")
+	} else if certificate != nil && key == nil {
+		return nil, nil, E.New("missing client key")
+	}
+	return
+}
+
+// ClientCertificateUser returns the common name (or the full subject if empty)
+// of a verified client certificate.
+func ClientCertificateUser(verifiedChains [][]*x509.Certificate) string {
+	if len(verifiedChains) == 0 || len(verifiedChains[0]) == 0 {
+		return ""
+	}
+	subject := verifiedChains[0][0].Subject
+	if subject.CommonName != "" {
+		return subject.CommonName
+	}
+	return subject.String()
+}
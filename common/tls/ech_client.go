@@ -89,7 +89,11 @@ func NewECHClient(router adapter.Router, serverAddress string, options option.Ou
 			serverName = serverAddress
 		}
 	}
-	if serverName == "" && !options.Insecure {
+	pinnedVerifier, err := newPinnedCertificateVerifier(options)
+	if err != nil {
+		return nil, err
+	}
+	if serverName == "" && !options.Insecure && pinnedVerifier == nil {
 		return nil, E.New("missing server_name or insecure=true")
 	}
 
@@ -102,6 +106,9 @@ func NewECHClient(router adapter.Router, serverAddress string, options option.Ou
 	}
 	if options.Insecure {
 		tlsConfig.InsecureSkipVerify = options.Insecure
+	} else if pinnedVerifier != nil {
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = pinnedVerifier.VerifyPeerCertificate
 	} else if options.DisableSNI {
 		tlsConfig.InsecureSkipVerify = true
 		tlsConfig.VerifyConnection = func(state cftls.ConnectionState) error {
@@ -162,6 +169,17 @@ func NewECHClient(router adapter.Router, serverAddress string, options option.Ou
 		}
 		tlsConfig.RootCAs = certPool
 	}
+	clientCertificate, clientKey, err := readClientKeyPair(options)
+	if err != nil {
+		return nil, err
+	}
+	if clientCertificate != nil {
+		keyPair, err := cftls.X509KeyPair(clientCertificate, clientKey)
+		if err != nil {
+			return nil, E.Cause(err, "parse client x509 key pair")
+		}
+		tlsConfig.Certificates = []cftls.Certificate{keyPair}
+	}
 
 	// ECH Config
 
@@ -0,0 +1,81 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+const (
+	echVersionDraft13 = 0xfe0d
+
+	hpkeKEMX25519HKDFSHA256  = 0x0020
+	hpkeKDFHKDFSHA256        = 0x0001
+	hpkeAEADAES128GCM        = 0x0001
+	hpkeAEADChaCha20Poly1305 = 0x0003
+)
+
+func ECHKeygenDefault(publicName string) (configList []byte, keyPem string, err error) {
+	if publicName == "" || len(publicName) > 255 {
+		return nil, "", E.New("invalid public name: ", publicName)
+	}
+	var configID [1]byte
+	_, err = rand.Read(configID[:])
+	if err != nil {
+		return
+	}
+	privateKey := make([]byte, curve25519.ScalarSize)
+	_, err = rand.Read(privateKey)
+	if err != nil {
+		return
+	}
+	publicKey, err := curve25519.X25519(privateKey, curve25519.Basepoint)
+	if err != nil {
+		return
+	}
+	config := marshalECHConfig(configID[0], publicKey, publicName)
+
+	var configBuffer bytes.Buffer
+	binary.Write(&configBuffer, binary.BigEndian, uint16(len(config)))
+	configBuffer.Write(config)
+
+	var keyBuffer bytes.Buffer
+	binary.Write(&keyBuffer, binary.BigEndian, uint16(len(privateKey)))
+	keyBuffer.Write(privateKey)
+	binary.Write(&keyBuffer, binary.BigEndian, uint16(len(config)))
+	keyBuffer.Write(config)
+
+	configList = configBuffer.Bytes()
+	keyPem = string(pem.EncodeToMemory(&pem.Block{Type: "ECH KEYS", Bytes: keyBuffer.Bytes()}))
+	return
+}
+
+func marshalECHConfig(configID byte, publicKey []byte, publicName string) []byte {
+	var contents bytes.Buffer
+	contents.WriteByte(configID)
+	binary.Write(&contents, binary.BigEndian, uint16(hpkeKEMX25519HKDFSHA256))
+	binary.Write(&contents, binary.BigEndian, uint16(len(publicKey)))
+	contents.Write(publicKey)
+	binary.Write(&contents, binary.BigEndian, uint16(2*4))
+	for _, aead := range []uint16{hpkeAEADAES128GCM, hpkeAEADChaCha20Poly1305} {
+		binary.Write(&contents, binary.BigEndian, uint16(hpkeKDFHKDFSHA256))
+		binary.Write(&contents, binary.BigEndian, aead)
+	}
+	// maximum_name_length
+	contents.WriteByte(0)
+	contents.WriteByte(byte(len(publicName)))
+	contents.WriteString(publicName)
+	// extensions
+	binary.Write(&contents, binary.BigEndian, uint16(0))
+
+	var config bytes.Buffer
+	binary.Write(&config, binary.BigEndian, uint16(echVersionDraft13))
+	binary.Write(&config, binary.BigEndian, uint16(contents.Len()))
+	config.Write(contents.Bytes())
+	return config.Bytes()
+}
@@ -0,0 +1,391 @@
+//go:build with_ech
+
+package tls
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	cftls "github.com/sagernet/cloudflare-tls"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/fswatch"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/atomic"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+type ECHServerConfig struct {
+	config                   *cftls.Config
+	logger                   log.Logger
+	certificate              []byte
+	key                      []byte
+	certificatePath          string
+	keyPath                  string
+	echKey                   []byte
+	echKeyPath               string
+	echKeys                  *echKeySetProvider
+	keyPair                  atomic.TypedValue[*cftls.Certificate]
+	clientCertificate        []string
+	clientCertificatePath    []string
+	clientCertificateContent []byte
+	clientCAs                *atomic.TypedValue[*x509.CertPool]
+	watcher                  *fswatch.Watcher
+}
+
+func (c *ECHServerConfig) ServerName() string {
+	return c.config.ServerName
+}
+
+func (c *ECHServerConfig) SetServerName(serverName string) {
+	c.config.ServerName = serverName
+}
+
+func (c *ECHServerConfig) NextProtos() []string {
+	return c.config.NextProtos
+}
+
+func (c *ECHServerConfig) SetNextProtos(nextProto []string) {
+	c.config.NextProtos = nextProto
+}
+
+func (c *ECHServerConfig) Config() (*STDConfig, error) {
+	return nil, E.New("unsupported usage for ECH")
+}
+
+func (c *ECHServerConfig) Client(conn net.Conn) (Conn, error) {
+	return &echConnWrapper{cftls.Client(conn, c.config)}, nil
+}
+
+func (c *ECHServerConfig) Server(conn net.Conn) (Conn, error) {
+	return &echConnWrapper{cftls.Server(conn, c.config)}, nil
+}
+
+func (c *ECHServerConfig) Clone() Config {
+	config := &ECHServerConfig{
+		config:    c.config.Clone(),
+		clientCAs: c.clientCAs,
+	}
+	if c.config.GetConfigForClient != nil {
+		config.config.GetConfigForClient = config.getConfigForClient
+	}
+	return config
+}
+
+func (c *ECHServerConfig) Start() error {
+	err := c.startWatcher()
+	if err != nil {
+		c.logger.Warn("create fsnotify watcher: ", err)
+	}
+	return nil
+}
+
+func (c *ECHServerConfig) startWatcher() error {
+	paths := append([]string{c.certificatePath, c.keyPath, c.echKeyPath}, c.clientCertificatePath...)
+	watcher, err := fswatch.New(c.logger, paths, c.reload)
+	if err != nil {
+		return err
+	}
+	c.watcher = watcher
+	return nil
+}
+
+func (c *ECHServerConfig) reload() {
+	if c.certificatePath != "" || c.keyPath != "" {
+		err := c.reloadKeyPair()
+		if err != nil {
+			c.logger.Error(E.Cause(err, "reload TLS key pair"))
+		}
+	}
+	if c.echKeyPath != "" {
+		err := c.reloadECHKeys()
+		if err != nil {
+			c.logger.Error(E.Cause(err, "reload ECH keys"))
+		}
+	}
+	if len(c.clientCertificatePath) > 0 {
+		err := c.reloadClientCertificate()
+		if err != nil {
+			c.logger.Error(E.Cause(err, "reload client certificate"))
+		}
+	}
+}
+
+func (c *ECHServerConfig) reloadKeyPair() error {
+	certificate := c.certificate
+	if c.certificatePath != "" {
+		content, err := os.ReadFile(c.certificatePath)
+		if err != nil {
+			return E.Cause(err, "reload certificate from ", c.certificatePath)
+		}
+		certificate = content
+	}
+	key := c.key
+	if c.keyPath != "" {
+		content, err := os.ReadFile(c.keyPath)
+		if err != nil {
+			return E.Cause(err, "reload key from ", c.keyPath)
+		}
+		key = content
+	}
+	if bytes.Equal(certificate, c.certificate) && bytes.Equal(key, c.key) {
+		return nil
+	}
+	keyPair, err := parseECHKeyPair(certificate, key)
+	if err != nil {
+		return err
+	}
+	c.certificate = certificate
+	c.key = key
+	c.keyPair.Store(keyPair)
+	c.logger.Info("reloaded TLS certificate, expires at ", keyPair.Leaf.NotAfter.Format(time.RFC3339))
+	return nil
+}
+
+func (c *ECHServerConfig) reloadECHKeys() error {
+	content, err := os.ReadFile(c.echKeyPath)
+	if err != nil {
+		return E.Cause(err, "reload ECH keys from ", c.echKeyPath)
+	}
+	if bytes.Equal(content, c.echKey) {
+		return nil
+	}
+	keySet, err := parseECHKeys(content)
+	if err != nil {
+		return err
+	}
+	c.echKey = content
+	c.echKeys.keySet.Store(keySet)
+	c.logger.Info("reloaded ECH keys")
+	return nil
+}
+
+func (c *ECHServerConfig) reloadClientCertificate() error {
+	content, err := readClientCertificate(c.clientCertificate, c.clientCertificatePath)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(content, c.clientCertificateContent) {
+		return nil
+	}
+	clientCAs, err := parseClientCertificate(content)
+	if err != nil {
+		return err
+	}
+	c.clientCertificateContent = content
+	c.clientCAs.Store(clientCAs)
+	c.logger.Info("reloaded TLS client certificate")
+	return nil
+}
+
+func (c *ECHServerConfig) getConfigForClient(*cftls.ClientHelloInfo) (*cftls.Config, error) {
+	clientCAs := c.clientCAs.Load()
+	if clientCAs == c.config.ClientCAs {
+		return nil, nil
+	}
+	config := c.config.Clone()
+	config.ClientCAs = clientCAs
+	return config, nil
+}
+
+func (c *ECHServerConfig) Certificates() []*x509.Certificate {
+	return []*x509.Certificate{c.keyPair.Load().Leaf}
+}
+
+func (c *ECHServerConfig) Close() error {
+	if c.watcher != nil {
+		return c.watcher.Close()
+	}
+	return nil
+}
+
+func NewECHServer(ctx context.Context, router adapter.Router, logger log.Logger, options option.InboundTLSOptions) (ServerConfig, error) {
+	if options.ACME != nil && len(options.ACME.Domain) > 0 {
+		return nil, E.New("acme is unavailable in ech")
+	}
+	if len(options.Certificates) > 0 {
+		return nil, E.New("certificates is unavailable in ech")
+	}
+	var tlsConfig cftls.Config
+	tlsConfig.Time = router.TimeFunc()
+	if options.ServerName != "" {
+		tlsConfig.ServerName = options.ServerName
+	}
+	if len(options.ALPN) > 0 {
+		tlsConfig.NextProtos = options.ALPN
+	}
+	if options.MinVersion != "" {
+		minVersion, err := ParseTLSVersion(options.MinVersion)
+		if err != nil {
+			return nil, E.Cause(err, "parse min_version")
+		}
+		tlsConfig.MinVersion = minVersion
+	}
+	if options.MaxVersion != "" {
+		maxVersion, err := ParseTLSVersion(options.MaxVersion)
+		if err != nil {
+			return nil, E.Cause(err, "parse max_version")
+		}
+		tlsConfig.MaxVersion = maxVersion
+	}
+	if options.CipherSuites != nil {
+	find:
+		for _, cipherSuite := range options.CipherSuites {
+			for _, tlsCipherSuite := range cftls.CipherSuites() {
+				if cipherSuite == tlsCipherSuite.Name {
+					tlsConfig.CipherSuites = append(tlsConfig.CipherSuites, tlsCipherSuite.ID)
+					continue find
+				}
+			}
+			return nil, E.New("unknown cipher_suite: ", cipherSuite)
+		}
+	}
+	var certificate []byte
+	var key []byte
+	if options.Certificate != "" {
+		certificate = []byte(options.Certificate)
+	} else if options.CertificatePath != "" {
+		content, err := os.ReadFile(options.CertificatePath)
+		if err != nil {
+			return nil, E.Cause(err, "read certificate")
+		}
+		certificate = content
+	}
+	if options.Key != "" {
+		key = []byte(options.Key)
+	} else if options.KeyPath != "" {
+		content, err := os.ReadFile(options.KeyPath)
+		if err != nil {
+			return nil, E.Cause(err, "read key")
+		}
+		key = content
+	}
+	if certificate == nil {
+		return nil, E.New("missing certificate")
+	} else if key == nil {
+		return nil, E.New("missing key")
+	}
+	keyPair, err := parseECHKeyPair(certificate, key)
+	if err != nil {
+		return nil, err
+	}
+
+	var echKey []byte
+	if len(options.ECH.Key) > 0 {
+		echKey = []byte(strings.Join(options.ECH.Key, "\n"))
+	} else if options.ECH.KeyPath != "" {
+		content, err := os.ReadFile(options.ECH.KeyPath)
+		if err != nil {
+			return nil, E.Cause(err, "read ECH keys")
+		}
+		echKey = content
+	} else {
+		return nil, E.New("missing ECH keys")
+	}
+	keySet, err := parseECHKeys(echKey)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.ECHEnabled = true
+	tlsConfig.PQSignatureSchemesEnabled = options.ECH.PQSignatureSchemesEnabled
+	tlsConfig.DynamicRecordSizingDisabled = options.ECH.DynamicRecordSizingDisabled
+	echKeys := new(echKeySetProvider)
+	echKeys.keySet.Store(keySet)
+	tlsConfig.ServerECHProvider = echKeys
+
+	serverConfig := &ECHServerConfig{
+		config:                &tlsConfig,
+		logger:                logger,
+		certificate:           certificate,
+		key:                   key,
+		certificatePath:       options.CertificatePath,
+		keyPath:               options.KeyPath,
+		echKey:                echKey,
+		echKeyPath:            options.ECH.KeyPath,
+		echKeys:               echKeys,
+		clientCertificate:     options.ClientCertificate,
+		clientCertificatePath: options.ClientCertificatePath,
+		clientCAs:             new(atomic.TypedValue[*x509.CertPool]),
+	}
+	clientCertificate, err := readClientCertificate(options.ClientCertificate, options.ClientCertificatePath)
+	if err != nil {
+		return nil, err
+	}
+	clientAuth, err := parseClientAuthentication(options, clientCertificate)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.ClientAuth = cftls.ClientAuthType(clientAuth)
+	if clientCertificate != nil {
+		clientCAs, err := parseClientCertificate(clientCertificate)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientCAs = clientCAs
+		serverConfig.clientCertificateContent = clientCertificate
+		serverConfig.clientCAs.Store(clientCAs)
+		if len(options.ClientCertificatePath) > 0 {
+			tlsConfig.GetConfigForClient = serverConfig.getConfigForClient
+		}
+	}
+	serverConfig.keyPair.Store(keyPair)
+	tlsConfig.GetCertificate = func(*cftls.ClientHelloInfo) (*cftls.Certificate, error) {
+		return serverConfig.keyPair.Load(), nil
+	}
+	return serverConfig, nil
+}
+
+// echKeySetProvider lets reloaded ECH keys be swapped in without writing to
+// the tls config, which is shared with in-flight handshakes.
+type echKeySetProvider struct {
+	keySet atomic.Pointer[cftls.EXP_ECHKeySet]
+}
+
+func (p *echKeySetProvider) GetDecryptionContext(rawHandle []byte, version uint16) cftls.ECHProviderResult {
+	return p.keySet.Load().GetDecryptionContext(rawHandle, version)
+}
+
+func parseECHKeyPair(certificate []byte, key []byte) (*cftls.Certificate, error) {
+	keyPair, err := cftls.X509KeyPair(certificate, key)
+	if err != nil {
+		return nil, E.Cause(err, "parse x509 key pair")
+	}
+	keyPair.Leaf, err = x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, E.Cause(err, "parse x509 key pair")
+	}
+	return &keyPair, nil
+}
+
+func parseECHKeys(content []byte) (*cftls.EXP_ECHKeySet, error) {
+	var keys []cftls.EXP_ECHKey
+	for {
+		var block *pem.Block
+		block, content = pem.Decode(content)
+		if block == nil {
+			break
+		}
+		if block.Type != "ECH KEYS" {
+			return nil, E.New("invalid ECH keys pem: unexpected block type ", block.Type)
+		}
+		blockKeys, err := cftls.EXP_UnmarshalECHKeys(block.Bytes)
+		if err != nil {
+			return nil, E.Cause(err, "parse ECH keys")
+		}
+		keys = append(keys, blockKeys...)
+	}
+	if len(keys) == 0 {
+		return nil, E.New("missing ECH keys")
+	}
+	keySet, err := cftls.EXP_NewECHKeySet(keys)
+	if err != nil {
+		return nil, E.Cause(err, "create ECH key set")
+	}
+	return keySet, nil
+}
@@ -0,0 +1,84 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+type pinnedCertificateVerifier struct {
+	certificateSHA256 [][]byte
+	publicKeySHA256   [][]byte
+}
+
+func newPinnedCertificateVerifier(options option.OutboundTLSOptions) (*pinnedCertificateVerifier, error) {
+	if len(options.CertificateSHA256) == 0 && len(options.CertificatePublicKeySHA256) == 0 {
+		return nil, nil
+	}
+	if options.Insecure {
+		return nil, E.New("certificate pinning is unused with insecure")
+	}
+	certificateSHA256, err := parsePinnedSHA256(options.CertificateSHA256)
+	if err != nil {
+		return nil, E.Cause(err, "parse certificate_sha256")
+	}
+	publicKeySHA256, err := parsePinnedSHA256(options.CertificatePublicKeySHA256)
+	if err != nil {
+		return nil, E.Cause(err, "parse certificate_public_key_sha256")
+	}
+	return &pinnedCertificateVerifier{certificateSHA256, publicKeySHA256}, nil
+}
+
+func parsePinnedSHA256(pins []string) ([][]byte, error) {
+	var hashes [][]byte
+	for _, pin := range pins {
+		hash, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil {
+			return nil, err
+		}
+		if len(hash) != sha256.Size {
+			return nil, E.New("invalid SHA-256 hash: ", pin)
+		}
+		hashes = append(hashes, hash)
+	}
+	return hashes, nil
+}
+
+func (v *pinnedCertificateVerifier) VerifyPeerCertificate(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+	if len(rawCerts) == 0 {
+		return E.New("missing peer certificate")
+	}
+	certificateHash := sha256.Sum256(rawCerts[0])
+	for _, pin := range v.certificateSHA256 {
+		if bytes.Equal(certificateHash[:], pin) {
+			return nil
+		}
+	}
+	if len(v.publicKeySHA256) > 0 {
+		certificate, err := x509.ParseCertificate(rawCerts[0])
+		if err != nil {
+			return err
+		}
+		publicKeyHash := sha256.Sum256(certificate.RawSubjectPublicKeyInfo)
+		for _, pin := range v.publicKeySHA256 {
+			if bytes.Equal(publicKeyHash[:], pin) {
+				return nil
+			}
+		}
+	}
+	return E.New("peer certificate does not match any pin")
+}
+
+func CertificateSHA256(certificate *x509.Certificate) string {
+	hash := sha256.Sum256(certificate.Raw)
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+func CertificatePublicKeySHA256(certificate *x509.Certificate) string {
+	hash := sha256.Sum256(certificate.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
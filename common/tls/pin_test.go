@@ -0,0 +1,181 @@
+package tls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testRouter struct {
+	adapter.Router
+}
+
+func (r *testRouter) TimeFunc() func() time.Time {
+	return time.Now
+}
+
+func newTestCertificate(t *testing.T, key *ecdsa.PrivateKey, serialNumber int64) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serialNumber),
+		Subject:      pkix.Name{CommonName: "example.org"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	certificateDer, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(certificateDer)
+	require.NoError(t, err)
+	return certificate
+}
+
+func TestParsePinnedSHA256(t *testing.T) {
+	t.Parallel()
+	hash := sha256.Sum256([]byte("test"))
+	pin := base64.StdEncoding.EncodeToString(hash[:])
+	for _, testCase := range []struct {
+		name     string
+		pins     []string
+		expected [][]byte
+		error    bool
+	}{
+		{name: "empty"},
+		{name: "valid", pins: []string{pin, pin}, expected: [][]byte{hash[:], hash[:]}},
+		{name: "invalid base64", pins: []string{"not base64!"}, error: true},
+		{name: "missing padding", pins: []string{base64.RawStdEncoding.EncodeToString(hash[:])}, error: true},
+		{name: "short hash", pins: []string{base64.StdEncoding.EncodeToString(hash[:16])}, error: true},
+		{name: "hex", pins: []string{strings.Repeat("ab", sha256.Size)}, error: true},
+	} {
+		hashes, err := parsePinnedSHA256(testCase.pins)
+		if testCase.error {
+			require.Error(t, err, testCase.name)
+			continue
+		}
+		require.NoError(t, err, testCase.name)
+		if testCase.expected != nil {
+			require.Equal(t, testCase.expected, hashes, testCase.name)
+		}
+	}
+}
+
+func TestPinnedCertificateVerifierOptions(t *testing.T) {
+	t.Parallel()
+	verifier, err := newPinnedCertificateVerifier(option.OutboundTLSOptions{})
+	require.NoError(t, err)
+	require.Nil(t, verifier)
+	hash := sha256.Sum256([]byte("test"))
+	_, err = newPinnedCertificateVerifier(option.OutboundTLSOptions{
+		Insecure:          true,
+		CertificateSHA256: []string{base64.StdEncoding.EncodeToString(hash[:])},
+	})
+	require.Error(t, err)
+	_, err = newPinnedCertificateVerifier(option.OutboundTLSOptions{
+		CertificatePublicKeySHA256: []string{"invalid"},
+	})
+	require.ErrorContains(t, err, "certificate_public_key_sha256")
+}
+
+func TestPinnedCertificateVerifier(t *testing.T) {
+	t.Parallel()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	certificate := newTestCertificate(t, key, 1)
+	renewedCertificate := newTestCertificate(t, key, 2)
+	otherCertificate := newTestCertificate(t, otherKey, 3)
+
+	certificateVerifier, err := newPinnedCertificateVerifier(option.OutboundTLSOptions{
+		CertificateSHA256: []string{CertificateSHA256(certificate)},
+	})
+	require.NoError(t, err)
+	publicKeyVerifier, err := newPinnedCertificateVerifier(option.OutboundTLSOptions{
+		CertificatePublicKeySHA256: []string{CertificatePublicKeySHA256(certificate)},
+	})
+	require.NoError(t, err)
+	bothVerifier, err := newPinnedCertificateVerifier(option.OutboundTLSOptions{
+		CertificateSHA256:          []string{CertificateSHA256(otherCertificate)},
+		CertificatePublicKeySHA256: []string{CertificatePublicKeySHA256(certificate)},
+	})
+	require.NoError(t, err)
+
+	for _, testCase := range []struct {
+		name     string
+		verifier *pinnedCertificateVerifier
+		rawCerts [][]byte
+		accepted bool
+	}{
+		{"certificate pin", certificateVerifier, [][]byte{certificate.Raw}, true},
+		{"certificate pin with chain", certificateVerifier, [][]byte{certificate.Raw, otherCertificate.Raw}, true},
+		{"certificate pin on intermediate", certificateVerifier, [][]byte{otherCertificate.Raw, certificate.Raw}, false},
+		{"certificate pin after renewal", certificateVerifier, [][]byte{renewedCertificate.Raw}, false},
+		{"certificate pin mismatch", certificateVerifier, [][]byte{otherCertificate.Raw}, false},
+		{"public key pin", publicKeyVerifier, [][]byte{certificate.Raw}, true},
+		{"public key pin after renewal", publicKeyVerifier, [][]byte{renewedCertificate.Raw}, true},
+		{"public key pin mismatch", publicKeyVerifier, [][]byte{otherCertificate.Raw}, false},
+		{"public key pin with bad certificate", publicKeyVerifier, [][]byte{[]byte("bad certificate")}, false},
+		{"either pin by certificate", bothVerifier, [][]byte{otherCertificate.Raw}, true},
+		{"either pin by public key", bothVerifier, [][]byte{renewedCertificate.Raw}, true},
+		{"missing certificate", bothVerifier, nil, false},
+	} {
+		err = testCase.verifier.VerifyPeerCertificate(testCase.rawCerts, nil)
+		if testCase.accepted {
+			require.NoError(t, err, testCase.name)
+		} else {
+			require.Error(t, err, testCase.name)
+		}
+	}
+}
+
+func TestPinnedCertificateHandshake(t *testing.T) {
+	t.Parallel()
+	keyPair, err := GenerateKeyPair(time.Now, "server.example")
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(keyPair.Certificate[0])
+	require.NoError(t, err)
+	for _, testCase := range []struct {
+		name     string
+		pin      string
+		accepted bool
+	}{
+		{"matching pin", CertificateSHA256(certificate), true},
+		{"mismatched pin", base64.StdEncoding.EncodeToString(make([]byte, sha256.Size)), false},
+	} {
+		// The certificate is self-signed and issued for another name, so it is
+		// only accepted because the pin replaces certificate authority verification.
+		clientConfig, err := NewSTDClient(&testRouter{}, "", option.OutboundTLSOptions{
+			Enabled:           true,
+			ServerName:        "client.example",
+			CertificateSHA256: []string{testCase.pin},
+		})
+		require.NoError(t, err)
+		serverConn, clientConn := net.Pipe()
+		go func() {
+			tlsConn := tls.Server(serverConn, &tls.Config{Certificates: []tls.Certificate{*keyPair}})
+			tlsConn.Handshake()
+			tlsConn.Close()
+		}()
+		_, err = ClientHandshake(context.Background(), clientConn, clientConfig)
+		clientConn.Close()
+		if testCase.accepted {
+			require.NoError(t, err, testCase.name)
+		} else {
+			require.Error(t, err, testCase.name)
+		}
+	}
+}
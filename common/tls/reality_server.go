@@ -72,6 +72,24 @@ func NewRealityServer(ctx context.Context, router adapter.Router, logger log.Log
 	if options.Key != "" || options.KeyPath != "" {
 		return nil, E.New("key is unavailable in reality")
 	}
+	if len(options.Certificates) > 0 {
+		return nil, E.New("certificates is unavailable in reality")
+	}
+	clientCertificate, err := readClientCertificate(options.ClientCertificate, options.ClientCertificatePath)
+	if err != nil {
+		return nil, err
+	}
+	clientAuth, err := parseClientAuthentication(options, clientCertificate)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.ClientAuth = reality.ClientAuthType(clientAuth)
+	if clientCertificate != nil {
+		tlsConfig.ClientCAs, err = parseClientCertificate(clientCertificate)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	tlsConfig.SessionTicketsDisabled = true
 	tlsConfig.Type = N.NetworkTCP
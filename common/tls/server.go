@@ -2,6 +2,7 @@ package tls
 
 import (
 	"context"
+	"crypto/x509"
 	"net"
 
 	"github.com/sagernet/sing-box/adapter"
@@ -15,7 +16,9 @@ func NewServer(ctx context.Context, router adapter.Router, logger log.Logger, op
 	if !options.Enabled {
 		return nil, nil
 	}
-	if options.Reality != nil && options.Reality.Enabled {
+	if options.ECH != nil && options.ECH.Enabled {
+		return NewECHServer(ctx, router, logger, options)
+	} else if options.Reality != nil && options.Reality.Enabled {
 		return NewRealityServer(ctx, router, logger, options)
 	} else {
 		return NewSTDServer(ctx, router, logger, options)
@@ -27,3 +30,14 @@ func ServerHandshake(ctx context.Context, conn net.Conn, config ServerConfig) (C
 	defer cancel()
 	return aTLS.ServerHandshake(ctx, conn, config)
 }
+
+type certificateServerConfig interface {
+	Certificates() []*x509.Certificate
+}
+
+func ServerCertificates(config ServerConfig) []*x509.Certificate {
+	if certificateConfig, isCertificateConfig := config.(certificateServerConfig); isCertificateConfig {
+		return certificateConfig.Certificates()
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/atomic"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// acmeTLS1Protocol is the ALPN protocol of ACME TLS-ALPN-01 challenges (RFC 8737).
+const acmeTLS1Protocol = "acme-tls/1"
+
+type serverCertificate struct {
+	serverName      []string
+	certificate     []byte
+	key             []byte
+	certificatePath string
+	keyPath         string
+	keyPair         atomic.TypedValue[*tls.Certificate]
+}
+
+func newServerCertificate(options option.InboundTLSCertificateOptions) (*serverCertificate, error) {
+	var certificate []byte
+	var key []byte
+	if options.Certificate != "" {
+		certificate = []byte(options.Certificate)
+	} else if options.CertificatePath != "" {
+		content, err := os.ReadFile(options.CertificatePath)
+		if err != nil {
+			return nil, E.Cause(err, "read certificate")
+		}
+		certificate = content
+	}
+	if options.Key != "" {
+		key = []byte(options.Key)
+	} else if options.KeyPath != "" {
+		content, err := os.ReadFile(options.KeyPath)
+		if err != nil {
+			return nil, E.Cause(err, "read key")
+		}
+		key = content
+	}
+	if certificate == nil {
+		return nil, E.New("missing certificate")
+	} else if key == nil {
+		return nil, E.New("missing key")
+	}
+	keyPair, err := parseKeyPair(certificate, key)
+	if err != nil {
+		return nil, E.Cause(err, "parse x509 key pair")
+	}
+	serverCertificate := &serverCertificate{
+		serverName:  options.ServerName,
+		certificate: certificate,
+		key:         key,
+	}
+	if options.Certificate == "" {
+		serverCertificate.certificatePath = options.CertificatePath
+	}
+	if options.Key == "" {
+		serverCertificate.keyPath = options.KeyPath
+	}
+	serverCertificate.keyPair.Store(keyPair)
+	return serverCertificate, nil
+}
+
+func (c *serverCertificate) reload() (bool, error) {
+	if c.certificatePath == "" && c.keyPath == "" {
+		return false, nil
+	}
+	certificate := c.certificate
+	if c.certificatePath != "" {
+		content, err := os.ReadFile(c.certificatePath)
+		if err != nil {
+			return false, E.Cause(err, "reload certificate from ", c.certificatePath)
+		}
+		certificate = content
+	}
+	key := c.key
+	if c.keyPath != "" {
+		content, err := os.ReadFile(c.keyPath)
+		if err != nil {
+			return false, E.Cause(err, "reload key from ", c.keyPath)
+		}
+		key = content
+	}
+	if bytes.Equal(certificate, c.certificate) && bytes.Equal(key, c.key) {
+		return false, nil
+	}
+	keyPair, err := parseKeyPair(certificate, key)
+	if err != nil {
+		return false, E.Cause(err, "reload TLS key pair")
+	}
+	c.certificate = certificate
+	c.key = key
+	c.keyPair.Store(keyPair)
+	return true, nil
+}
+
+func (c *serverCertificate) Leaf() *x509.Certificate {
+	return c.keyPair.Load().Leaf
+}
+
+type serverCertificateStore struct {
+	certificates       []*serverCertificate
+	certificateByName  map[string]*serverCertificate
+	defaultCertificate *serverCertificate
+	fallback           func(info *tls.ClientHelloInfo) (*tls.Certificate, error)
+	acme               bool
+}
+
+func newServerCertificateStore(options option.InboundTLSOptions, useDefault bool) (*serverCertificateStore, error) {
+	store := &serverCertificateStore{
+		certificateByName: make(map[string]*serverCertificate),
+	}
+	if useDefault && (options.Certificate != "" || options.CertificatePath != "" || options.Key != "" || options.KeyPath != "") {
+		certificate, err := newServerCertificate(option.InboundTLSCertificateOptions{
+			Certificate:     options.Certificate,
+			CertificatePath: options.CertificatePath,
+			Key:             options.Key,
+			KeyPath:         options.KeyPath,
+		})
+		if err != nil {
+			return nil, err
+		}
+		store.certificates = append(store.certificates, certificate)
+		store.defaultCertificate = certificate
+	}
+	for i, certificateOptions := range options.Certificates {
+		if len(certificateOptions.ServerName) == 0 {
+			return nil, E.New("parse certificates[", i, "]: missing server_name")
+		}
+		certificate, err := newServerCertificate(certificateOptions)
+		if err != nil {
+			return nil, E.Cause(err, "parse certificates[", i, "]")
+		}
+		for _, serverName := range certificateOptions.ServerName {
+			serverName = strings.ToLower(serverName)
+			if store.certificateByName[serverName] != nil {
+				return nil, E.New("parse certificates[", i, "]: duplicate server_name: ", serverName)
+			}
+			store.certificateByName[serverName] = certificate
+		}
+		store.certificates = append(store.certificates, certificate)
+	}
+	return store, nil
+}
+
+func (s *serverCertificateStore) GetCertificate(info *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	if s.acme && common.Contains(info.SupportedProtos, acmeTLS1Protocol) {
+		return s.fallback(info)
+	}
+	if certificate := s.match(info.ServerName); certificate != nil {
+		return certificate.keyPair.Load(), nil
+	}
+	if s.defaultCertificate != nil {
+		return s.defaultCertificate.keyPair.Load(), nil
+	}
+	if s.fallback != nil {
+		return s.fallback(info)
+	}
+	return s.certificates[0].keyPair.Load(), nil
+}
+
+// match looks up the certificate for serverName; a wildcard entry covers
+// exactly one label, so *.example.com does not match a.b.example.com.
+func (s *serverCertificateStore) match(serverName string) *serverCertificate {
+	if len(s.certificateByName) == 0 || serverName == "" {
+		return nil
+	}
+	serverName = strings.ToLower(strings.TrimSuffix(serverName, "."))
+	if certificate, loaded := s.certificateByName[serverName]; loaded {
+		return certificate
+	}
+	if _, domain, found := strings.Cut(serverName, "."); found {
+		if certificate, loaded := s.certificateByName["*."+domain]; loaded {
+			return certificate
+		}
+	}
+	return nil
+}
+
+func (s *serverCertificateStore) Paths() []string {
+	var paths []string
+	for _, certificate := range s.certificates {
+		paths = append(paths, certificate.certificatePath, certificate.keyPath)
+	}
+	return paths
+}
+
+func (s *serverCertificateStore) Leaves() []*x509.Certificate {
+	leaves := make([]*x509.Certificate, 0, len(s.certificates))
+	for _, certificate := range s.certificates {
+		leaves = append(leaves, certificate.Leaf())
+	}
+	return leaves
+}
+
+func parseKeyPair(certificate []byte, key []byte) (*tls.Certificate, error) {
+	keyPair, err := tls.X509KeyPair(certificate, key)
+	if err != nil {
+		return nil, err
+	}
+	if keyPair.Leaf == nil {
+		keyPair.Leaf, err = x509.ParseCertificate(keyPair.Certificate[0])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &keyPair, nil
+}
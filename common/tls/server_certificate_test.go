@@ -0,0 +1,104 @@
+package tls
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServerCertificate(t *testing.T, serverName ...string) *serverCertificate {
+	keyPair, err := GenerateKeyPair(time.Now, "test")
+	require.NoError(t, err)
+	certificate := &serverCertificate{serverName: serverName}
+	certificate.keyPair.Store(keyPair)
+	return certificate
+}
+
+func newTestServerCertificateStore(certificates ...*serverCertificate) *serverCertificateStore {
+	store := &serverCertificateStore{
+		certificates:      certificates,
+		certificateByName: make(map[string]*serverCertificate),
+	}
+	for _, certificate := range certificates {
+		for _, serverName := range certificate.serverName {
+			store.certificateByName[serverName] = certificate
+		}
+	}
+	return store
+}
+
+func TestServerCertificateMatch(t *testing.T) {
+	t.Parallel()
+	exact := newTestServerCertificate(t, "example.com")
+	wildcard := newTestServerCertificate(t, "*.example.com")
+	store := newTestServerCertificateStore(exact, wildcard)
+	for _, testCase := range []struct {
+		serverName string
+		expected   *serverCertificate
+	}{
+		{"example.com", exact},
+		{"EXAMPLE.com.", exact},
+		{"a.example.com", wildcard},
+		{"A.Example.Com", wildcard},
+		{"a.b.example.com", nil},
+		{"example.org", nil},
+		{"", nil},
+	} {
+		require.Equal(t, testCase.expected, store.match(testCase.serverName), testCase.serverName)
+	}
+}
+
+func TestServerCertificateGetCertificate(t *testing.T) {
+	t.Parallel()
+	named := newTestServerCertificate(t, "example.com")
+	defaultCertificate := newTestServerCertificate(t)
+	fallbackCertificate := newTestServerCertificate(t).keyPair.Load()
+	fallback := func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return fallbackCertificate, nil
+	}
+
+	store := newTestServerCertificateStore(named)
+	certificate, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.org"})
+	require.NoError(t, err)
+	require.Equal(t, named.keyPair.Load(), certificate)
+
+	store.fallback = fallback
+	certificate, err = store.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.org"})
+	require.NoError(t, err)
+	require.Equal(t, fallbackCertificate, certificate)
+
+	store = newTestServerCertificateStore(defaultCertificate, named)
+	store.defaultCertificate = defaultCertificate
+	store.fallback = fallback
+	certificate, err = store.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.org"})
+	require.NoError(t, err)
+	require.Equal(t, defaultCertificate.keyPair.Load(), certificate)
+	certificate, err = store.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.com"})
+	require.NoError(t, err)
+	require.Equal(t, named.keyPair.Load(), certificate)
+}
+
+func TestServerCertificateACMEChallenge(t *testing.T) {
+	t.Parallel()
+	named := newTestServerCertificate(t, "example.com")
+	challengeCertificate := newTestServerCertificate(t).keyPair.Load()
+	store := newTestServerCertificateStore(named)
+	store.fallback = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+		return challengeCertificate, nil
+	}
+	store.acme = true
+	certificate, err := store.GetCertificate(&tls.ClientHelloInfo{
+		ServerName:      "example.com",
+		SupportedProtos: []string{acmeTLS1Protocol},
+	})
+	require.NoError(t, err)
+	require.Equal(t, challengeCertificate, certificate)
+	certificate, err = store.GetCertificate(&tls.ClientHelloInfo{
+		ServerName:      "example.com",
+		SupportedProtos: []string{"h2", "http/1.1"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, named.keyPair.Load(), certificate)
+}
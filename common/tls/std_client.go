@@ -53,7 +53,11 @@ func NewSTDClient(router adapter.Router, serverAddress string, options option.Ou
 			serverName = serverAddress
 		}
 	}
-	if serverName == "" && !options.Insecure {
+	pinnedVerifier, err := newPinnedCertificateVerifier(options)
+	if err != nil {
+		return nil, err
+	}
+	if serverName == "" && !options.Insecure && pinnedVerifier == nil {
 		return nil, E.New("missing server_name or insecure=true")
 	}
 
@@ -66,6 +70,9 @@ func NewSTDClient(router adapter.Router, serverAddress string, options option.Ou
 	}
 	if options.Insecure {
 		tlsConfig.InsecureSkipVerify = options.Insecure
+	} else if pinnedVerifier != nil {
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = pinnedVerifier.VerifyPeerCertificate
 	} else if options.DisableSNI {
 		tlsConfig.InsecureSkipVerify = true
 		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
@@ -126,5 +133,16 @@ func NewSTDClient(router adapter.Router, serverAddress string, options option.Ou
 		}
 		tlsConfig.RootCAs = certPool
 	}
+	clientCertificate, clientKey, err := readClientKeyPair(options)
+	if err != nil {
+		return nil, err
+	}
+	if clientCertificate != nil {
+		keyPair, err := tls.X509KeyPair(clientCertificate, clientKey)
+		if err != nil {
+			return nil, E.Cause(err, "parse client x509 key pair")
+		}
+		tlsConfig.Certificates = []tls.Certificate{keyPair}
+	}
 	return &STDClientConfig{&tlsConfig}, nil
 }
@@ -1,31 +1,35 @@
 package tls
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"net"
-	"os"
+	"strings"
+	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/fswatch"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/atomic"
 	E "github.com/sagernet/sing/common/exceptions"
-
-	"github.com/fsnotify/fsnotify"
 )
 
 var errInsecureUnused = E.New("tls: insecure unused")
 
 type STDServerConfig struct {
-	config          *tls.Config
-	logger          log.Logger
-	acmeService     adapter.Service
-	certificate     []byte
-	key             []byte
-	certificatePath string
-	keyPath         string
-	watcher         *fsnotify.Watcher
+	config                   *tls.Config
+	logger                   log.Logger
+	acmeService              adapter.Service
+	certificates             *serverCertificateStore
+	clientCertificate        []string
+	clientCertificatePath    []string
+	clientCertificateContent []byte
+	clientCAs                *atomic.TypedValue[*x509.CertPool]
+	watcher                  *fswatch.Watcher
 }
 
 func (c *STDServerConfig) ServerName() string {
@@ -57,103 +61,95 @@ func (c *STDServerConfig) Server(conn net.Conn) (Conn, error) {
 }
 
 func (c *STDServerConfig) Clone() Config {
-	return &STDServerConfig{
-		config: c.config.Clone(),
+	config := &STDServerConfig{
+		config:       c.config.Clone(),
+		certificates: c.certificates,
+		clientCAs:    c.clientCAs,
+	}
+	if c.config.GetConfigForClient != nil {
+		config.config.GetConfigForClient = config.getConfigForClient
 	}
+	return config
 }
 
 func (c *STDServerConfig) Start() error {
 	if c.acmeService != nil {
-		return c.acmeService.Start()
-	} else {
-		if c.certificatePath == "" && c.keyPath == "" {
-			return nil
-		}
-		err := c.startWatcher()
+		err := c.acmeService.Start()
 		if err != nil {
-			c.logger.Warn("create fsnotify watcher: ", err)
+			return err
 		}
-		return nil
 	}
+	err := c.startWatcher()
+	if err != nil {
+		c.logger.Warn("create fsnotify watcher: ", err)
+	}
+	return nil
 }
 
 func (c *STDServerConfig) startWatcher() error {
-	watcher, err := fsnotify.NewWatcher()
+	paths := append(c.certificates.Paths(), c.clientCertificatePath...)
+	watcher, err := fswatch.New(c.logger, paths, c.reload)
 	if err != nil {
 		return err
 	}
-	if c.certificatePath != "" {
-		err = watcher.Add(c.certificatePath)
-		if err != nil {
-			return err
-		}
-	}
-	if c.keyPath != "" {
-		err = watcher.Add(c.keyPath)
-		if err != nil {
-			return err
-		}
-	}
 	c.watcher = watcher
-	go c.loopUpdate()
 	return nil
 }
 
-func (c *STDServerConfig) loopUpdate() {
-	for {
-		select {
-		case event, ok := <-c.watcher.Events:
-			if !ok {
-				return
-			}
-			if event.Op&fsnotify.Write != fsnotify.Write {
-				continue
-			}
-			err := c.reloadKeyPair()
-			if err != nil {
-				c.logger.Error(E.Cause(err, "reload TLS key pair"))
-			}
-		case err, ok := <-c.watcher.Errors:
-			if !ok {
-				return
+func (c *STDServerConfig) reload() {
+	for _, certificate := range c.certificates.certificates {
+		reloaded, err := certificate.reload()
+		if err != nil {
+			c.logger.Error(err)
+		} else if reloaded {
+			expiry := certificate.Leaf().NotAfter.Format(time.RFC3339)
+			if len(certificate.serverName) > 0 {
+				c.logger.Info("reloaded TLS certificate for ", strings.Join(certificate.serverName, ", "), ", expires at ", expiry)
+			} else {
+				c.logger.Info("reloaded TLS certificate, expires at ", expiry)
 			}
-			c.logger.Error(E.Cause(err, "fsnotify error"))
 		}
 	}
+	if len(c.clientCertificatePath) > 0 {
+		c.reloadClientCertificate()
+	}
 }
 
-func (c *STDServerConfig) reloadKeyPair() error {
-	if c.certificatePath != "" {
-		certificate, err := os.ReadFile(c.certificatePath)
-		if err != nil {
-			return E.Cause(err, "reload certificate from ", c.certificatePath)
-		}
-		c.certificate = certificate
+func (c *STDServerConfig) reloadClientCertificate() {
+	content, err := readClientCertificate(c.clientCertificate, c.clientCertificatePath)
+	if err != nil {
+		c.logger.Error(E.Cause(err, "reload client certificate"))
+		return
 	}
-	if c.keyPath != "" {
-		key, err := os.ReadFile(c.keyPath)
-		if err != nil {
-			return E.Cause(err, "reload key from ", c.keyPath)
-		}
-		c.key = key
+	if bytes.Equal(content, c.clientCertificateContent) {
+		return
 	}
-	keyPair, err := tls.X509KeyPair(c.certificate, c.key)
+	clientCAs, err := parseClientCertificate(content)
 	if err != nil {
-		return E.Cause(err, "reload key pair")
+		c.logger.Error(E.Cause(err, "reload client certificate"))
+		return
 	}
-	c.config.Certificates = []tls.Certificate{keyPair}
-	c.logger.Info("reloaded TLS certificate")
-	return nil
+	c.clientCertificateContent = content
+	c.clientCAs.Store(clientCAs)
+	c.logger.Info("reloaded TLS client certificate")
 }
 
-func (c *STDServerConfig) Close() error {
-	if c.acmeService != nil {
-		return c.acmeService.Close()
-	}
-	if c.watcher != nil {
-		return c.watcher.Close()
+func (c *STDServerConfig) getConfigForClient(*tls.ClientHelloInfo) (*tls.Config, error) {
+	clientCAs := c.clientCAs.Load()
+	if clientCAs == c.config.ClientCAs {
+		return nil, nil
 	}
-	return nil
+	config := c.config.Clone()
+	config.ClientCAs = clientCAs
+	return config, nil
+}
+
+func (c *STDServerConfig) Certificates() []*x509.Certificate {
+	return c.certificates.Leaves()
+}
+
+func (c *STDServerConfig) Close() error {
+	return common.Close(c.acmeService, common.PtrOrNil(c.watcher))
 }
 
 func NewSTDServer(ctx context.Context, router adapter.Router, logger log.Logger, options option.InboundTLSOptions) (ServerConfig, error) {
@@ -208,52 +204,49 @@ func NewSTDServer(ctx context.Context, router adapter.Router, logger log.Logger,
 			return nil, E.New("unknown cipher_suite: ", cipherSuite)
 		}
 	}
-	var certificate []byte
-	var key []byte
-	if acmeService == nil {
-		if options.Certificate != "" {
-			certificate = []byte(options.Certificate)
-		} else if options.CertificatePath != "" {
-			content, err := os.ReadFile(options.CertificatePath)
-			if err != nil {
-				return nil, E.Cause(err, "read certificate")
-			}
-			certificate = content
+	serverConfig := &STDServerConfig{
+		config:                tlsConfig,
+		logger:                logger,
+		acmeService:           acmeService,
+		clientCertificate:     options.ClientCertificate,
+		clientCertificatePath: options.ClientCertificatePath,
+		clientCAs:             new(atomic.TypedValue[*x509.CertPool]),
+	}
+	clientCertificate, err := readClientCertificate(options.ClientCertificate, options.ClientCertificatePath)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.ClientAuth, err = parseClientAuthentication(options, clientCertificate)
+	if err != nil {
+		return nil, err
+	}
+	if clientCertificate != nil {
+		clientCAs, err := parseClientCertificate(clientCertificate)
+		if err != nil {
+			return nil, err
 		}
-		if options.Key != "" {
-			key = []byte(options.Key)
-		} else if options.KeyPath != "" {
-			content, err := os.ReadFile(options.KeyPath)
-			if err != nil {
-				return nil, E.Cause(err, "read key")
-			}
-			key = content
+		tlsConfig.ClientCAs = clientCAs
+		serverConfig.clientCertificateContent = clientCertificate
+		serverConfig.clientCAs.Store(clientCAs)
+		if len(options.ClientCertificatePath) > 0 {
+			tlsConfig.GetConfigForClient = serverConfig.getConfigForClient
 		}
-		if certificate == nil && key == nil && options.Insecure {
-			tlsConfig.GetCertificate = func(info *tls.ClientHelloInfo) (*tls.Certificate, error) {
-				return GenerateKeyPair(router.TimeFunc(), info.ServerName)
-			}
-		} else {
-			if certificate == nil {
-				return nil, E.New("missing certificate")
-			} else if key == nil {
-				return nil, E.New("missing key")
-			}
-
-			keyPair, err := tls.X509KeyPair(certificate, key)
-			if err != nil {
-				return nil, E.Cause(err, "parse x509 key pair")
-			}
-			tlsConfig.Certificates = []tls.Certificate{keyPair}
+	}
+	certificates, err := newServerCertificateStore(options, acmeService == nil)
+	if err != nil {
+		return nil, err
+	}
+	if acmeService != nil {
+		certificates.fallback = tlsConfig.GetCertificate
+		certificates.acme = true
+	} else if certificates.defaultCertificate == nil && options.Insecure {
+		certificates.fallback = func(info *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return GenerateKeyPair(router.TimeFunc(), info.ServerName)
 		}
+	} else if len(certificates.certificates) == 0 {
+		return nil, E.New("missing certificate")
 	}
-	return &STDServerConfig{
-		config:          tlsConfig,
-		logger:          logger,
-		acmeService:     acmeService,
-		certificate:     certificate,
-		key:             key,
-		certificatePath: options.CertificatePath,
-		keyPath:         options.KeyPath,
-	}, nil
+	serverConfig.certificates = certificates
+	tlsConfig.GetCertificate = certificates.GetCertificate
+	return serverConfig, nil
 }
@@ -22,6 +22,7 @@ import (
 type UTLSClientConfig struct {
 	config *utls.Config
 	id     utls.ClientHelloID
+	spec   func() (*utls.ClientHelloSpec, error)
 }
 
 func (e *UTLSClientConfig) ServerName() string {
@@ -48,7 +49,27 @@ func (e *UTLSClientConfig) Config() (*STDConfig, error) {
 }
 
 func (e *UTLSClientConfig) Client(conn net.Conn) (Conn, error) {
-	return &utlsALPNWrapper{utlsConnWrapper{utls.UClient(conn, e.config.Clone(), e.id)}, e.config.NextProtos}, nil
+	uConn, err := e.uClient(conn, e.config.Clone())
+	if err != nil {
+		return nil, err
+	}
+	return &utlsALPNWrapper{utlsConnWrapper{uConn}, e.config.NextProtos}, nil
+}
+
+func (e *UTLSClientConfig) uClient(conn net.Conn, config *utls.Config) (*utls.UConn, error) {
+	if e.spec == nil {
+		return utls.UClient(conn, config, e.id), nil
+	}
+	spec, err := e.spec()
+	if err != nil {
+		return nil, E.Cause(err, "build uTLS client hello")
+	}
+	uConn := utls.UClient(conn, config, utls.HelloCustom)
+	err = uConn.ApplyPreset(spec)
+	if err != nil {
+		return nil, E.Cause(err, "apply uTLS client hello")
+	}
+	return uConn, nil
 }
 
 func (e *UTLSClientConfig) SetSessionIDGenerator(generator func(clientHello []byte, sessionID []byte) error) {
@@ -59,6 +80,7 @@ func (e *UTLSClientConfig) Clone() Config {
 	return &UTLSClientConfig{
 		config: e.config.Clone(),
 		id:     e.id,
+		spec:   e.spec,
 	}
 }
 
@@ -122,7 +144,11 @@ func NewUTLSClient(router adapter.Router, serverAddress string, options option.O
 			serverName = serverAddress
 		}
 	}
-	if serverName == "" && !options.Insecure {
+	pinnedVerifier, err := newPinnedCertificateVerifier(options)
+	if err != nil {
+		return nil, err
+	}
+	if serverName == "" && !options.Insecure && pinnedVerifier == nil {
 		return nil, E.New("missing server_name or insecure=true")
 	}
 
@@ -135,6 +161,9 @@ func NewUTLSClient(router adapter.Router, serverAddress string, options option.O
 	}
 	if options.Insecure {
 		tlsConfig.InsecureSkipVerify = options.Insecure
+	} else if pinnedVerifier != nil {
+		tlsConfig.InsecureSkipVerify = true
+		tlsConfig.VerifyPeerCertificate = pinnedVerifier.VerifyPeerCertificate
 	} else if options.DisableSNI {
 		return nil, E.New("disable_sni is unsupported in uTLS")
 	}
@@ -184,11 +213,52 @@ func NewUTLSClient(router adapter.Router, serverAddress string, options option.O
 		}
 		tlsConfig.RootCAs = certPool
 	}
+	clientCertificate, clientKey, err := readClientKeyPair(options)
+	if err != nil {
+		return nil, err
+	}
+	if clientCertificate != nil {
+		keyPair, err := utls.X509KeyPair(clientCertificate, clientKey)
+		if err != nil {
+			return nil, E.Cause(err, "parse client x509 key pair")
+		}
+		tlsConfig.Certificates = []utls.Certificate{keyPair}
+	}
+	if options.UTLS.ClientHello != "" || options.UTLS.ClientHelloSpec != nil {
+		spec, err := newUTLSClientHelloSpecFunc(options.UTLS, options.ALPN)
+		if err != nil {
+			return nil, err
+		}
+		return &UTLSClientConfig{&tlsConfig, utls.HelloCustom, spec}, nil
+	}
 	id, err := uTLSClientHelloID(options.UTLS.Fingerprint)
 	if err != nil {
 		return nil, err
 	}
-	return &UTLSClientConfig{&tlsConfig, id}, nil
+	return &UTLSClientConfig{&tlsConfig, id, nil}, nil
+}
+
+func newUTLSClientHelloSpecFunc(options *option.OutboundUTLSOptions, alpn []string) (func() (*utls.ClientHelloSpec, error), error) {
+	if options.Fingerprint != "" {
+		return nil, E.New("fingerprint is conflict with client_hello and client_hello_spec")
+	}
+	if options.ClientHello != "" {
+		if options.ClientHelloSpec != nil {
+			return nil, E.New("client_hello is conflict with client_hello_spec")
+		}
+		clientHello, err := parseUTLSClientHello(options.ClientHello)
+		if err != nil {
+			return nil, E.Cause(err, "parse client_hello")
+		}
+		return func() (*utls.ClientHelloSpec, error) {
+			return fingerprintUTLSClientHello(clientHello)
+		}, nil
+	}
+	spec, err := newUTLSClientHelloSpec(*options.ClientHelloSpec, alpn)
+	if err != nil {
+		return nil, E.Cause(err, "parse client_hello_spec")
+	}
+	return spec.Build, nil
 }
 
 var (
@@ -0,0 +1,306 @@
+//go:build with_utls
+
+package tls
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	utls "github.com/sagernet/utls"
+)
+
+const uTLSGREASE = "GREASE"
+
+var (
+	uTLSSupportedGroups = map[string]utls.CurveID{
+		"x25519":    utls.X25519,
+		"secp256r1": utls.CurveP256,
+		"secp384r1": utls.CurveP384,
+		"secp521r1": utls.CurveP521,
+	}
+	uTLSSignatureAlgorithms = map[string]utls.SignatureScheme{
+		"rsa_pkcs1_sha256":       utls.PKCS1WithSHA256,
+		"rsa_pkcs1_sha384":       utls.PKCS1WithSHA384,
+		"rsa_pkcs1_sha512":       utls.PKCS1WithSHA512,
+		"rsa_pss_rsae_sha256":    utls.PSSWithSHA256,
+		"rsa_pss_rsae_sha384":    utls.PSSWithSHA384,
+		"rsa_pss_rsae_sha512":    utls.PSSWithSHA512,
+		"ecdsa_secp256r1_sha256": utls.ECDSAWithP256AndSHA256,
+		"ecdsa_secp384r1_sha384": utls.ECDSAWithP384AndSHA384,
+		"ecdsa_secp521r1_sha512": utls.ECDSAWithP521AndSHA512,
+		"ed25519":                utls.Ed25519,
+		"rsa_pkcs1_sha1":         utls.PKCS1WithSHA1,
+		"ecdsa_sha1":             utls.ECDSAWithSHA1,
+	}
+	uTLSCertificateCompression = map[string]utls.CertCompressionAlgo{
+		"zlib":   utls.CertCompressionZlib,
+		"brotli": utls.CertCompressionBrotli,
+		"zstd":   utls.CertCompressionZstd,
+	}
+)
+
+type uTLSClientHelloSpec struct {
+	cipherSuites           []uint16
+	extensions             []string
+	supportedGroups        []utls.CurveID
+	keyShares              []utls.CurveID
+	signatureAlgorithms    []utls.SignatureScheme
+	supportedVersions      []uint16
+	alpn                   []string
+	certificateCompression []utls.CertCompressionAlgo
+	applicationSettings    []string
+}
+
+func newUTLSClientHelloSpec(options option.UTLSClientHelloSpecOptions, alpn []string) (*uTLSClientHelloSpec, error) {
+	spec := &uTLSClientHelloSpec{
+		alpn:                options.ALPN,
+		applicationSettings: options.ApplicationSettings,
+	}
+	if len(spec.alpn) == 0 {
+		spec.alpn = alpn
+	}
+	if len(spec.alpn) == 0 && common.Contains(options.Extensions, "alpn") {
+		return nil, E.New("missing alpn for the alpn extension")
+	}
+	for _, cipherSuite := range options.CipherSuites {
+		cipherSuiteID, err := parseUTLSCipherSuite(cipherSuite)
+		if err != nil {
+			return nil, err
+		}
+		spec.cipherSuites = append(spec.cipherSuites, cipherSuiteID)
+	}
+	if len(options.Extensions) == 0 {
+		return nil, E.New("missing extensions")
+	}
+	var greaseExtensions int
+	for _, extension := range options.Extensions {
+		if extension == uTLSGREASE {
+			greaseExtensions++
+			if greaseExtensions > 2 {
+				return nil, E.New("at most 2 GREASE extensions are supported")
+			}
+		} else if _, err := newUTLSExtension(extension, spec); err != nil {
+			return nil, err
+		}
+	}
+	spec.extensions = options.Extensions
+	for _, group := range options.SupportedGroups {
+		groupID, err := parseUTLSSupportedGroup(group)
+		if err != nil {
+			return nil, E.Cause(err, "parse supported_groups")
+		}
+		spec.supportedGroups = append(spec.supportedGroups, groupID)
+	}
+	if len(spec.supportedGroups) == 0 {
+		spec.supportedGroups = []utls.CurveID{utls.X25519, utls.CurveP256, utls.CurveP384}
+	}
+	for _, keyShare := range options.KeyShares {
+		if keyShare == uTLSGREASE {
+			spec.keyShares = append(spec.keyShares, utls.GREASE_PLACEHOLDER)
+			continue
+		}
+		groupID, loaded := uTLSSupportedGroups[keyShare]
+		if !loaded {
+			return nil, E.New("parse key_shares: unsupported group: ", keyShare)
+		}
+		spec.keyShares = append(spec.keyShares, groupID)
+	}
+	if len(spec.keyShares) == 0 {
+		spec.keyShares = []utls.CurveID{utls.X25519}
+	}
+	for _, signatureAlgorithm := range options.SignatureAlgorithms {
+		signatureScheme, loaded := uTLSSignatureAlgorithms[signatureAlgorithm]
+		if !loaded {
+			value, err := parseUTLSUint16(signatureAlgorithm)
+			if err != nil {
+				return nil, E.New("parse signature_algorithms: unknown signature algorithm: ", signatureAlgorithm)
+			}
+			signatureScheme = utls.SignatureScheme(value)
+		}
+		spec.signatureAlgorithms = append(spec.signatureAlgorithms, signatureScheme)
+	}
+	if len(spec.signatureAlgorithms) == 0 {
+		spec.signatureAlgorithms = []utls.SignatureScheme{
+			utls.ECDSAWithP256AndSHA256,
+			utls.PSSWithSHA256,
+			utls.PKCS1WithSHA256,
+			utls.ECDSAWithP384AndSHA384,
+			utls.PSSWithSHA384,
+			utls.PKCS1WithSHA384,
+			utls.PSSWithSHA512,
+			utls.PKCS1WithSHA512,
+		}
+	}
+	for _, version := range options.SupportedVersions {
+		if version == uTLSGREASE {
+			spec.supportedVersions = append(spec.supportedVersions, utls.GREASE_PLACEHOLDER)
+			continue
+		}
+		versionID, err := ParseTLSVersion(version)
+		if err != nil {
+			return nil, E.Cause(err, "parse supported_versions")
+		}
+		spec.supportedVersions = append(spec.supportedVersions, versionID)
+	}
+	if len(spec.supportedVersions) == 0 {
+		spec.supportedVersions = []uint16{utls.VersionTLS13, utls.VersionTLS12}
+	}
+	for _, algorithm := range options.CertificateCompression {
+		algorithmID, loaded := uTLSCertificateCompression[algorithm]
+		if !loaded {
+			return nil, E.New("parse certificate_compression: unknown algorithm: ", algorithm)
+		}
+		spec.certificateCompression = append(spec.certificateCompression, algorithmID)
+	}
+	if len(spec.certificateCompression) == 0 {
+		spec.certificateCompression = []utls.CertCompressionAlgo{utls.CertCompressionBrotli}
+	}
+	if len(spec.applicationSettings) == 0 {
+		spec.applicationSettings = []string{"h2"}
+	}
+	return spec, nil
+}
+
+func (s *uTLSClientHelloSpec) Build() (*utls.ClientHelloSpec, error) {
+	spec := &utls.ClientHelloSpec{
+		CipherSuites:       append([]uint16(nil), s.cipherSuites...),
+		CompressionMethods: []uint8{0},
+	}
+	for _, name := range s.extensions {
+		extension, err := newUTLSExtension(name, s)
+		if err != nil {
+			return nil, err
+		}
+		spec.Extensions = append(spec.Extensions, extension)
+	}
+	return spec, nil
+}
+
+func newUTLSExtension(name string, spec *uTLSClientHelloSpec) (utls.TLSExtension, error) {
+	switch name {
+	case uTLSGREASE:
+		return &utls.UtlsGREASEExtension{}, nil
+	case "server_name":
+		return &utls.SNIExtension{}, nil
+	case "extended_master_secret":
+		return &utls.UtlsExtendedMasterSecretExtension{}, nil
+	case "renegotiation_info":
+		return &utls.RenegotiationInfoExtension{Renegotiation: utls.RenegotiateOnceAsClient}, nil
+	case "supported_groups":
+		return &utls.SupportedCurvesExtension{Curves: append([]utls.CurveID(nil), spec.supportedGroups...)}, nil
+	case "ec_point_formats":
+		return &utls.SupportedPointsExtension{SupportedPoints: []uint8{0}}, nil
+	case "session_ticket":
+		return &utls.SessionTicketExtension{}, nil
+	case "alpn":
+		return &utls.ALPNExtension{AlpnProtocols: append([]string(nil), spec.alpn...)}, nil
+	case "status_request":
+		return &utls.StatusRequestExtension{}, nil
+	case "signature_algorithms":
+		return &utls.SignatureAlgorithmsExtension{SupportedSignatureAlgorithms: spec.signatureAlgorithms}, nil
+	case "signed_certificate_timestamp":
+		return &utls.SCTExtension{}, nil
+	case "key_share":
+		keyShares := make([]utls.KeyShare, 0, len(spec.keyShares))
+		for _, group := range spec.keyShares {
+			keyShare := utls.KeyShare{Group: group}
+			if group == utls.GREASE_PLACEHOLDER {
+				keyShare.Data = []byte{0}
+			}
+			keyShares = append(keyShares, keyShare)
+		}
+		return &utls.KeyShareExtension{KeyShares: keyShares}, nil
+	case "psk_key_exchange_modes":
+		return &utls.PSKKeyExchangeModesExtension{Modes: []uint8{utls.PskModeDHE}}, nil
+	case "supported_versions":
+		return &utls.SupportedVersionsExtension{Versions: append([]uint16(nil), spec.supportedVersions...)}, nil
+	case "compress_certificate":
+		return &utls.UtlsCompressCertExtension{Algorithms: spec.certificateCompression}, nil
+	case "application_settings":
+		return &utls.ApplicationSettingsExtension{SupportedProtocols: spec.applicationSettings}, nil
+	case "padding":
+		return &utls.UtlsPaddingExtension{GetPaddingLen: utls.BoringPaddingStyle}, nil
+	default:
+		extensionID, err := parseUTLSUint16(name)
+		if err != nil {
+			return nil, E.New("unknown extension: ", name)
+		}
+		return &utls.GenericExtension{Id: extensionID}, nil
+	}
+}
+
+func parseUTLSCipherSuite(name string) (uint16, error) {
+	if name == uTLSGREASE {
+		return utls.GREASE_PLACEHOLDER, nil
+	}
+	for _, cipherSuite := range tls.CipherSuites() {
+		if name == cipherSuite.Name {
+			return cipherSuite.ID, nil
+		}
+	}
+	for _, cipherSuite := range tls.InsecureCipherSuites() {
+		if name == cipherSuite.Name {
+			return cipherSuite.ID, nil
+		}
+	}
+	cipherSuiteID, err := parseUTLSUint16(name)
+	if err != nil {
+		return 0, E.New("unknown cipher_suite: ", name)
+	}
+	return cipherSuiteID, nil
+}
+
+func parseUTLSSupportedGroup(name string) (utls.CurveID, error) {
+	if name == uTLSGREASE {
+		return utls.GREASE_PLACEHOLDER, nil
+	}
+	if groupID, loaded := uTLSSupportedGroups[name]; loaded {
+		return groupID, nil
+	}
+	value, err := parseUTLSUint16(name)
+	if err != nil {
+		return 0, E.New("unknown group: ", name)
+	}
+	return utls.CurveID(value), nil
+}
+
+func parseUTLSUint16(value string) (uint16, error) {
+	if !strings.HasPrefix(value, "0x") {
+		return 0, E.New("not a hex value")
+	}
+	parsed, err := strconv.ParseUint(value[2:], 16, 16)
+	if err != nil {
+		return 0, err
+	}
+	return uint16(parsed), nil
+}
+
+func parseUTLSClientHello(content string) ([]byte, error) {
+	content = strings.NewReplacer(" ", "", "\n", "", ":", "").Replace(content)
+	clientHello, err := hex.DecodeString(content)
+	if err != nil {
+		return nil, err
+	}
+	if len(clientHello) > 0 && clientHello[0] == 0x01 {
+		// handshake message without the record header
+		record := []byte{0x16, 0x03, 0x01, 0x00, 0x00}
+		binary.BigEndian.PutUint16(record[3:], uint16(len(clientHello)))
+		clientHello = append(record, clientHello...)
+	}
+	_, err = fingerprintUTLSClientHello(clientHello)
+	if err != nil {
+		return nil, err
+	}
+	return clientHello, nil
+}
+
+func fingerprintUTLSClientHello(clientHello []byte) (*utls.ClientHelloSpec, error) {
+	fingerprinter := &utls.Fingerprinter{AllowBluntMimicry: true}
+	return fingerprinter.FingerprintClientHello(clientHello)
+}
@@ -0,0 +1,222 @@
+//go:build with_utls
+
+package tls
+
+import (
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+	utls "github.com/sagernet/utls"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUTLSUint16(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		value    string
+		expected uint16
+		error    bool
+	}{
+		{value: "0x0000", expected: 0},
+		{value: "0x1a1a", expected: 0x1a1a},
+		{value: "0xFFFF", expected: 0xffff},
+		{value: "0x10000", error: true},
+		{value: "1a1a", error: true},
+		{value: "0x", error: true},
+		{value: "0xzz", error: true},
+		{value: "", error: true},
+	} {
+		value, err := parseUTLSUint16(testCase.value)
+		if testCase.error {
+			require.Error(t, err, testCase.value)
+		} else {
+			require.NoError(t, err, testCase.value)
+			require.Equal(t, testCase.expected, value, testCase.value)
+		}
+	}
+}
+
+func TestUTLSClientHelloSpec(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name    string
+		options option.UTLSClientHelloSpecOptions
+		alpn    []string
+		check   func(t *testing.T, spec *uTLSClientHelloSpec)
+		error   string
+	}{
+		{
+			name:    "defaults",
+			options: option.UTLSClientHelloSpecOptions{Extensions: []string{"server_name"}},
+			check: func(t *testing.T, spec *uTLSClientHelloSpec) {
+				require.Empty(t, spec.alpn)
+				require.Equal(t, []utls.CurveID{utls.X25519, utls.CurveP256, utls.CurveP384}, spec.supportedGroups)
+				require.Equal(t, []utls.CurveID{utls.X25519}, spec.keyShares)
+				require.Equal(t, []uint16{utls.VersionTLS13, utls.VersionTLS12}, spec.supportedVersions)
+				require.Equal(t, []utls.CertCompressionAlgo{utls.CertCompressionBrotli}, spec.certificateCompression)
+				require.Equal(t, []string{"h2"}, spec.applicationSettings)
+			},
+		},
+		{
+			name:    "alpn from outbound",
+			options: option.UTLSClientHelloSpecOptions{Extensions: []string{"alpn"}},
+			alpn:    []string{"http/1.1"},
+			check: func(t *testing.T, spec *uTLSClientHelloSpec) {
+				require.Equal(t, []string{"http/1.1"}, spec.alpn)
+			},
+		},
+		{
+			name:    "alpn from spec",
+			options: option.UTLSClientHelloSpecOptions{Extensions: []string{"alpn"}, ALPN: []string{"h2"}},
+			alpn:    []string{"http/1.1"},
+			check: func(t *testing.T, spec *uTLSClientHelloSpec) {
+				require.Equal(t, []string{"h2"}, spec.alpn)
+			},
+		},
+		{
+			name:    "missing alpn",
+			options: option.UTLSClientHelloSpecOptions{Extensions: []string{"alpn"}},
+			error:   "missing alpn",
+		},
+		{
+			name: "values",
+			options: option.UTLSClientHelloSpecOptions{
+				CipherSuites:           []string{"GREASE", "TLS_AES_128_GCM_SHA256", "TLS_RSA_WITH_RC4_128_SHA", "0x00ff"},
+				Extensions:             []string{"GREASE", "server_name", "0xfe0d", "GREASE"},
+				SupportedGroups:        []string{"GREASE", "x25519", "0x6399"},
+				KeyShares:              []string{"GREASE", "secp256r1"},
+				SignatureAlgorithms:    []string{"ed25519", "0x0806"},
+				SupportedVersions:      []string{"GREASE", "1.3"},
+				CertificateCompression: []string{"zstd", "zlib"},
+			},
+			check: func(t *testing.T, spec *uTLSClientHelloSpec) {
+				require.Equal(t, []uint16{utls.GREASE_PLACEHOLDER, tls.TLS_AES_128_GCM_SHA256, tls.TLS_RSA_WITH_RC4_128_SHA, 0x00ff}, spec.cipherSuites)
+				require.Equal(t, []utls.CurveID{utls.GREASE_PLACEHOLDER, utls.X25519, 0x6399}, spec.supportedGroups)
+				require.Equal(t, []utls.CurveID{utls.GREASE_PLACEHOLDER, utls.CurveP256}, spec.keyShares)
+				require.Equal(t, []utls.SignatureScheme{utls.Ed25519, 0x0806}, spec.signatureAlgorithms)
+				require.Equal(t, []uint16{utls.GREASE_PLACEHOLDER, utls.VersionTLS13}, spec.supportedVersions)
+				require.Equal(t, []utls.CertCompressionAlgo{utls.CertCompressionZstd, utls.CertCompressionZlib}, spec.certificateCompression)
+			},
+		},
+		{name: "missing extensions", error: "missing extensions"},
+		{
+			name:    "too many GREASE",
+			options: option.UTLSClientHelloSpecOptions{Extensions: []string{"GREASE", "GREASE", "GREASE"}},
+			error:   "at most 2 GREASE",
+		},
+		{
+			name:    "unknown extension",
+			options: option.UTLSClientHelloSpecOptions{Extensions: []string{"unknown"}},
+			error:   "unknown extension",
+		},
+		{
+			name:    "unknown cipher suite",
+			options: option.UTLSClientHelloSpecOptions{CipherSuites: []string{"TLS_UNKNOWN"}, Extensions: []string{"server_name"}},
+			error:   "unknown cipher_suite",
+		},
+		{
+			name:    "unknown group",
+			options: option.UTLSClientHelloSpecOptions{Extensions: []string{"server_name"}, SupportedGroups: []string{"x448"}},
+			error:   "parse supported_groups",
+		},
+		{
+			name:    "unnamed key share",
+			options: option.UTLSClientHelloSpecOptions{Extensions: []string{"server_name"}, KeyShares: []string{"0x6399"}},
+			error:   "parse key_shares",
+		},
+		{
+			name:    "unknown signature algorithm",
+			options: option.UTLSClientHelloSpecOptions{Extensions: []string{"server_name"}, SignatureAlgorithms: []string{"rsa_md5"}},
+			error:   "parse signature_algorithms",
+		},
+		{
+			name:    "unknown version",
+			options: option.UTLSClientHelloSpecOptions{Extensions: []string{"server_name"}, SupportedVersions: []string{"1.4"}},
+			error:   "parse supported_versions",
+		},
+		{
+			name:    "unknown certificate compression",
+			options: option.UTLSClientHelloSpecOptions{Extensions: []string{"server_name"}, CertificateCompression: []string{"lz4"}},
+			error:   "parse certificate_compression",
+		},
+	} {
+		spec, err := newUTLSClientHelloSpec(testCase.options, testCase.alpn)
+		if testCase.error != "" {
+			require.ErrorContains(t, err, testCase.error, testCase.name)
+			continue
+		}
+		require.NoError(t, err, testCase.name)
+		testCase.check(t, spec)
+	}
+}
+
+func TestUTLSClientHelloSpecBuild(t *testing.T) {
+	t.Parallel()
+	spec, err := newUTLSClientHelloSpec(option.UTLSClientHelloSpecOptions{
+		Extensions: []string{"GREASE", "server_name", "alpn", "key_share", "0xfe0d"},
+		KeyShares:  []string{"GREASE", "x25519"},
+	}, []string{"h2", "http/1.1"})
+	require.NoError(t, err)
+	clientHelloSpec, err := spec.Build()
+	require.NoError(t, err)
+	require.Len(t, clientHelloSpec.Extensions, 5)
+	require.IsType(t, &utls.UtlsGREASEExtension{}, clientHelloSpec.Extensions[0])
+	require.IsType(t, &utls.SNIExtension{}, clientHelloSpec.Extensions[1])
+	require.Equal(t, []string{"h2", "http/1.1"}, clientHelloSpec.Extensions[2].(*utls.ALPNExtension).AlpnProtocols)
+	keyShares := clientHelloSpec.Extensions[3].(*utls.KeyShareExtension).KeyShares
+	require.Equal(t, utls.KeyShare{Group: utls.GREASE_PLACEHOLDER, Data: []byte{0}}, keyShares[0])
+	require.Equal(t, utls.X25519, keyShares[1].Group)
+	require.Equal(t, uint16(0xfe0d), clientHelloSpec.Extensions[4].(*utls.GenericExtension).Id)
+}
+
+func captureTestClientHello(t *testing.T) []byte {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+	go tls.Client(clientConn, &tls.Config{ServerName: "example.org", NextProtos: []string{"h2"}}).Handshake()
+	header := make([]byte, 5)
+	_, err := io.ReadFull(serverConn, header)
+	require.NoError(t, err)
+	body := make([]byte, binary.BigEndian.Uint16(header[3:]))
+	_, err = io.ReadFull(serverConn, body)
+	require.NoError(t, err)
+	return append(header, body...)
+}
+
+func TestParseUTLSClientHello(t *testing.T) {
+	t.Parallel()
+	clientHello := captureTestClientHello(t)
+	clientHelloHex := hex.EncodeToString(clientHello)
+	var formatted []string
+	for i := 0; i < len(clientHello); i++ {
+		formatted = append(formatted, clientHelloHex[i*2:i*2+2])
+	}
+	for _, testCase := range []struct {
+		name    string
+		content string
+		error   bool
+	}{
+		{name: "record", content: clientHelloHex},
+		{name: "handshake message", content: clientHelloHex[10:]},
+		{name: "formatted", content: strings.Join(formatted[:16], ":") + "\n" + strings.Join(formatted[16:], " ")},
+		{name: "invalid hex", content: "zz" + clientHelloHex, error: true},
+		{name: "odd length", content: clientHelloHex[1:], error: true},
+		{name: "truncated", content: clientHelloHex[:len(clientHelloHex)/2], error: true},
+		{name: "not a client hello", content: "1603010004" + "02000000", error: true},
+	} {
+		content, err := parseUTLSClientHello(testCase.content)
+		if testCase.error {
+			require.Error(t, err, testCase.name)
+		} else {
+			require.NoError(t, err, testCase.name)
+			require.Equal(t, clientHello, content, testCase.name)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package traffic
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/atomic"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+type Connection struct {
+	ID       uuid.UUID
+	Context  context.Context
+	Network  string
+	Metadata adapter.InboundContext
+	// Rule is nil for connections routed to the final outbound.
+	Rule adapter.Rule
+	// Chain lists the outbounds from the matched one to the one actually used.
+	Chain    []string
+	Start    time.Time
+	Upload   atomic.Int64
+	Download atomic.Int64
+
+	manager   *Manager
+	closer    io.Closer
+	leaveOnce sync.Once
+}
+
+// Close closes the underlying connection.
+func (c *Connection) Close() error {
+	return c.closer.Close()
+}
+
+// Leave stops tracking the connection, err is the error returned by the outbound.
+func (c *Connection) Leave(err error) {
+	c.leaveOnce.Do(func() {
+		c.manager.leave(c, err)
+	})
+}
+
+func (c *Connection) countUpload(n int64) {
+	c.Upload.Add(n)
+	c.manager.uploadTotal.Add(n)
+}
+
+func (c *Connection) countDownload(n int64) {
+	c.Download.Add(n)
+	c.manager.downloadTotal.Add(n)
+}
+
+type trackerConn struct {
+	N.ExtendedConn
+}
+
+func (c *trackerConn) Upstream() any {
+	return c.ExtendedConn
+}
+
+func (c *trackerConn) ReaderReplaceable() bool {
+	return true
+}
+
+func (c *trackerConn) WriterReplaceable() bool {
+	return true
+}
+
+type trackerPacketConn struct {
+	N.PacketConn
+}
+
+func (c *trackerPacketConn) Upstream() any {
+	return c.PacketConn
+}
+
+func (c *trackerPacketConn) ReaderReplaceable() bool {
+	return true
+}
+
+func (c *trackerPacketConn) WriterReplaceable() bool {
+	return true
+}
@@ -0,0 +1,96 @@
+package traffic
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/atomic"
+	"github.com/sagernet/sing/common/bufio"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+// Hook observes the connections tracked by a Manager. The counters of a
+// connection are final when ConnectionClosed is called.
+type Hook interface {
+	ConnectionJoined(connection *Connection)
+	ConnectionClosed(connection *Connection, err error)
+}
+
+type Manager struct {
+	uploadTotal   atomic.Int64
+	downloadTotal atomic.Int64
+	hooks         []Hook
+}
+
+func NewManager() *Manager {
+	return &Manager{}
+}
+
+// AddHook registers a hook, it must be called before any connection is tracked.
+func (m *Manager) AddHook(hook Hook) {
+	m.hooks = append(m.hooks, hook)
+}
+
+func (m *Manager) Total() (up int64, down int64) {
+	return m.uploadTotal.Load(), m.downloadTotal.Load()
+}
+
+func (m *Manager) RoutedConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, matchedRule adapter.Rule, outbound adapter.Outbound, router adapter.Router) (net.Conn, *Connection) {
+	connection := m.newConnection(ctx, N.NetworkTCP, metadata, matchedRule, outbound, router)
+	trackerConn := &trackerConn{bufio.NewCounterConn(conn, []N.CountFunc{connection.countUpload}, []N.CountFunc{connection.countDownload})}
+	connection.closer = trackerConn
+	m.join(connection)
+	return trackerConn, connection
+}
+
+func (m *Manager) RoutedPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, matchedRule adapter.Rule, outbound adapter.Outbound, router adapter.Router) (N.PacketConn, *Connection) {
+	connection := m.newConnection(ctx, N.NetworkUDP, metadata, matchedRule, outbound, router)
+	trackerConn := &trackerPacketConn{bufio.NewCounterPacketConn(conn, []N.CountFunc{connection.countUpload}, []N.CountFunc{connection.countDownload})}
+	connection.closer = trackerConn
+	m.join(connection)
+	return trackerConn, connection
+}
+
+func (m *Manager) newConnection(ctx context.Context, network string, metadata adapter.InboundContext, matchedRule adapter.Rule, outbound adapter.Outbound, router adapter.Router) *Connection {
+	id, _ := uuid.NewV4()
+	var chain []string
+	next := outbound.Tag()
+	for {
+		chain = append(chain, next)
+		detour, loaded := router.Outbound(next)
+		if !loaded {
+			break
+		}
+		group, isGroup := detour.(adapter.OutboundGroup)
+		if !isGroup {
+			break
+		}
+		next = group.Now()
+	}
+	return &Connection{
+		ID:       id,
+		Context:  ctx,
+		Network:  network,
+		Metadata: metadata,
+		Rule:     matchedRule,
+		Chain:    chain,
+		Start:    time.Now(),
+		manager:  m,
+	}
+}
+
+func (m *Manager) join(connection *Connection) {
+	for _, hook := range m.hooks {
+		hook.ConnectionJoined(connection)
+	}
+}
+
+func (m *Manager) leave(connection *Connection, err error) {
+	for _, hook := range m.hooks {
+		hook.ConnectionClosed(connection, err)
+	}
+}
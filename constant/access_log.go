@@ -0,0 +1,6 @@
+package constant
+
+const (
+	AccessLogFormatJSON = "json"
+	AccessLogFormatCSV  = "csv"
+)
@@ -0,0 +1,6 @@
+package constant
+
+const (
+	DNSProviderRFC2136 = "rfc2136"
+	DNSProviderWebhook = "webhook"
+)
@@ -0,0 +1,12 @@
+package constant
+
+const (
+	QuotaPeriodDaily   = "daily"
+	QuotaPeriodMonthly = "monthly"
+)
+
+const (
+	QuotaActionBlock    = "block"
+	QuotaActionThrottle = "throttle"
+	QuotaActionClose    = "close"
+)
@@ -0,0 +1,6 @@
+package constant
+
+const (
+	DefaultTProxyMark       = 0x2023
+	DefaultTProxyTableIndex = 2023
+)
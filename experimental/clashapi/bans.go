@@ -0,0 +1,94 @@
+package clashapi
+
+import (
+	"net/http"
+	"net/netip"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func banRouter(router adapter.Router) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", getBans(router))
+	r.Delete("/", clearBans(router))
+	r.Delete("/{address}", clearBan(router))
+	return r
+}
+
+type Ban struct {
+	Inbound  string    `json:"inbound"`
+	Address  string    `json:"address"`
+	Failures int       `json:"failures"`
+	Expires  time.Time `json:"expires"`
+}
+
+func accessControllers(router adapter.Router) map[string]adapter.InboundAccessController {
+	controllers := make(map[string]adapter.InboundAccessController)
+	for _, inbound := range router.Inbounds() {
+		accessControlInbound, isAccessControl := inbound.(adapter.AccessControlInbound)
+		if !isAccessControl {
+			continue
+		}
+		if controller := accessControlInbound.AccessController(); controller != nil {
+			controllers[inbound.Tag()] = controller
+		}
+	}
+	return controllers
+}
+
+func getBans(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		bans := []Ban{}
+		for tag, controller := range accessControllers(router) {
+			for _, ban := range controller.Bans() {
+				bans = append(bans, Ban{
+					Inbound:  tag,
+					Address:  ban.Address.String(),
+					Failures: ban.Failures,
+					Expires:  ban.Expires,
+				})
+			}
+		}
+		render.JSON(w, r, render.M{
+			"bans": bans,
+		})
+	}
+}
+
+func clearBans(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, controller := range accessControllers(router) {
+			for _, ban := range controller.Bans() {
+				controller.Unban(ban.Address)
+			}
+		}
+		render.NoContent(w, r)
+	}
+}
+
+func clearBan(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		address, err := netip.ParseAddr(chi.URLParam(r, "address"))
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrBadRequest)
+			return
+		}
+		var unbanned bool
+		for _, controller := range accessControllers(router) {
+			if controller.Unban(address) {
+				unbanned = true
+			}
+		}
+		if !unbanned {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, ErrNotFound)
+			return
+		}
+		render.NoContent(w, r)
+	}
+}
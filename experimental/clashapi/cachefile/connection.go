@@ -0,0 +1,33 @@
+package cachefile
+
+import (
+	"go.etcd.io/bbolt"
+)
+
+var (
+	bucketConnection     = []byte("connection")
+	keyConnectionHistory = []byte("history")
+)
+
+func (c *CacheFile) LoadConnectionHistory() []byte {
+	var content []byte
+	c.DB.View(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketConnection)
+		if bucket == nil {
+			return nil
+		}
+		content = append(content, bucket.Get(keyConnectionHistory)...)
+		return nil
+	})
+	return content
+}
+
+func (c *CacheFile) StoreConnectionHistory(content []byte) error {
+	return c.DB.Batch(func(t *bbolt.Tx) error {
+		bucket, err := c.createBucket(t, bucketConnection)
+		if err != nil {
+			return err
+		}
+		return bucket.Put(keyConnectionHistory, content)
+	})
+}
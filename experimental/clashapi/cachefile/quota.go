@@ -0,0 +1,38 @@
+package cachefile
+
+import (
+	"github.com/sagernet/sing-box/adapter"
+
+	"go.etcd.io/bbolt"
+)
+
+var bucketQuota = []byte("quota")
+
+func (c *CacheFile) LoadQuotaUsage(name string) *adapter.QuotaUsage {
+	var usage adapter.QuotaUsage
+	err := c.DB.View(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketQuota)
+		if bucket == nil {
+			return nil
+		}
+		return usage.UnmarshalBinary(bucket.Get([]byte(name)))
+	})
+	if err != nil || usage.PeriodStart.IsZero() {
+		return nil
+	}
+	return &usage
+}
+
+func (c *CacheFile) StoreQuotaUsage(name string, usage *adapter.QuotaUsage) error {
+	return c.DB.Batch(func(t *bbolt.Tx) error {
+		bucket, err := c.createBucket(t, bucketQuota)
+		if err != nil {
+			return err
+		}
+		usageBinary, err := usage.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		return bucket.Put([]byte(name), usageBinary)
+	})
+}
@@ -0,0 +1,49 @@
+package clashapi
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func certificateRouter(router adapter.Router) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", getCertificates(router))
+	return r
+}
+
+type Certificate struct {
+	Inbound   string    `json:"inbound"`
+	Subject   string    `json:"subject"`
+	DNSNames  []string  `json:"dnsNames"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+}
+
+func getCertificates(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		certificates := []Certificate{}
+		for _, inbound := range router.Inbounds() {
+			tlsInbound, isTLS := inbound.(adapter.TLSInbound)
+			if !isTLS {
+				continue
+			}
+			for _, certificate := range tlsInbound.Certificates() {
+				certificates = append(certificates, Certificate{
+					Inbound:   inbound.Tag(),
+					Subject:   certificate.Subject.String(),
+					DNSNames:  certificate.DNSNames,
+					NotBefore: certificate.NotBefore,
+					NotAfter:  certificate.NotAfter,
+				})
+			}
+		}
+		render.JSON(w, r, render.M{
+			"certificates": certificates,
+		})
+	}
+}
@@ -25,9 +25,17 @@ func connectionRouter(router adapter.Router, trafficManager *trafficontrol.Manag
 
 func getConnections(trafficManager *trafficontrol.Manager) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		snapshot := func() any {
+			return trafficManager.Snapshot()
+		}
+		if r.URL.Query().Get("closed") == "true" {
+			snapshot = func() any {
+				return trafficManager.ClosedSnapshot()
+			}
+		}
+
 		if !websocket.IsWebSocketUpgrade(r) {
-			snapshot := trafficManager.Snapshot()
-			render.JSON(w, r, snapshot)
+			render.JSON(w, r, snapshot())
 			return
 		}
 
@@ -52,8 +60,7 @@ func getConnections(trafficManager *trafficontrol.Manager) func(w http.ResponseW
 		buf := &bytes.Buffer{}
 		sendSnapshot := func() error {
 			buf.Reset()
-			snapshot := trafficManager.Snapshot()
-			if err := json.NewEncoder(buf).Encode(snapshot); err != nil {
+			if err := json.NewEncoder(buf).Encode(snapshot()); err != nil {
 				return err
 			}
 			return conn.WriteMessage(websocket.TextMessage, buf.Bytes())
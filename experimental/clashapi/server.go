@@ -21,8 +21,6 @@ import (
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
-	F "github.com/sagernet/sing/common/format"
-	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/service/filemanager"
 	"github.com/sagernet/websocket"
 
@@ -47,6 +45,8 @@ type Server struct {
 	mode           string
 	storeSelected  bool
 	storeFakeIP    bool
+	storeQuota     bool
+	storeHistory   bool
 	cacheFilePath  string
 	cacheID        string
 	cacheFile      adapter.ClashCacheFile
@@ -57,7 +57,10 @@ type Server struct {
 }
 
 func NewServer(ctx context.Context, router adapter.Router, logFactory log.ObservableFactory, options option.ClashAPIOptions) (adapter.ClashServer, error) {
-	trafficManager := trafficontrol.NewManager()
+	trafficManager, err := trafficontrol.NewManager(ctx, options.ConnectionHistory)
+	if err != nil {
+		return nil, err
+	}
 	chiRouter := chi.NewRouter()
 	server := &Server{
 		ctx:    ctx,
@@ -72,13 +75,15 @@ func NewServer(ctx context.Context, router adapter.Router, logFactory log.Observ
 		mode:                     strings.ToLower(options.DefaultMode),
 		storeSelected:            options.StoreSelected,
 		storeFakeIP:              options.StoreFakeIP,
+		storeQuota:               options.StoreQuota,
+		storeHistory:             options.StoreConnectionHistory && options.ConnectionHistory > 0,
 		externalUIDownloadURL:    options.ExternalUIDownloadURL,
 		externalUIDownloadDetour: options.ExternalUIDownloadDetour,
 	}
 	if server.mode == "" {
 		server.mode = "rule"
 	}
-	if options.StoreSelected || options.StoreFakeIP {
+	if options.StoreSelected || options.StoreFakeIP || options.StoreQuota || server.storeHistory {
 		cachePath := os.ExpandEnv(options.CacheFile)
 		if cachePath == "" {
 			cachePath = "cache.db"
@@ -107,6 +112,7 @@ func NewServer(ctx context.Context, router adapter.Router, logFactory log.Observ
 		r.Mount("/configs", configRouter(server, logFactory, server.logger))
 		r.Mount("/proxies", proxyRouter(server, router))
 		r.Mount("/rules", ruleRouter(router))
+		r.Mount("/certificates", certificateRouter(router))
 		r.Mount("/connections", connectionRouter(router, trafficManager))
 		r.Mount("/providers/proxies", proxyProviderRouter())
 		r.Mount("/providers/rules", ruleProviderRouter())
@@ -114,6 +120,7 @@ func NewServer(ctx context.Context, router adapter.Router, logFactory log.Observ
 		r.Mount("/profile", profileRouter())
 		r.Mount("/cache", cacheRouter(router))
 		r.Mount("/dns", dnsRouter(router))
+		r.Mount("/bans", banRouter(router))
 
 		server.setupMetaAPI(r)
 	})
@@ -137,6 +144,9 @@ func (s *Server) PreStart() error {
 			return E.Cause(err, "open cache file")
 		}
 		s.cacheFile = cacheFile
+		if s.storeHistory {
+			s.loadConnectionHistory()
+		}
 	}
 	return nil
 }
@@ -158,6 +168,9 @@ func (s *Server) Start() error {
 }
 
 func (s *Server) Close() error {
+	if s.storeHistory && s.cacheFile != nil {
+		s.storeConnectionHistory()
+	}
 	return common.Close(
 		common.PtrOrNil(s.httpServer),
 		s.trafficManager,
@@ -165,6 +178,30 @@ func (s *Server) Close() error {
 	)
 }
 
+func (s *Server) loadConnectionHistory() {
+	content := s.cacheFile.LoadConnectionHistory()
+	if len(content) == 0 {
+		return
+	}
+	var connections []trafficontrol.ClosedConnection
+	err := json.Unmarshal(content, &connections)
+	if err != nil {
+		s.logger.Warn("load connection history: ", err)
+		return
+	}
+	s.trafficManager.LoadClosedConnections(connections)
+}
+
+func (s *Server) storeConnectionHistory() {
+	content, err := json.Marshal(s.trafficManager.ClosedSnapshot().Connections)
+	if err == nil {
+		err = s.cacheFile.StoreConnectionHistory(content)
+	}
+	if err != nil {
+		s.logger.Warn("store connection history: ", err)
+	}
+}
+
 func (s *Server) Mode() string {
 	return s.mode
 }
@@ -177,6 +214,10 @@ func (s *Server) StoreFakeIP() bool {
 	return s.storeFakeIP
 }
 
+func (s *Server) StoreQuota() bool {
+	return s.storeQuota
+}
+
 func (s *Server) CacheFile() adapter.ClashCacheFile {
 	return s.cacheFile
 }
@@ -185,59 +226,6 @@ func (s *Server) HistoryStorage() *urltest.HistoryStorage {
 	return s.urlTestHistory
 }
 
-func (s *Server) RoutedConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, matchedRule adapter.Rule) (net.Conn, adapter.Tracker) {
-	tracker := trafficontrol.NewTCPTracker(conn, s.trafficManager, castMetadata(metadata), s.router, matchedRule)
-	return tracker, tracker
-}
-
-func (s *Server) RoutedPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, matchedRule adapter.Rule) (N.PacketConn, adapter.Tracker) {
-	tracker := trafficontrol.NewUDPTracker(conn, s.trafficManager, castMetadata(metadata), s.router, matchedRule)
-	return tracker, tracker
-}
-
-func castMetadata(metadata adapter.InboundContext) trafficontrol.Metadata {
-	var inbound string
-	if metadata.Inbound != "" {
-		inbound = metadata.InboundType + "/" + metadata.Inbound
-	} else {
-		inbound = metadata.InboundType
-	}
-	var domain string
-	if metadata.Domain != "" {
-		domain = metadata.Domain
-	} else {
-		domain = metadata.Destination.Fqdn
-	}
-	var processPath string
-	if metadata.ProcessInfo != nil {
-		if metadata.ProcessInfo.ProcessPath != "" {
-			processPath = metadata.ProcessInfo.ProcessPath
-		} else if metadata.ProcessInfo.PackageName != "" {
-			processPath = metadata.ProcessInfo.PackageName
-		}
-		if processPath == "" {
-			if metadata.ProcessInfo.UserId != -1 {
-				processPath = F.ToString(metadata.ProcessInfo.UserId)
-			}
-		} else if metadata.ProcessInfo.User != "" {
-			processPath = F.ToString(processPath, " (", metadata.ProcessInfo.User, ")")
-		} else if metadata.ProcessInfo.UserId != -1 {
-			processPath = F.ToString(processPath, " (", metadata.ProcessInfo.UserId, ")")
-		}
-	}
-	return trafficontrol.Metadata{
-		NetWork:     metadata.Network,
-		Type:        inbound,
-		SrcIP:       metadata.Source.Addr,
-		DstIP:       metadata.Destination.Addr,
-		SrcPort:     F.ToString(metadata.Source.Port),
-		DstPort:     F.ToString(metadata.Destination.Port),
-		Host:        domain,
-		DNSMode:     "normal",
-		ProcessPath: processPath,
-	}
-}
-
 func authentication(serverSecret string) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,79 @@
+package trafficontrol
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/common/baderror"
+)
+
+type ClosedConnection struct {
+	ID          string    `json:"id"`
+	Metadata    Metadata  `json:"metadata"`
+	Upload      int64     `json:"upload"`
+	Download    int64     `json:"download"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Duration    int64     `json:"duration"`
+	Chain       []string  `json:"chains"`
+	Rule        string    `json:"rule"`
+	RulePayload string    `json:"rulePayload"`
+	CloseReason string    `json:"closeReason"`
+}
+
+type ClosedSnapshot struct {
+	Connections []ClosedConnection `json:"connections"`
+}
+
+// connectionHistory is a ring buffer of the most recently closed connections.
+type connectionHistory struct {
+	access      sync.Mutex
+	connections []ClosedConnection
+	size        int
+	next        int
+}
+
+func newConnectionHistory(size int) *connectionHistory {
+	return &connectionHistory{
+		connections: make([]ClosedConnection, 0, size),
+		size:        size,
+	}
+}
+
+func (h *connectionHistory) push(connection ClosedConnection) {
+	h.access.Lock()
+	defer h.access.Unlock()
+	if len(h.connections) < h.size {
+		h.connections = append(h.connections, connection)
+	} else {
+		h.connections[h.next] = connection
+	}
+	h.next = (h.next + 1) % h.size
+}
+
+// list returns the connections from the newest to the oldest.
+func (h *connectionHistory) list() []ClosedConnection {
+	h.access.Lock()
+	defer h.access.Unlock()
+	connections := make([]ClosedConnection, 0, len(h.connections))
+	for i := range h.connections {
+		connections = append(connections, h.connections[(h.next-1-i+len(h.connections))%len(h.connections)])
+	}
+	return connections
+}
+
+func newClosedConnection(t *tracker, err error) ClosedConnection {
+	end := time.Now()
+	return ClosedConnection{
+		ID:          t.ID(),
+		Metadata:    t.metadata,
+		Upload:      t.connection.Upload.Load(),
+		Download:    t.connection.Download.Load(),
+		Start:       t.connection.Start,
+		End:         end,
+		Duration:    end.Sub(t.connection.Start).Milliseconds(),
+		Chain:       t.chain,
+		Rule:        t.rule,
+		CloseReason: baderror.CloseReason(err),
+	}
+}
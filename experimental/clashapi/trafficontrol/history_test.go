@@ -0,0 +1,126 @@
+package trafficontrol
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/traffic"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testOutbound struct {
+	adapter.Outbound
+	tag string
+}
+
+func (o *testOutbound) Tag() string {
+	return o.tag
+}
+
+type testRouter struct {
+	adapter.Router
+}
+
+func (r *testRouter) Outbound(tag string) (adapter.Outbound, bool) {
+	return nil, false
+}
+
+func newTestManager(t *testing.T, historySize int) (*Manager, *traffic.Manager) {
+	trafficManager := traffic.NewManager()
+	manager, err := NewManager(service.ContextWith(context.Background(), trafficManager), historySize)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		manager.Close()
+	})
+	return manager, trafficManager
+}
+
+func testClosedConnections(ids ...int) []ClosedConnection {
+	connections := make([]ClosedConnection, 0, len(ids))
+	for _, id := range ids {
+		connections = append(connections, ClosedConnection{ID: strconv.Itoa(id)})
+	}
+	return connections
+}
+
+func closedConnectionIDs(connections []ClosedConnection) []int {
+	ids := make([]int, 0, len(connections))
+	for _, connection := range connections {
+		id, _ := strconv.Atoi(connection.ID)
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestConnectionHistory(t *testing.T) {
+	t.Parallel()
+	history := newConnectionHistory(3)
+	require.Empty(t, history.list())
+	for i, expected := range [][]int{
+		{0},
+		{1, 0},
+		{2, 1, 0},
+		{3, 2, 1},
+		{4, 3, 2},
+		{5, 4, 3},
+		{6, 5, 4},
+	} {
+		history.push(testClosedConnections(i)[0])
+		require.Equal(t, expected, closedConnectionIDs(history.list()), i)
+	}
+
+	history = newConnectionHistory(1)
+	history.push(testClosedConnections(0)[0])
+	history.push(testClosedConnections(1)[0])
+	require.Equal(t, []int{1}, closedConnectionIDs(history.list()))
+}
+
+func TestManagerClosedSnapshot(t *testing.T) {
+	t.Parallel()
+	manager, _ := newTestManager(t, 0)
+	manager.LoadClosedConnections(testClosedConnections(1, 0))
+	snapshot := manager.ClosedSnapshot()
+	require.NotNil(t, snapshot.Connections)
+	require.Empty(t, snapshot.Connections)
+
+	manager, trafficManager := newTestManager(t, 3)
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	trackerConn, connection := trafficManager.RoutedConnection(context.Background(), serverConn, adapter.InboundContext{Inbound: "mixed-in", InboundType: "mixed"}, nil, &testOutbound{tag: "direct"}, &testRouter{})
+	go clientConn.Write([]byte("ping"))
+	_, err := trackerConn.Read(make([]byte, 4))
+	require.NoError(t, err)
+	require.Empty(t, manager.ClosedSnapshot().Connections)
+	trackerConn.Close()
+	connection.Leave(E.New("connection reset"))
+	connection.Leave(nil)
+	connections := manager.ClosedSnapshot().Connections
+	require.Len(t, connections, 1)
+	require.Equal(t, connection.ID.String(), connections[0].ID)
+	require.Equal(t, "mixed/mixed-in", connections[0].Metadata.Type)
+	require.Equal(t, []string{"direct"}, connections[0].Chain)
+	require.Equal(t, "final", connections[0].Rule)
+	require.Equal(t, int64(4), connections[0].Upload)
+	require.Equal(t, "connection reset", connections[0].CloseReason)
+	require.False(t, connections[0].End.Before(connections[0].Start))
+}
+
+func TestManagerLoadClosedConnections(t *testing.T) {
+	t.Parallel()
+	manager, _ := newTestManager(t, 3)
+	// snapshots list the newest connection first, the oldest ones are dropped
+	manager.LoadClosedConnections(testClosedConnections(9, 8, 7, 6, 5))
+	require.Equal(t, []int{9, 8, 7}, closedConnectionIDs(manager.ClosedSnapshot().Connections))
+	manager.LoadClosedConnections(testClosedConnections(11, 10))
+	require.Equal(t, []int{11, 10, 9}, closedConnectionIDs(manager.ClosedSnapshot().Connections))
+
+	restored, _ := newTestManager(t, 3)
+	restored.LoadClosedConnections(manager.ClosedSnapshot().Connections)
+	require.Equal(t, manager.ClosedSnapshot(), restored.ClosedSnapshot())
+}
@@ -1,54 +1,85 @@
 package trafficontrol
 
 import (
+	"context"
 	"runtime"
 	"time"
 
+	"github.com/sagernet/sing-box/common/traffic"
 	"github.com/sagernet/sing-box/experimental/clashapi/compatible"
 	"github.com/sagernet/sing/common/atomic"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service"
 )
 
+var _ traffic.Hook = (*Manager)(nil)
+
 type Manager struct {
-	uploadTemp    atomic.Int64
-	downloadTemp  atomic.Int64
-	uploadBlip    atomic.Int64
-	downloadBlip  atomic.Int64
-	uploadTotal   atomic.Int64
-	downloadTotal atomic.Int64
-
-	connections compatible.Map[string, tracker]
+	traffic      *traffic.Manager
+	uploadBase   atomic.Int64
+	downloadBase atomic.Int64
+	uploadBlip   atomic.Int64
+	downloadBlip atomic.Int64
+
+	connections compatible.Map[string, *tracker]
+	history     *connectionHistory
 	ticker      *time.Ticker
 	done        chan struct{}
 	// process     *process.Process
 	memory uint64
 }
 
-func NewManager() *Manager {
+func NewManager(ctx context.Context, historySize int) (*Manager, error) {
+	trafficManager := service.FromContext[*traffic.Manager](ctx)
+	if trafficManager == nil {
+		return nil, E.New("missing traffic manager")
+	}
 	manager := &Manager{
-		ticker: time.NewTicker(time.Second),
-		done:   make(chan struct{}),
+		traffic: trafficManager,
+		ticker:  time.NewTicker(time.Second),
+		done:    make(chan struct{}),
 		// process: &process.Process{Pid: int32(os.Getpid())},
 	}
+	if historySize > 0 {
+		manager.history = newConnectionHistory(historySize)
+	}
+	trafficManager.AddHook(manager)
 	go manager.handle()
-	return manager
+	return manager, nil
 }
 
-func (m *Manager) Join(c tracker) {
-	m.connections.Store(c.ID(), c)
+func (m *Manager) ConnectionJoined(connection *traffic.Connection) {
+	m.connections.Store(connection.ID.String(), newTracker(connection))
 }
 
-func (m *Manager) Leave(c tracker) {
-	m.connections.Delete(c.ID())
+func (m *Manager) ConnectionClosed(connection *traffic.Connection, err error) {
+	t, loaded := m.connections.LoadAndDelete(connection.ID.String())
+	if loaded && m.history != nil {
+		m.history.push(newClosedConnection(t, err))
+	}
 }
 
-func (m *Manager) PushUploaded(size int64) {
-	m.uploadTemp.Add(size)
-	m.uploadTotal.Add(size)
+// ClosedSnapshot returns the recently closed connections from the newest to the oldest.
+func (m *Manager) ClosedSnapshot() *ClosedSnapshot {
+	connections := []ClosedConnection{}
+	if m.history != nil {
+		connections = m.history.list()
+	}
+	return &ClosedSnapshot{Connections: connections}
 }
 
-func (m *Manager) PushDownloaded(size int64) {
-	m.downloadTemp.Add(size)
-	m.downloadTotal.Add(size)
+// LoadClosedConnections restores the connection history from a previous ClosedSnapshot,
+// keeping the newest connections that fit in the history.
+func (m *Manager) LoadClosedConnections(connections []ClosedConnection) {
+	if m.history == nil {
+		return
+	}
+	if len(connections) > m.history.size {
+		connections = connections[:m.history.size]
+	}
+	for i := len(connections) - 1; i >= 0; i-- {
+		m.history.push(connections[i])
+	}
 }
 
 func (m *Manager) Now() (up int64, down int64) {
@@ -56,8 +87,8 @@ func (m *Manager) Now() (up int64, down int64) {
 }
 
 func (m *Manager) Snapshot() *Snapshot {
-	var connections []tracker
-	m.connections.Range(func(_ string, value tracker) bool {
+	var connections []*tracker
+	m.connections.Range(func(_ string, value *tracker) bool {
 		connections = append(connections, value)
 		return true
 	})
@@ -69,36 +100,35 @@ func (m *Manager) Snapshot() *Snapshot {
 	runtime.ReadMemStats(&memStats)
 	m.memory = memStats.StackInuse + memStats.HeapInuse + memStats.HeapIdle - memStats.HeapReleased
 
+	uploadTotal, downloadTotal := m.traffic.Total()
 	return &Snapshot{
-		UploadTotal:   m.uploadTotal.Load(),
-		DownloadTotal: m.downloadTotal.Load(),
+		UploadTotal:   uploadTotal - m.uploadBase.Load(),
+		DownloadTotal: downloadTotal - m.downloadBase.Load(),
 		Connections:   connections,
 		Memory:        m.memory,
 	}
 }
 
 func (m *Manager) ResetStatistic() {
-	m.uploadTemp.Store(0)
+	uploadTotal, downloadTotal := m.traffic.Total()
+	m.uploadBase.Store(uploadTotal)
 	m.uploadBlip.Store(0)
-	m.uploadTotal.Store(0)
-	m.downloadTemp.Store(0)
+	m.downloadBase.Store(downloadTotal)
 	m.downloadBlip.Store(0)
-	m.downloadTotal.Store(0)
 }
 
 func (m *Manager) handle() {
-	var uploadTemp int64
-	var downloadTemp int64
+	lastUpload, lastDownload := m.traffic.Total()
 	for {
 		select {
 		case <-m.done:
 			return
 		case <-m.ticker.C:
 		}
-		uploadTemp = m.uploadTemp.Swap(0)
-		downloadTemp = m.downloadTemp.Swap(0)
-		m.uploadBlip.Store(uploadTemp)
-		m.downloadBlip.Store(downloadTemp)
+		uploadTotal, downloadTotal := m.traffic.Total()
+		m.uploadBlip.Store(uploadTotal - lastUpload)
+		m.downloadBlip.Store(downloadTotal - lastDownload)
+		lastUpload, lastDownload = uploadTotal, downloadTotal
 	}
 }
 
@@ -109,8 +139,8 @@ func (m *Manager) Close() error {
 }
 
 type Snapshot struct {
-	DownloadTotal int64     `json:"downloadTotal"`
-	UploadTotal   int64     `json:"uploadTotal"`
-	Connections   []tracker `json:"connections"`
-	Memory        uint64    `json:"memory"`
+	DownloadTotal int64      `json:"downloadTotal"`
+	UploadTotal   int64      `json:"uploadTotal"`
+	Connections   []*tracker `json:"connections"`
+	Memory        uint64     `json:"memory"`
 }
@@ -2,17 +2,12 @@ package trafficontrol
 
 import (
 	"encoding/json"
-	"net"
 	"net/netip"
-	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/traffic"
 	"github.com/sagernet/sing/common"
-	"github.com/sagernet/sing/common/atomic"
-	"github.com/sagernet/sing/common/bufio"
-	N "github.com/sagernet/sing/common/network"
-
-	"github.com/gofrs/uuid/v5"
+	F "github.com/sagernet/sing/common/format"
 )
 
 type Metadata struct {
@@ -27,206 +22,87 @@ type Metadata struct {
 	ProcessPath string     `json:"processPath"`
 }
 
-type tracker interface {
-	ID() string
-	Close() error
-	Leave()
-}
-
-type trackerInfo struct {
-	UUID          uuid.UUID     `json:"id"`
-	Metadata      Metadata      `json:"metadata"`
-	UploadTotal   *atomic.Int64 `json:"upload"`
-	DownloadTotal *atomic.Int64 `json:"download"`
-	Start         time.Time     `json:"start"`
-	Chain         []string      `json:"chains"`
-	Rule          string        `json:"rule"`
-	RulePayload   string        `json:"rulePayload"`
-}
-
-func (t trackerInfo) MarshalJSON() ([]byte, error) {
-	return json.Marshal(map[string]any{
-		"id":          t.UUID.String(),
-		"metadata":    t.Metadata,
-		"upload":      t.UploadTotal.Load(),
-		"download":    t.DownloadTotal.Load(),
-		"start":       t.Start,
-		"chains":      t.Chain,
-		"rule":        t.Rule,
-		"rulePayload": t.RulePayload,
-	})
-}
-
-type tcpTracker struct {
-	N.ExtendedConn `json:"-"`
-	*trackerInfo
-	manager *Manager
-}
-
-func (tt *tcpTracker) ID() string {
-	return tt.UUID.String()
-}
-
-func (tt *tcpTracker) Close() error {
-	tt.manager.Leave(tt)
-	return tt.ExtendedConn.Close()
+type tracker struct {
+	connection *traffic.Connection
+	metadata   Metadata
+	chain      []string
+	rule       string
 }
 
-func (tt *tcpTracker) Leave() {
-	tt.manager.Leave(tt)
-}
-
-func (tt *tcpTracker) Upstream() any {
-	return tt.ExtendedConn
-}
-
-func (tt *tcpTracker) ReaderReplaceable() bool {
-	return true
-}
-
-func (tt *tcpTracker) WriterReplaceable() bool {
-	return true
-}
-
-func NewTCPTracker(conn net.Conn, manager *Manager, metadata Metadata, router adapter.Router, rule adapter.Rule) *tcpTracker {
-	uuid, _ := uuid.NewV4()
-
-	var chain []string
-	var next string
-	if rule == nil {
-		next = router.DefaultOutbound(N.NetworkTCP).Tag()
-	} else {
-		next = rule.Outbound()
-	}
-	for {
-		chain = append(chain, next)
-		detour, loaded := router.Outbound(next)
-		if !loaded {
-			break
-		}
-		group, isGroup := detour.(adapter.OutboundGroup)
-		if !isGroup {
-			break
-		}
-		next = group.Now()
-	}
-
-	upload := new(atomic.Int64)
-	download := new(atomic.Int64)
-
-	t := &tcpTracker{
-		ExtendedConn: bufio.NewCounterConn(conn, []N.CountFunc{func(n int64) {
-			upload.Add(n)
-			manager.PushUploaded(n)
-		}}, []N.CountFunc{func(n int64) {
-			download.Add(n)
-			manager.PushDownloaded(n)
-		}}),
-		manager: manager,
-		trackerInfo: &trackerInfo{
-			UUID:          uuid,
-			Start:         time.Now(),
-			Metadata:      metadata,
-			Chain:         common.Reverse(chain),
-			Rule:          "",
-			UploadTotal:   upload,
-			DownloadTotal: download,
-		},
+func newTracker(connection *traffic.Connection) *tracker {
+	t := &tracker{
+		connection: connection,
+		metadata:   castMetadata(connection.Metadata),
+		chain:      common.Reverse(append([]string(nil), connection.Chain...)),
 	}
-
-	if rule != nil {
-		t.trackerInfo.Rule = rule.String() + " => " + rule.Outbound()
+	if connection.Rule != nil {
+		t.rule = connection.Rule.String() + " => " + connection.Rule.Outbound()
 	} else {
-		t.trackerInfo.Rule = "final"
+		t.rule = "final"
 	}
-
-	manager.Join(t)
 	return t
 }
 
-type udpTracker struct {
-	N.PacketConn `json:"-"`
-	*trackerInfo
-	manager *Manager
+func (t *tracker) ID() string {
+	return t.connection.ID.String()
 }
 
-func (ut *udpTracker) ID() string {
-	return ut.UUID.String()
+func (t *tracker) Close() error {
+	return t.connection.Close()
 }
 
-func (ut *udpTracker) Close() error {
-	ut.manager.Leave(ut)
-	return ut.PacketConn.Close()
-}
-
-func (ut *udpTracker) Leave() {
-	ut.manager.Leave(ut)
-}
-
-func (ut *udpTracker) Upstream() any {
-	return ut.PacketConn
-}
-
-func (ut *udpTracker) ReaderReplaceable() bool {
-	return true
-}
-
-func (ut *udpTracker) WriterReplaceable() bool {
-	return true
+func (t *tracker) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]any{
+		"id":          t.ID(),
+		"metadata":    t.metadata,
+		"upload":      t.connection.Upload.Load(),
+		"download":    t.connection.Download.Load(),
+		"start":       t.connection.Start,
+		"chains":      t.chain,
+		"rule":        t.rule,
+		"rulePayload": "",
+	})
 }
 
-func NewUDPTracker(conn N.PacketConn, manager *Manager, metadata Metadata, router adapter.Router, rule adapter.Rule) *udpTracker {
-	uuid, _ := uuid.NewV4()
-
-	var chain []string
-	var next string
-	if rule == nil {
-		next = router.DefaultOutbound(N.NetworkUDP).Tag()
+func castMetadata(metadata adapter.InboundContext) Metadata {
+	var inbound string
+	if metadata.Inbound != "" {
+		inbound = metadata.InboundType + "/" + metadata.Inbound
+	} else {
+		inbound = metadata.InboundType
+	}
+	var domain string
+	if metadata.Domain != "" {
+		domain = metadata.Domain
 	} else {
-		next = rule.Outbound()
+		domain = metadata.Destination.Fqdn
 	}
-	for {
-		chain = append(chain, next)
-		detour, loaded := router.Outbound(next)
-		if !loaded {
-			break
+	var processPath string
+	if metadata.ProcessInfo != nil {
+		if metadata.ProcessInfo.ProcessPath != "" {
+			processPath = metadata.ProcessInfo.ProcessPath
+		} else if metadata.ProcessInfo.PackageName != "" {
+			processPath = metadata.ProcessInfo.PackageName
 		}
-		group, isGroup := detour.(adapter.OutboundGroup)
-		if !isGroup {
-			break
+		if processPath == "" {
+			if metadata.ProcessInfo.UserId != -1 {
+				processPath = F.ToString(metadata.ProcessInfo.UserId)
+			}
+		} else if metadata.ProcessInfo.User != "" {
+			processPath = F.ToString(processPath, " (", metadata.ProcessInfo.User, ")")
+		} else if metadata.ProcessInfo.UserId != -1 {
+			processPath = F.ToString(processPath, " (", metadata.ProcessInfo.UserId, ")")
 		}
-		next = group.Now()
 	}
-
-	upload := new(atomic.Int64)
-	download := new(atomic.Int64)
-
-	ut := &udpTracker{
-		PacketConn: bufio.NewCounterPacketConn(conn, []N.CountFunc{func(n int64) {
-			upload.Add(n)
-			manager.PushUploaded(n)
-		}}, []N.CountFunc{func(n int64) {
-			download.Add(n)
-			manager.PushDownloaded(n)
-		}}),
-		manager: manager,
-		trackerInfo: &trackerInfo{
-			UUID:          uuid,
-			Start:         time.Now(),
-			Metadata:      metadata,
-			Chain:         common.Reverse(chain),
-			Rule:          "",
-			UploadTotal:   upload,
-			DownloadTotal: download,
-		},
-	}
-
-	if rule != nil {
-		ut.trackerInfo.Rule = rule.String() + " => " + rule.Outbound()
-	} else {
-		ut.trackerInfo.Rule = "final"
+	return Metadata{
+		NetWork:     metadata.Network,
+		Type:        inbound,
+		SrcIP:       metadata.Source.Addr,
+		DstIP:       metadata.Destination.Addr,
+		SrcPort:     F.ToString(metadata.Source.Port),
+		DstPort:     F.ToString(metadata.Destination.Port),
+		Host:        domain,
+		DNSMode:     "normal",
+		ProcessPath: processPath,
 	}
-
-	manager.Join(ut)
-	return ut
 }
@@ -0,0 +1,25 @@
+package experimental
+
+import (
+	"context"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+)
+
+type MetricsServerConstructor = func(ctx context.Context, router adapter.Router, logger log.Logger, options option.MetricsOptions) (adapter.MetricsServer, error)
+
+var metricsServerConstructor MetricsServerConstructor
+
+func RegisterMetricsServerConstructor(constructor MetricsServerConstructor) {
+	metricsServerConstructor = constructor
+}
+
+func NewMetricsServer(ctx context.Context, router adapter.Router, logger log.Logger, options option.MetricsOptions) (adapter.MetricsServer, error) {
+	if metricsServerConstructor == nil {
+		return nil, os.ErrInvalid
+	}
+	return metricsServerConstructor(ctx, router, logger, options)
+}
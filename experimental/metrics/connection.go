@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"github.com/sagernet/sing-box/common/traffic"
+)
+
+var _ traffic.Hook = (*Server)(nil)
+
+type connectionKey struct {
+	network  string
+	inbound  string
+	outbound string
+	user     string
+	rule     string
+}
+
+func newConnectionKey(connection *traffic.Connection) connectionKey {
+	key := connectionKey{
+		network:  connection.Network,
+		inbound:  connection.Metadata.Inbound,
+		outbound: connection.Chain[0],
+		user:     connection.Metadata.User,
+		rule:     "final",
+	}
+	if connection.Rule != nil {
+		key.rule = connection.Rule.String()
+	}
+	return key
+}
+
+func (k connectionKey) labels() []label {
+	return []label{
+		{"network", k.network},
+		{"inbound", k.inbound},
+		{"outbound", k.outbound},
+		{"user", k.user},
+		{"rule", k.rule},
+	}
+}
+
+// connectionMetric holds the traffic of closed connections, the traffic of
+// active ones is read from their counters on scrape.
+type connectionMetric struct {
+	active   map[*traffic.Connection]struct{}
+	total    int64
+	upload   int64
+	download int64
+}
+
+func (s *Server) ConnectionJoined(connection *traffic.Connection) {
+	key := newConnectionKey(connection)
+	s.connectionAccess.Lock()
+	defer s.connectionAccess.Unlock()
+	metric, loaded := s.connections[key]
+	if !loaded {
+		metric = &connectionMetric{active: make(map[*traffic.Connection]struct{})}
+		s.connections[key] = metric
+	}
+	metric.active[connection] = struct{}{}
+	metric.total++
+}
+
+func (s *Server) ConnectionClosed(connection *traffic.Connection, err error) {
+	s.connectionAccess.Lock()
+	defer s.connectionAccess.Unlock()
+	metric, loaded := s.connections[newConnectionKey(connection)]
+	if !loaded {
+		return
+	}
+	if _, isActive := metric.active[connection]; !isActive {
+		return
+	}
+	delete(metric.active, connection)
+	metric.upload += connection.Upload.Load()
+	metric.download += connection.Download.Load()
+}
+
+func (s *Server) writeConnections(w *writer) {
+	var active, total, upload, download []sample
+	s.connectionAccess.Lock()
+	for key, metric := range s.connections {
+		labels := key.labels()
+		uploadBytes, downloadBytes := metric.upload, metric.download
+		for connection := range metric.active {
+			uploadBytes += connection.Upload.Load()
+			downloadBytes += connection.Download.Load()
+		}
+		active = append(active, sample{labels: labels, value: float64(len(metric.active))})
+		total = append(total, sample{labels: labels, value: float64(metric.total)})
+		upload = append(upload, sample{labels: labels, value: float64(uploadBytes)})
+		download = append(download, sample{labels: labels, value: float64(downloadBytes)})
+	}
+	s.connectionAccess.Unlock()
+	w.family("sing_box_connections_active", "gauge", "Number of active connections.", sortSamples(active))
+	w.family("sing_box_connections_total", "counter", "Total number of routed connections.", sortSamples(total))
+	w.family("sing_box_upload_bytes_total", "counter", "Total bytes sent by clients.", sortSamples(upload))
+	w.family("sing_box_download_bytes_total", "counter", "Total bytes received by clients.", sortSamples(download))
+}
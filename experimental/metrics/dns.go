@@ -0,0 +1,87 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+var dnsDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+type dnsKey struct {
+	transport string
+	queryType string
+}
+
+type dnsMetric struct {
+	success uint64
+	failure uint64
+	buckets []uint64
+	sum     float64
+}
+
+type dnsMetrics struct {
+	access  sync.Mutex
+	queries map[dnsKey]*dnsMetric
+}
+
+func (s *Server) DNSQuery(transport string, queryType string, elapsed time.Duration, err error) {
+	key := dnsKey{transport, queryType}
+	seconds := elapsed.Seconds()
+	s.dns.access.Lock()
+	defer s.dns.access.Unlock()
+	metric, loaded := s.dns.queries[key]
+	if !loaded {
+		metric = &dnsMetric{buckets: make([]uint64, len(dnsDurationBuckets))}
+		s.dns.queries[key] = metric
+	}
+	if err != nil {
+		metric.failure++
+	} else {
+		metric.success++
+	}
+	for i, bound := range dnsDurationBuckets {
+		if seconds <= bound {
+			metric.buckets[i]++
+		}
+	}
+	metric.sum += seconds
+}
+
+func (s *Server) writeDNS(w *writer) {
+	var queries, durations []sample
+	s.dns.access.Lock()
+	keys := make([]dnsKey, 0, len(s.dns.queries))
+	for key := range s.dns.queries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].transport != keys[j].transport {
+			return keys[i].transport < keys[j].transport
+		}
+		return keys[i].queryType < keys[j].queryType
+	})
+	for _, key := range keys {
+		metric := s.dns.queries[key]
+		queries = append(queries,
+			sample{labels: []label{{"transport", key.transport}, {"type", key.queryType}, {"result", "failure"}}, value: float64(metric.failure)},
+			sample{labels: []label{{"transport", key.transport}, {"type", key.queryType}, {"result", "success"}}, value: float64(metric.success)},
+		)
+		for i, bound := range dnsDurationBuckets {
+			durations = append(durations, sample{
+				suffix: "_bucket",
+				labels: []label{{"transport", key.transport}, {"type", key.queryType}, {"le", formatValue(bound)}},
+				value:  float64(metric.buckets[i]),
+			})
+		}
+		count := float64(metric.success + metric.failure)
+		durations = append(durations,
+			sample{suffix: "_bucket", labels: []label{{"transport", key.transport}, {"type", key.queryType}, {"le", "+Inf"}}, value: count},
+			sample{suffix: "_sum", labels: []label{{"transport", key.transport}, {"type", key.queryType}}, value: metric.sum},
+			sample{suffix: "_count", labels: []label{{"transport", key.transport}, {"type", key.queryType}}, value: count},
+		)
+	}
+	s.dns.access.Unlock()
+	w.family("sing_box_dns_queries_total", "counter", "Total number of DNS queries sent to transports.", queries)
+	w.family("sing_box_dns_query_duration_seconds", "histogram", "DNS query latency.", durations)
+}
@@ -0,0 +1,177 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/traffic"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/experimental"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service"
+)
+
+func init() {
+	experimental.RegisterMetricsServerConstructor(func(ctx context.Context, router adapter.Router, logger log.Logger, options option.MetricsOptions) (adapter.MetricsServer, error) {
+		server, err := NewServer(ctx, router, logger, options)
+		if err != nil {
+			return nil, err
+		}
+		return server, nil
+	})
+}
+
+const DefaultPath = "/metrics"
+
+var _ adapter.MetricsServer = (*Server)(nil)
+
+type Server struct {
+	ctx        context.Context
+	router     adapter.Router
+	logger     log.Logger
+	listen     string
+	path       string
+	createdAt  time.Time
+	httpServer *http.Server
+	listener   net.Listener
+
+	connectionAccess sync.Mutex
+	connections      map[connectionKey]*connectionMetric
+	dns              dnsMetrics
+}
+
+func NewServer(ctx context.Context, router adapter.Router, logger log.Logger, options option.MetricsOptions) (*Server, error) {
+	path := options.Path
+	if path == "" {
+		path = DefaultPath
+	} else if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	server := &Server{
+		ctx:         ctx,
+		router:      router,
+		logger:      logger,
+		listen:      options.Listen,
+		path:        path,
+		createdAt:   time.Now(),
+		connections: make(map[connectionKey]*connectionMetric),
+		dns:         dnsMetrics{queries: make(map[dnsKey]*dnsMetric)},
+	}
+	trafficManager := service.FromContext[*traffic.Manager](ctx)
+	if trafficManager == nil {
+		return nil, E.New("missing traffic manager")
+	}
+	trafficManager.AddHook(server)
+	mux := http.NewServeMux()
+	mux.HandleFunc(path, server.serveMetrics)
+	server.httpServer = &http.Server{
+		Handler: mux,
+	}
+	return server, nil
+}
+
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.listen)
+	if err != nil {
+		return err
+	}
+	s.logger.Info("metrics server started at http://", listener.Addr(), s.path)
+	s.listener = listener
+	go func() {
+		err = s.httpServer.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error(err)
+		}
+	}()
+	return nil
+}
+
+func (s *Server) Close() error {
+	return common.Close(
+		common.PtrOrNil(s.httpServer),
+		s.listener,
+	)
+}
+
+func (s *Server) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var metrics writer
+	s.writeInfo(&metrics)
+	s.writeConnections(&metrics)
+	s.writeDNS(&metrics)
+	s.writeURLTest(&metrics)
+	s.writeFakeIP(&metrics)
+	writeRuntime(&metrics)
+	w.Header().Set("Content-Type", ContentType)
+	w.Write(metrics.buffer.Bytes())
+}
+
+func (s *Server) writeInfo(w *writer) {
+	w.family("sing_box_info", "gauge", "Version information about sing-box.", []sample{{labels: []label{{"version", C.Version}}, value: 1}})
+	w.single("sing_box_start_time_seconds", "gauge", "Start time of sing-box since unix epoch in seconds.", float64(s.createdAt.UnixNano())/1e9)
+}
+
+func (s *Server) writeURLTest(w *writer) {
+	var delays []sample
+	for _, outbound := range s.router.Outbounds() {
+		group, isURLTestGroup := outbound.(adapter.URLTestGroup)
+		if !isURLTestGroup {
+			continue
+		}
+		history := group.HistoryStorage()
+		for _, tag := range group.All() {
+			detour, loaded := s.router.Outbound(tag)
+			if !loaded {
+				continue
+			}
+			delay := history.LoadURLTestHistory(adapter.OutboundTag(detour))
+			if delay == nil {
+				continue
+			}
+			delays = append(delays, sample{labels: []label{{"group", outbound.Tag()}, {"outbound", tag}}, value: float64(delay.Delay)})
+		}
+	}
+	w.family("sing_box_urltest_delay_milliseconds", "gauge", "Last URL test delay of each group member.", sortSamples(delays))
+}
+
+func (s *Server) writeFakeIP(w *writer) {
+	store := s.router.FakeIPStore()
+	if store == nil {
+		return
+	}
+	var allocated, size []sample
+	for _, usage := range store.Usage() {
+		labels := []label{{"range", usage.Range.String()}}
+		allocated = append(allocated, sample{labels: labels, value: usage.Allocated})
+		size = append(size, sample{labels: labels, value: usage.Size})
+	}
+	w.family("sing_box_fakeip_allocated_addresses", "gauge", "Number of FakeIP addresses allocated.", allocated)
+	w.family("sing_box_fakeip_pool_addresses", "gauge", "Number of addresses in the FakeIP pool.", size)
+}
+
+func writeRuntime(w *writer) {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	w.family("go_info", "gauge", "Information about the Go environment.", []sample{{labels: []label{{"version", runtime.Version()}}, value: 1}})
+	w.single("go_goroutines", "gauge", "Number of goroutines that currently exist.", float64(runtime.NumGoroutine()))
+	w.single("go_memstats_alloc_bytes", "gauge", "Number of bytes allocated and still in use.", float64(memStats.Alloc))
+	w.single("go_memstats_alloc_bytes_total", "counter", "Total number of bytes allocated, even if freed.", float64(memStats.TotalAlloc))
+	w.single("go_memstats_sys_bytes", "gauge", "Number of bytes obtained from system.", float64(memStats.Sys))
+	w.single("go_memstats_heap_inuse_bytes", "gauge", "Number of heap bytes that are in use.", float64(memStats.HeapInuse))
+	w.single("go_memstats_heap_objects", "gauge", "Number of allocated objects.", float64(memStats.HeapObjects))
+	w.single("go_memstats_last_gc_time_seconds", "gauge", "Number of seconds since 1970 of last garbage collection.", float64(memStats.LastGC)/1e9)
+	w.single("go_gc_cycles_total", "counter", "Number of completed GC cycles.", float64(memStats.NumGC))
+}
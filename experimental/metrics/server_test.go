@@ -0,0 +1,268 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/traffic"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/fakeip"
+	"github.com/sagernet/sing-dns"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testOutbound struct {
+	adapter.Outbound
+	tag string
+}
+
+func (o *testOutbound) Tag() string {
+	return o.tag
+}
+
+type testRouter struct {
+	adapter.Router
+	outbounds   []adapter.Outbound
+	fakeIPStore adapter.FakeIPStore
+}
+
+func (r *testRouter) Outbounds() []adapter.Outbound {
+	return r.outbounds
+}
+
+func (r *testRouter) Outbound(tag string) (adapter.Outbound, bool) {
+	for _, outbound := range r.outbounds {
+		if outbound.Tag() == tag {
+			return outbound, true
+		}
+	}
+	return nil, false
+}
+
+func (r *testRouter) DefaultOutbound(network string) adapter.Outbound {
+	return r.outbounds[0]
+}
+
+func (r *testRouter) FakeIPStore() adapter.FakeIPStore {
+	return r.fakeIPStore
+}
+
+func (r *testRouter) ClashServer() adapter.ClashServer {
+	return nil
+}
+
+type metricSample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+// parseMetrics parses the Prometheus text format, checking that every sample
+// belongs to a family declared with HELP and TYPE.
+func parseMetrics(t *testing.T, content string) (map[string]string, []metricSample) {
+	types := make(map[string]string)
+	var samples []metricSample
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "# HELP ") {
+			continue
+		}
+		if strings.HasPrefix(line, "# TYPE ") {
+			fields := strings.Fields(line)
+			require.Len(t, fields, 4, line)
+			require.NotContains(t, types, fields[2], line)
+			types[fields[2]] = fields[3]
+			continue
+		}
+		var sample metricSample
+		nameEnd := strings.IndexAny(line, "{ ")
+		require.Positive(t, nameEnd, line)
+		sample.name = line[:nameEnd]
+		rest := line[nameEnd:]
+		if strings.HasPrefix(rest, "{") {
+			sample.labels, rest = parseLabels(t, rest[1:])
+		}
+		require.True(t, strings.HasPrefix(rest, " "), line)
+		value, err := strconv.ParseFloat(rest[1:], 64)
+		require.NoError(t, err, line)
+		sample.value = value
+		family := sample.name
+		if types[family] == "" {
+			family = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(family, "_bucket"), "_sum"), "_count")
+			require.Equal(t, "histogram", types[family], line)
+		}
+		samples = append(samples, sample)
+	}
+	require.NoError(t, scanner.Err())
+	return types, samples
+}
+
+func parseLabels(t *testing.T, content string) (map[string]string, string) {
+	labels := make(map[string]string)
+	for {
+		if strings.HasPrefix(content, "}") {
+			return labels, content[1:]
+		}
+		content = strings.TrimPrefix(content, ",")
+		nameEnd := strings.Index(content, "=\"")
+		require.Positive(t, nameEnd, content)
+		name := content[:nameEnd]
+		content = content[nameEnd+2:]
+		var value strings.Builder
+		for {
+			require.NotEmpty(t, content)
+			if content[0] == '"' {
+				content = content[1:]
+				break
+			}
+			if content[0] == '\\' {
+				switch content[1] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(content[1])
+				}
+				content = content[2:]
+				continue
+			}
+			value.WriteByte(content[0])
+			content = content[1:]
+		}
+		labels[name] = value.String()
+	}
+}
+
+func findSample(samples []metricSample, name string, labels map[string]string) (float64, bool) {
+	for _, sample := range samples {
+		if sample.name != name {
+			continue
+		}
+		matched := true
+		for key, value := range labels {
+			if sample.labels[key] != value {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return sample.value, true
+		}
+	}
+	return 0, false
+}
+
+func scrape(t *testing.T, address string) (map[string]string, []metricSample) {
+	response, err := http.Get("http://" + address + DefaultPath)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	require.Equal(t, ContentType, response.Header.Get("Content-Type"))
+	content, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	return parseMetrics(t, string(content))
+}
+
+func TestServerScrape(t *testing.T) {
+	trafficManager := traffic.NewManager()
+	ctx := service.ContextWith(context.Background(), trafficManager)
+	router := &testRouter{outbounds: []adapter.Outbound{&testOutbound{tag: "direct"}}}
+	store := fakeip.NewStore(router, netip.MustParsePrefix("198.18.0.0/30"), netip.Prefix{})
+	require.NoError(t, store.Start())
+	router.fakeIPStore = store
+	server, err := NewServer(ctx, router, log.NewNOPFactory().Logger(), option.MetricsOptions{Listen: "127.0.0.1:0"})
+	require.NoError(t, err)
+	require.NoError(t, server.Start())
+	defer server.Close()
+	address := server.listener.Addr().String()
+
+	types, samples := scrape(t, address)
+	require.Equal(t, "gauge", types["sing_box_info"])
+	require.Equal(t, "gauge", types["go_goroutines"])
+	require.NotContains(t, types, "sing_box_connections_active")
+	value, loaded := findSample(samples, "sing_box_fakeip_allocated_addresses", map[string]string{"range": "198.18.0.0/30"})
+	require.True(t, loaded)
+	require.Zero(t, value)
+	value, _ = findSample(samples, "sing_box_fakeip_pool_addresses", nil)
+	require.Equal(t, float64(2), value)
+
+	_, err = store.Create("example.com", dns.DomainStrategyUseIPv4)
+	require.NoError(t, err)
+	server.DNSQuery("local", "A", 20*time.Millisecond, nil)
+	server.DNSQuery("local", "A", 2*time.Second, E.New("timeout"))
+
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+	metadata := adapter.InboundContext{Inbound: "mixed-in", User: "user"}
+	trackerConn, connection := trafficManager.RoutedConnection(ctx, serverConn, metadata, nil, router.outbounds[0], router)
+	go clientConn.Write([]byte("ping"))
+	_, err = io.ReadFull(trackerConn, make([]byte, 4))
+	require.NoError(t, err)
+	connectionLabels := map[string]string{"network": N.NetworkTCP, "inbound": "mixed-in", "outbound": "direct", "user": "user", "rule": "final"}
+
+	types, samples = scrape(t, address)
+	require.Equal(t, "gauge", types["sing_box_connections_active"])
+	require.Equal(t, "counter", types["sing_box_upload_bytes_total"])
+	require.Equal(t, "histogram", types["sing_box_dns_query_duration_seconds"])
+	value, _ = findSample(samples, "sing_box_connections_active", connectionLabels)
+	require.Equal(t, float64(1), value)
+	value, _ = findSample(samples, "sing_box_upload_bytes_total", connectionLabels)
+	require.Equal(t, float64(4), value)
+	value, _ = findSample(samples, "sing_box_fakeip_allocated_addresses", nil)
+	require.Equal(t, float64(1), value)
+	value, _ = findSample(samples, "sing_box_dns_queries_total", map[string]string{"transport": "local", "type": "A", "result": "failure"})
+	require.Equal(t, float64(1), value)
+	value, _ = findSample(samples, "sing_box_dns_query_duration_seconds_bucket", map[string]string{"transport": "local", "le": "0.025"})
+	require.Equal(t, float64(1), value)
+	value, _ = findSample(samples, "sing_box_dns_query_duration_seconds_bucket", map[string]string{"transport": "local", "le": "+Inf"})
+	require.Equal(t, float64(2), value)
+	value, _ = findSample(samples, "sing_box_dns_query_duration_seconds_count", map[string]string{"transport": "local"})
+	require.Equal(t, float64(2), value)
+
+	// the traffic of closed connections stays in the counters
+	go clientConn.Write([]byte("pong"))
+	_, err = io.ReadFull(trackerConn, make([]byte, 4))
+	require.NoError(t, err)
+	trackerConn.Close()
+	connection.Leave(nil)
+	_, samples = scrape(t, address)
+	value, _ = findSample(samples, "sing_box_connections_active", connectionLabels)
+	require.Zero(t, value)
+	value, _ = findSample(samples, "sing_box_connections_total", connectionLabels)
+	require.Equal(t, float64(1), value)
+	value, _ = findSample(samples, "sing_box_upload_bytes_total", connectionLabels)
+	require.Equal(t, float64(8), value)
+
+	response, err := http.Post("http://"+address+DefaultPath, "text/plain", nil)
+	require.NoError(t, err)
+	response.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+}
+
+func TestServerMissingTrafficManager(t *testing.T) {
+	_, err := NewServer(context.Background(), &testRouter{}, log.NewNOPFactory().Logger(), option.MetricsOptions{Listen: "127.0.0.1:0"})
+	require.Error(t, err)
+}
+
+func TestFormatLabels(t *testing.T) {
+	labels := []label{{"rule", "domain=[a\"b\\c\nd]"}, {"user", ""}}
+	formatted := formatLabels(labels)
+	require.Equal(t, `{rule="domain=[a\"b\\c\nd]",user=""}`, formatted)
+	parsed, rest := parseLabels(t, formatted[1:])
+	require.Empty(t, rest)
+	require.Equal(t, map[string]string{"rule": "domain=[a\"b\\c\nd]", "user": ""}, parsed)
+}
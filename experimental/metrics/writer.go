@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type label struct {
+	name  string
+	value string
+}
+
+type sample struct {
+	suffix string
+	labels []label
+	value  float64
+}
+
+type writer struct {
+	buffer bytes.Buffer
+}
+
+func (w *writer) family(name string, metricType string, help string, samples []sample) {
+	if len(samples) == 0 {
+		return
+	}
+	w.buffer.WriteString("# HELP " + name + " " + help + "\n")
+	w.buffer.WriteString("# TYPE " + name + " " + metricType + "\n")
+	for _, s := range samples {
+		w.buffer.WriteString(name + s.suffix + formatLabels(s.labels) + " " + formatValue(s.value) + "\n")
+	}
+}
+
+func (w *writer) single(name string, metricType string, help string, value float64) {
+	w.family(name, metricType, help, []sample{{value: value}})
+}
+
+func sortSamples(samples []sample) []sample {
+	sort.SliceStable(samples, func(i, j int) bool {
+		return formatLabels(samples[i].labels) < formatLabels(samples[j].labels)
+	})
+	return samples
+}
+
+func formatLabels(labels []label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var builder strings.Builder
+	builder.WriteString("{")
+	for i, l := range labels {
+		if i > 0 {
+			builder.WriteString(",")
+		}
+		builder.WriteString(l.name + "=\"" + labelEscaper.Replace(l.value) + "\"")
+	}
+	builder.WriteString("}")
+	return builder.String()
+}
+
+var labelEscaper = strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\"", "\\\"")
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
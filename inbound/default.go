@@ -3,20 +3,24 @@ package inbound
 import (
 	"context"
 	"net"
+	"net/netip"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/access"
+	"github.com/sagernet/sing-box/common/proxyproto"
 	"github.com/sagernet/sing-box/common/settings"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/atomic"
+	"github.com/sagernet/sing/common/auth"
 	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 )
 
-var _ adapter.Inbound = (*myInboundAdapter)(nil)
+var _ adapter.AccessControlInbound = (*myInboundAdapter)(nil)
 
 type myInboundAdapter struct {
 	protocol         string
@@ -35,11 +39,13 @@ type myInboundAdapter struct {
 
 	setSystemProxy   bool
 	clearSystemProxy func() error
+	pac              *pacServer
 
 	// internal
 
+	access               *access.Controller
 	tcpListener          net.Listener
-	udpConn              *net.UDPConn
+	udpConn              inboundUDPConn
 	udpAddr              M.Socksaddr
 	packetOutboundClosed chan struct{}
 	packetOutbound       chan *myInboundPacket
@@ -68,7 +74,7 @@ func (a *myInboundAdapter) Start() error {
 		}
 		go a.loopTCPIn()
 	}
-	if common.Contains(a.network, N.NetworkUDP) {
+	if common.Contains(a.network, N.NetworkUDP) && a.listenOptions.ListenUnix == "" {
 		_, err = a.ListenUDP()
 		if err != nil {
 			return err
@@ -107,10 +113,61 @@ func (a *myInboundAdapter) Close() error {
 	}
 	return E.Errors(err, common.Close(
 		a.tcpListener,
-		common.PtrOrNil(a.udpConn),
+		a.udpConn,
+		common.PtrOrNil(a.access),
 	))
 }
 
+func (a *myInboundAdapter) initAccess() error {
+	if a.access != nil || a.listenOptions.Access == nil {
+		return nil
+	}
+	controller, err := access.NewController(a.logger, *a.listenOptions.Access)
+	if err != nil {
+		return E.Cause(err, "create access controller")
+	}
+	a.access = controller
+	return nil
+}
+
+func (a *myInboundAdapter) AccessController() adapter.InboundAccessController {
+	if a.access == nil {
+		return nil
+	}
+	return a.access
+}
+
+// accessAuthenticator counts failed verifications from source towards auth_failure_limit,
+// as the socks and http handshakes do not return them as errors.
+func (a *myInboundAdapter) accessAuthenticator(authenticator auth.Authenticator, source M.Socksaddr) auth.Authenticator {
+	if _, isAccess := authenticator.(*accessAuthenticator); isAccess || authenticator == nil || a.access == nil {
+		return authenticator
+	}
+	return &accessAuthenticator{authenticator, a.access, source.Addr}
+}
+
+type accessAuthenticator struct {
+	auth.Authenticator
+	controller *access.Controller
+	source     netip.Addr
+}
+
+func (a *accessAuthenticator) Verify(user string, pass string) bool {
+	if a.Authenticator.Verify(user, pass) {
+		return true
+	}
+	a.controller.ConnectionFailed(a.source, access.NewAuthError(E.New("wrong password for user ", user)))
+	return false
+}
+
+// wrapAuthError marks err as an authentication failure if it is one of authErrors.
+func wrapAuthError(err error, authErrors ...error) error {
+	if err != nil && E.IsMulti(err, authErrors...) {
+		return access.NewAuthError(err)
+	}
+	return err
+}
+
 func (a *myInboundAdapter) upstreamHandler(metadata adapter.InboundContext) adapter.UpstreamHandlerAdapter {
 	return adapter.NewUpstreamHandler(metadata, a.newConnection, a.streamPacketConnection, a)
 }
@@ -141,6 +198,12 @@ func (a *myInboundAdapter) createMetadata(conn net.Conn, metadata adapter.Inboun
 	metadata.InboundType = a.protocol
 	metadata.InboundDetour = a.listenOptions.Detour
 	metadata.InboundOptions = a.listenOptions.InboundOptions
+	if proxyConn, isProxyConn := common.Cast[*proxyproto.Conn](conn); isProxyConn {
+		metadata.ProxyProtocol = proxyConn.Info()
+	}
+	if _, isUnix := conn.LocalAddr().(*net.UnixAddr); isUnix {
+		return metadata
+	}
 	if !metadata.Source.IsValid() {
 		metadata.Source = M.SocksaddrFromNet(conn.RemoteAddr()).Unwrap()
 	}
@@ -0,0 +1,304 @@
+package inbound
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/common/portrange"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+func (a *myInboundAdapter) listenPorts() ([]uint16, error) {
+	ports, err := portrange.Parse(a.listenOptions.ListenPorts)
+	if err != nil {
+		return nil, E.Cause(err, "parse listen_ports")
+	}
+	if a.listenOptions.ListenPort != 0 && !common.Contains(ports, a.listenOptions.ListenPort) {
+		ports = append([]uint16{a.listenOptions.ListenPort}, ports...)
+	}
+	if len(ports) == 0 {
+		ports = []uint16{0}
+	}
+	return ports, nil
+}
+
+type multiListener struct {
+	listeners []net.Listener
+	conns     chan multiAcceptResult
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+type multiAcceptResult struct {
+	conn net.Conn
+	err  error
+}
+
+func newMultiListener(listeners []net.Listener) *multiListener {
+	listener := &multiListener{
+		listeners: listeners,
+		conns:     make(chan multiAcceptResult),
+		done:      make(chan struct{}),
+	}
+	for _, subListener := range listeners {
+		go listener.loopAccept(subListener)
+	}
+	return listener
+}
+
+func (l *multiListener) loopAccept(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		select {
+		case l.conns <- multiAcceptResult{conn, err}:
+		case <-l.done:
+			common.Close(conn)
+			return
+		}
+		if err != nil {
+			//goland:noinspection GoDeprecation
+			//nolint:staticcheck
+			if netError, isNetError := err.(net.Error); isNetError && netError.Temporary() {
+				continue
+			}
+			return
+		}
+	}
+}
+
+func (l *multiListener) Accept() (net.Conn, error) {
+	select {
+	case result := <-l.conns:
+		return result.conn, result.err
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *multiListener) Close() error {
+	l.closeOnce.Do(func() {
+		close(l.done)
+	})
+	return common.Close(common.Map(l.listeners, func(it net.Listener) any {
+		return it
+	})...)
+}
+
+func (l *multiListener) Addr() net.Addr {
+	return l.listeners[0].Addr()
+}
+
+type multiUDPConn struct {
+	conns     []*net.UDPConn
+	packets   chan *multiUDPPacket
+	done      chan struct{}
+	closeOnce sync.Once
+
+	routeAccess sync.Mutex
+	routes      map[netip.AddrPort]multiUDPRoute
+	lastPurge   time.Time
+
+	deadlineAccess sync.Mutex
+	readDeadline   time.Time
+	deadlineNotify chan struct{}
+}
+
+type multiUDPPacket struct {
+	conn   *net.UDPConn
+	buffer *buf.Buffer
+	addr   netip.AddrPort
+	err    error
+}
+
+type multiUDPRoute struct {
+	conn     *net.UDPConn
+	lastSeen time.Time
+}
+
+// newMultiUDPConn merges packets received on several sockets into one connection.
+// Replies are written from the socket that last received a packet from the destination.
+func newMultiUDPConn(conns []*net.UDPConn) *multiUDPConn {
+	conn := &multiUDPConn{
+		conns:          conns,
+		packets:        make(chan *multiUDPPacket),
+		done:           make(chan struct{}),
+		routes:         make(map[netip.AddrPort]multiUDPRoute),
+		lastPurge:      time.Now(),
+		deadlineNotify: make(chan struct{}),
+	}
+	for _, udpConn := range conns {
+		go conn.loopRead(udpConn)
+	}
+	return conn
+}
+
+func (c *multiUDPConn) loopRead(conn *net.UDPConn) {
+	for {
+		buffer := buf.NewPacket()
+		n, addr, err := conn.ReadFromUDPAddrPort(buffer.FreeBytes())
+		var packet *multiUDPPacket
+		if err != nil {
+			buffer.Release()
+			packet = &multiUDPPacket{err: err}
+		} else {
+			buffer.Truncate(n)
+			packet = &multiUDPPacket{conn: conn, buffer: buffer, addr: addr}
+		}
+		select {
+		case c.packets <- packet:
+		case <-c.done:
+			if packet.buffer != nil {
+				packet.buffer.Release()
+			}
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (c *multiUDPConn) ReadFromUDPAddrPort(b []byte) (int, netip.AddrPort, error) {
+	for {
+		c.deadlineAccess.Lock()
+		deadline := c.readDeadline
+		deadlineNotify := c.deadlineNotify
+		c.deadlineAccess.Unlock()
+		var (
+			timer   *time.Timer
+			timeout <-chan time.Time
+		)
+		if !deadline.IsZero() {
+			duration := time.Until(deadline)
+			if duration <= 0 {
+				return 0, netip.AddrPort{}, os.ErrDeadlineExceeded
+			}
+			timer = time.NewTimer(duration)
+			timeout = timer.C
+		}
+		var (
+			packet          *multiUDPPacket
+			deadlineChanged bool
+		)
+		select {
+		case packet = <-c.packets:
+		case <-c.done:
+			packet = &multiUDPPacket{err: net.ErrClosed}
+		case <-timeout:
+			packet = &multiUDPPacket{err: os.ErrDeadlineExceeded}
+		case <-deadlineNotify:
+			deadlineChanged = true
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if deadlineChanged {
+			continue
+		}
+		if packet.err != nil {
+			return 0, netip.AddrPort{}, packet.err
+		}
+		c.updateRoute(packet.addr, packet.conn)
+		n := copy(b, packet.buffer.Bytes())
+		packet.buffer.Release()
+		return n, packet.addr, nil
+	}
+}
+
+// ReadMsgUDPAddrPort does not return control messages.
+func (c *multiUDPConn) ReadMsgUDPAddrPort(b []byte, oob []byte) (n int, oobn int, flags int, addr netip.AddrPort, err error) {
+	n, addr, err = c.ReadFromUDPAddrPort(b)
+	return
+}
+
+func (c *multiUDPConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	n, addrPort, err := c.ReadFromUDPAddrPort(p)
+	if err != nil {
+		return
+	}
+	return n, net.UDPAddrFromAddrPort(addrPort), nil
+}
+
+func (c *multiUDPConn) updateRoute(addr netip.AddrPort, conn *net.UDPConn) {
+	now := time.Now()
+	c.routeAccess.Lock()
+	defer c.routeAccess.Unlock()
+	c.routes[routeKey(addr)] = multiUDPRoute{conn, now}
+	if now.Sub(c.lastPurge) < time.Minute {
+		return
+	}
+	c.lastPurge = now
+	for key, route := range c.routes {
+		if now.Sub(route.lastSeen) > C.UDPTimeout {
+			delete(c.routes, key)
+		}
+	}
+}
+
+func (c *multiUDPConn) route(addr netip.AddrPort) *net.UDPConn {
+	c.routeAccess.Lock()
+	defer c.routeAccess.Unlock()
+	if route, loaded := c.routes[routeKey(addr)]; loaded {
+		return route.conn
+	}
+	return c.conns[0]
+}
+
+func routeKey(addr netip.AddrPort) netip.AddrPort {
+	return netip.AddrPortFrom(addr.Addr().Unmap(), addr.Port())
+}
+
+func (c *multiUDPConn) WriteToUDPAddrPort(b []byte, addr netip.AddrPort) (int, error) {
+	return c.route(addr).WriteToUDPAddrPort(b, addr)
+}
+
+func (c *multiUDPConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
+	return c.WriteToUDPAddrPort(p, M.AddrPortFromNet(addr))
+}
+
+func (c *multiUDPConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+	return common.Close(common.Map(c.conns, func(it *net.UDPConn) any {
+		return it
+	})...)
+}
+
+func (c *multiUDPConn) LocalAddr() net.Addr {
+	return c.conns[0].LocalAddr()
+}
+
+func (c *multiUDPConn) SetDeadline(t time.Time) error {
+	err := c.SetReadDeadline(t)
+	if err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
+}
+
+func (c *multiUDPConn) SetReadDeadline(t time.Time) error {
+	c.deadlineAccess.Lock()
+	defer c.deadlineAccess.Unlock()
+	c.readDeadline = t
+	close(c.deadlineNotify)
+	c.deadlineNotify = make(chan struct{})
+	return nil
+}
+
+func (c *multiUDPConn) SetWriteDeadline(t time.Time) error {
+	for _, conn := range c.conns {
+		err := conn.SetWriteDeadline(t)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/proxyproto"
 	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
@@ -14,23 +15,60 @@ import (
 )
 
 func (a *myInboundAdapter) ListenTCP() (net.Listener, error) {
-	var err error
-	bindAddr := M.SocksaddrFrom(a.listenOptions.Listen.Build(), a.listenOptions.ListenPort)
+	err := a.initAccess()
+	if err != nil {
+		return nil, err
+	}
 	var tcpListener net.Listener
-	if !a.listenOptions.TCPFastOpen {
-		tcpListener, err = net.ListenTCP(M.NetworkFromNetAddr(N.NetworkTCP, bindAddr.Addr), bindAddr.TCPAddr())
-	} else {
-		tcpListener, err = tfo.ListenTCP(M.NetworkFromNetAddr(N.NetworkTCP, bindAddr.Addr), bindAddr.TCPAddr())
+	ports, err := a.listenPorts()
+	if err != nil {
+		return nil, err
 	}
-	if err == nil {
+	if a.listenOptions.ListenUnix != "" {
+		tcpListener, err = a.listenUnix()
+		if err != nil {
+			return nil, err
+		}
+		a.logger.Info("unix server started at ", a.listenOptions.ListenUnix)
+	} else if len(ports) == 1 {
+		tcpListener, err = a.listenTCP(ports[0])
+		if err != nil {
+			return nil, err
+		}
 		a.logger.Info("tcp server started at ", tcpListener.Addr())
+	} else {
+		listeners := make([]net.Listener, 0, len(ports))
+		for _, port := range ports {
+			listener, lErr := a.listenTCP(port)
+			if lErr != nil {
+				common.Close(common.Map(listeners, func(it net.Listener) any {
+					return it
+				})...)
+				return nil, lErr
+			}
+			listeners = append(listeners, listener)
+		}
+		tcpListener = newMultiListener(listeners)
+		a.logger.Info("tcp server started at ", tcpListener.Addr(), " and ", len(ports)-1, " more ports")
 	}
 	if a.listenOptions.ProxyProtocol {
 		a.logger.Debug("proxy protocol enabled")
 		tcpListener = &proxyproto.Listener{Listener: tcpListener, AcceptNoHeader: a.listenOptions.ProxyProtocolAcceptNoHeader}
 	}
+	if a.access != nil {
+		tcpListener = a.access.Listener(tcpListener)
+	}
 	a.tcpListener = tcpListener
-	return tcpListener, err
+	return tcpListener, nil
+}
+
+func (a *myInboundAdapter) listenTCP(port uint16) (net.Listener, error) {
+	bindAddr := M.SocksaddrFrom(a.listenOptions.Listen.Build(), port)
+	if !a.listenOptions.TCPFastOpen {
+		return net.ListenTCP(M.NetworkFromNetAddr(N.NetworkTCP, bindAddr.Addr), bindAddr.TCPAddr())
+	} else {
+		return tfo.ListenTCP(M.NetworkFromNetAddr(N.NetworkTCP, bindAddr.Addr), bindAddr.TCPAddr())
+	}
 }
 
 func (a *myInboundAdapter) loopTCPIn() {
@@ -62,6 +100,9 @@ func (a *myInboundAdapter) injectTCP(conn net.Conn, metadata adapter.InboundCont
 	hErr := a.connHandler.NewConnection(ctx, conn, metadata)
 	if hErr != nil {
 		conn.Close()
+		if a.access != nil {
+			a.access.ConnectionFailed(metadata.Source.Addr, hErr)
+		}
 		a.NewError(ctx, E.Cause(hErr, "process connection from ", metadata.Source))
 	}
 }
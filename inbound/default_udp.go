@@ -2,6 +2,7 @@ package inbound
 
 import (
 	"net"
+	"net/netip"
 	"os"
 	"time"
 
@@ -14,8 +15,57 @@ import (
 	N "github.com/sagernet/sing/common/network"
 )
 
+type inboundUDPConn interface {
+	net.PacketConn
+	ReadFromUDPAddrPort(b []byte) (n int, addr netip.AddrPort, err error)
+	ReadMsgUDPAddrPort(b []byte, oob []byte) (n int, oobn int, flags int, addr netip.AddrPort, err error)
+	WriteToUDPAddrPort(b []byte, addr netip.AddrPort) (int, error)
+}
+
 func (a *myInboundAdapter) ListenUDP() (net.PacketConn, error) {
-	bindAddr := M.SocksaddrFrom(a.listenOptions.Listen.Build(), a.listenOptions.ListenPort)
+	err := a.initAccess()
+	if err != nil {
+		return nil, err
+	}
+	if a.listenOptions.ListenUnix != "" {
+		return nil, E.New("UDP is not supported on unix socket")
+	}
+	ports, err := a.listenPorts()
+	if err != nil {
+		return nil, err
+	}
+	if len(ports) == 1 {
+		udpConn, err := a.listenUDP(ports[0])
+		if err != nil {
+			return nil, err
+		}
+		a.udpConn = udpConn
+		a.logger.Info("udp server started at ", udpConn.LocalAddr())
+	} else {
+		udpConns := make([]*net.UDPConn, 0, len(ports))
+		for _, port := range ports {
+			udpConn, err := a.listenUDP(port)
+			if err != nil {
+				common.Close(common.Map(udpConns, func(it *net.UDPConn) any {
+					return it
+				})...)
+				return nil, err
+			}
+			udpConns = append(udpConns, udpConn)
+		}
+		udpConn := newMultiUDPConn(udpConns)
+		a.udpConn = udpConn
+		a.logger.Info("udp server started at ", udpConn.LocalAddr(), " and ", len(ports)-1, " more ports")
+	}
+	a.udpAddr = M.SocksaddrFrom(a.listenOptions.Listen.Build(), ports[0])
+	if a.access != nil {
+		return a.access.PacketConn(a.udpConn), nil
+	}
+	return a.udpConn, nil
+}
+
+func (a *myInboundAdapter) listenUDP(port uint16) (*net.UDPConn, error) {
+	bindAddr := M.SocksaddrFrom(a.listenOptions.Listen.Build(), port)
 	var lc net.ListenConfig
 	var udpFragment bool
 	if a.listenOptions.UDPFragment != nil {
@@ -30,10 +80,7 @@ func (a *myInboundAdapter) ListenUDP() (net.PacketConn, error) {
 	if err != nil {
 		return nil, err
 	}
-	a.udpConn = udpConn.(*net.UDPConn)
-	a.udpAddr = bindAddr
-	a.logger.Info("udp server started at ", udpConn.LocalAddr())
-	return udpConn, err
+	return udpConn.(*net.UDPConn), nil
 }
 
 func (a *myInboundAdapter) loopUDPIn() {
@@ -51,6 +98,9 @@ func (a *myInboundAdapter) loopUDPIn() {
 		if err != nil {
 			return
 		}
+		if a.access != nil && !a.access.AllowPacket(addr.Addr()) {
+			continue
+		}
 		buffer.Truncate(n)
 		var metadata adapter.InboundContext
 		metadata.Inbound = a.tag
@@ -81,6 +131,9 @@ func (a *myInboundAdapter) loopUDPOOBIn() {
 		if err != nil {
 			return
 		}
+		if a.access != nil && !a.access.AllowPacket(addr.Addr()) {
+			continue
+		}
 		buffer.Truncate(n)
 		var metadata adapter.InboundContext
 		metadata.Inbound = a.tag
@@ -105,6 +158,10 @@ func (a *myInboundAdapter) loopUDPInThreadSafe() {
 			buffer.Release()
 			return
 		}
+		if a.access != nil && !a.access.AllowPacket(addr.Addr()) {
+			buffer.Release()
+			continue
+		}
 		buffer.Truncate(n)
 		var metadata adapter.InboundContext
 		metadata.Inbound = a.tag
@@ -131,6 +188,10 @@ func (a *myInboundAdapter) loopUDPOOBInThreadSafe() {
 			buffer.Release()
 			return
 		}
+		if a.access != nil && !a.access.AllowPacket(addr.Addr()) {
+			buffer.Release()
+			continue
+		}
 		buffer.Truncate(n)
 		var metadata adapter.InboundContext
 		metadata.Inbound = a.tag
@@ -0,0 +1,99 @@
+package inbound
+
+import (
+	"errors"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func (a *myInboundAdapter) listenUnix() (net.Listener, error) {
+	path := a.listenOptions.ListenUnix
+	abstract := strings.HasPrefix(path, "@")
+	if !abstract {
+		if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+			err = removeStaleSocket(path)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if !abstract {
+		err = a.configureUnixSocket(path)
+		if err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+	return listener, nil
+}
+
+// removeStaleSocket removes a socket file left behind by a previous process,
+// refusing to touch it if something is still listening on it.
+func removeStaleSocket(path string) error {
+	conn, err := net.DialTimeout("unix", path, time.Second)
+	if err == nil {
+		conn.Close()
+		return E.New("unix socket ", path, " is already in use")
+	}
+	if !errors.Is(err, syscall.ECONNREFUSED) {
+		return E.Cause(err, "check stale socket")
+	}
+	err = os.Remove(path)
+	if err != nil {
+		return E.Cause(err, "remove stale socket")
+	}
+	return nil
+}
+
+func (a *myInboundAdapter) configureUnixSocket(path string) error {
+	if a.listenOptions.ListenUnixMode != "" {
+		mode, err := strconv.ParseUint(a.listenOptions.ListenUnixMode, 8, 32)
+		if err != nil {
+			return E.Cause(err, "parse listen_unix_mode")
+		}
+		err = os.Chmod(path, os.FileMode(mode))
+		if err != nil {
+			return E.Cause(err, "chmod unix socket")
+		}
+	}
+	if a.listenOptions.ListenUnixUser == "" && a.listenOptions.ListenUnixGroup == "" {
+		return nil
+	}
+	uid, gid := -1, -1
+	if a.listenOptions.ListenUnixUser != "" {
+		socketUser, err := user.Lookup(a.listenOptions.ListenUnixUser)
+		if err != nil {
+			socketUser, err = user.LookupId(a.listenOptions.ListenUnixUser)
+		}
+		if err != nil {
+			return E.Cause(err, "lookup listen_unix_user")
+		}
+		uid, _ = strconv.Atoi(socketUser.Uid)
+	}
+	if a.listenOptions.ListenUnixGroup != "" {
+		socketGroup, err := user.LookupGroup(a.listenOptions.ListenUnixGroup)
+		if err != nil {
+			socketGroup, err = user.LookupGroupId(a.listenOptions.ListenUnixGroup)
+		}
+		if err != nil {
+			return E.Cause(err, "lookup listen_unix_group")
+		}
+		gid, _ = strconv.Atoi(socketGroup.Gid)
+	}
+	err := os.Chown(path, uid, gid)
+	if err != nil {
+		return E.Cause(err, "chown unix socket")
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+//go:build linux
+
+package inbound
+
+import (
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestUnixInbound(options option.ListenOptions) *myInboundAdapter {
+	return &myInboundAdapter{listenOptions: options}
+}
+
+func TestListenUnix(t *testing.T) {
+	t.Parallel()
+	currentUser, err := user.Current()
+	require.NoError(t, err)
+	currentGroup, err := user.LookupGroupId(currentUser.Gid)
+	require.NoError(t, err)
+	for _, testCase := range []struct {
+		name  string
+		user  string
+		group string
+	}{
+		{name: "name", user: currentUser.Username, group: currentGroup.Name},
+		{name: "id", user: currentUser.Uid, group: currentUser.Gid},
+	} {
+		path := filepath.Join(t.TempDir(), "test.sock")
+		listener, err := newTestUnixInbound(option.ListenOptions{
+			ListenUnix:      path,
+			ListenUnixMode:  "0600",
+			ListenUnixUser:  testCase.user,
+			ListenUnixGroup: testCase.group,
+		}).listenUnix()
+		require.NoError(t, err, testCase.name)
+		info, err := os.Lstat(path)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o600), info.Mode().Perm(), testCase.name)
+		stat := info.Sys().(*syscall.Stat_t)
+		require.Equal(t, currentUser.Uid, strconv.Itoa(int(stat.Uid)), testCase.name)
+		require.Equal(t, currentUser.Gid, strconv.Itoa(int(stat.Gid)), testCase.name)
+		conn, err := net.Dial("unix", path)
+		require.NoError(t, err)
+		conn.Close()
+		serverConn, err := listener.Accept()
+		require.NoError(t, err)
+		serverConn.Close()
+		listener.Close()
+	}
+}
+
+func TestListenUnixInvalidOptions(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		options option.ListenOptions
+		err     string
+	}{
+		{options: option.ListenOptions{ListenUnixMode: "0999"}, err: "listen_unix_mode"},
+		{options: option.ListenOptions{ListenUnixUser: "sing-box-test-nonexistent"}, err: "listen_unix_user"},
+		{options: option.ListenOptions{ListenUnixGroup: "sing-box-test-nonexistent"}, err: "listen_unix_group"},
+	} {
+		path := filepath.Join(t.TempDir(), "test.sock")
+		testCase.options.ListenUnix = path
+		_, err := newTestUnixInbound(testCase.options).listenUnix()
+		require.ErrorContains(t, err, testCase.err)
+		// the socket is closed and removed on failure
+		_, err = os.Lstat(path)
+		require.True(t, os.IsNotExist(err), testCase.err)
+	}
+}
+
+func TestListenUnixStaleSocket(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "test.sock")
+	staleListener, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	require.NoError(t, err)
+	staleListener.SetUnlinkOnClose(false)
+	staleListener.Close()
+	_, err = os.Lstat(path)
+	require.NoError(t, err)
+
+	listener, err := newTestUnixInbound(option.ListenOptions{ListenUnix: path}).listenUnix()
+	require.NoError(t, err)
+	listener.Close()
+}
+
+func TestListenUnixInUse(t *testing.T) {
+	t.Parallel()
+	path := filepath.Join(t.TempDir(), "test.sock")
+	activeListener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	defer activeListener.Close()
+	go func() {
+		for {
+			conn, err := activeListener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	_, err = newTestUnixInbound(option.ListenOptions{ListenUnix: path}).listenUnix()
+	require.ErrorContains(t, err, "already in use")
+	// the running listener keeps its socket
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func TestListenUnixAbstract(t *testing.T) {
+	t.Parallel()
+	path := "@sing-box-test-" + strconv.Itoa(os.Getpid())
+	listener, err := newTestUnixInbound(option.ListenOptions{ListenUnix: path, ListenUnixMode: "0600"}).listenUnix()
+	require.NoError(t, err)
+	defer listener.Close()
+	conn, err := net.Dial("unix", path)
+	require.NoError(t, err)
+	conn.Close()
+}
@@ -3,6 +3,8 @@ package inbound
 import (
 	std_bufio "bufio"
 	"context"
+	"crypto/x509"
+	"io"
 	"net"
 	"os"
 
@@ -15,18 +17,22 @@ import (
 	"github.com/sagernet/sing/common/auth"
 	E "github.com/sagernet/sing/common/exceptions"
 	N "github.com/sagernet/sing/common/network"
-	"github.com/sagernet/sing/protocol/http"
+
+	"golang.org/x/net/http2"
 )
 
 var (
 	_ adapter.Inbound           = (*HTTP)(nil)
 	_ adapter.InjectableInbound = (*HTTP)(nil)
+	_ adapter.TLSInbound        = (*HTTP)(nil)
 )
 
 type HTTP struct {
 	myInboundAdapter
 	authenticator auth.Authenticator
 	tlsConfig     tls.ServerConfig
+	http3         bool
+	h3Server      io.Closer
 }
 
 func NewHTTP(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.HTTPMixedInboundOptions) (*HTTP, error) {
@@ -42,6 +48,7 @@ func NewHTTP(ctx context.Context, router adapter.Router, logger log.ContextLogge
 			setSystemProxy: options.SetSystemProxy,
 		},
 		authenticator: auth.NewAuthenticator(options.Users),
+		http3:         options.HTTP3,
 	}
 	if options.TLS != nil {
 		tlsConfig, err := tls.NewServer(ctx, router, logger, common.PtrValueOrDefault(options.TLS))
@@ -50,6 +57,10 @@ func NewHTTP(ctx context.Context, router adapter.Router, logger log.ContextLogge
 		}
 		inbound.tlsConfig = tlsConfig
 	}
+	if options.HTTP3 && inbound.tlsConfig == nil {
+		return nil, E.New("TLS is required for HTTP/3 server")
+	}
+	inbound.pac = newPACServer(options.PAC, options.TLS != nil)
 	inbound.connHandler = inbound
 	return inbound, nil
 }
@@ -61,25 +72,44 @@ func (h *HTTP) Start() error {
 			return E.Cause(err, "create TLS config")
 		}
 	}
-	return h.myInboundAdapter.Start()
+	err := h.myInboundAdapter.Start()
+	if err != nil {
+		return err
+	}
+	if h.http3 {
+		h.h3Server, err = h.listenHTTP3(h.tlsConfig, h.authenticator)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (h *HTTP) Close() error {
 	return common.Close(
 		&h.myInboundAdapter,
+		h.h3Server,
 		h.tlsConfig,
 	)
 }
 
+func (h *HTTP) Certificates() []*x509.Certificate {
+	return tls.ServerCertificates(h.tlsConfig)
+}
+
 func (h *HTTP) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
-	var err error
 	if h.tlsConfig != nil {
-		conn, err = tls.ServerHandshake(ctx, conn, h.tlsConfig)
+		tlsConn, err := tls.ServerHandshake(ctx, conn, h.tlsConfig)
 		if err != nil {
 			return err
 		}
+		metadata.User = tls.ClientCertificateUser(tlsConn.ConnectionState().VerifiedChains)
+		if tlsConn.ConnectionState().NegotiatedProtocol == http2.NextProtoTLS {
+			return h.newHTTP2Connection(ctx, tlsConn, h.authenticator, metadata)
+		}
+		conn = tlsConn
 	}
-	return http.HandleConnection(ctx, conn, std_bufio.NewReader(conn), h.authenticator, h.upstreamUserHandler(metadata), adapter.UpstreamMetadata(metadata))
+	return h.newHTTPConnection(ctx, conn, std_bufio.NewReader(conn), h.accessAuthenticator(h.authenticator, metadata.Source), metadata)
 }
 
 func (h *HTTP) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
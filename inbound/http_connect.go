@@ -0,0 +1,122 @@
+package inbound
+
+import (
+	std_bufio "bufio"
+	"context"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/pac"
+	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/transport/v2rayhttp"
+	"github.com/sagernet/sing/common/auth"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	sHTTP "github.com/sagernet/sing/protocol/http"
+
+	"golang.org/x/net/http2"
+)
+
+func (a *myInboundAdapter) newHTTPConnection(ctx context.Context, conn net.Conn, reader *std_bufio.Reader, authenticator auth.Authenticator, metadata adapter.InboundContext) error {
+	if prefix, err := reader.Peek(3); err == nil && string(prefix) == "PRI" {
+		cached, _ := reader.Peek(reader.Buffered())
+		return a.newHTTP2Connection(ctx, bufio.NewCachedConn(conn, buf.As(cached)), authenticator, metadata)
+	}
+	if a.isPACRequest(reader) {
+		return a.servePAC(ctx, conn, reader, metadata)
+	}
+	return sHTTP.HandleConnection(ctx, conn, reader, authenticator, a.upstreamUserHandler(metadata), adapter.UpstreamMetadata(metadata))
+}
+
+func (a *myInboundAdapter) newHTTP2Connection(ctx context.Context, conn net.Conn, authenticator auth.Authenticator, metadata adapter.InboundContext) error {
+	h2Server := &http2.Server{}
+	h2Server.ServeConn(conn, &http2.ServeConnOpts{
+		Context: ctx,
+		Handler: &httpConnectHandler{
+			myInboundAdapter: a,
+			authenticator:    authenticator,
+			metadata:         metadata,
+		},
+	})
+	return nil
+}
+
+type httpConnectHandler struct {
+	*myInboundAdapter
+	authenticator auth.Authenticator
+	metadata      adapter.InboundContext
+}
+
+func (h *httpConnectHandler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	ctx := log.ContextWithNewID(request.Context())
+	metadata := h.metadata
+	if !metadata.Source.IsValid() {
+		metadata.Source = M.ParseSocksaddr(request.RemoteAddr).Unwrap()
+	}
+	if metadata.User == "" && request.TLS != nil {
+		metadata.User = tls.ClientCertificateUser(request.TLS.VerifiedChains)
+	}
+	if h.pac != nil && request.Method != http.MethodConnect {
+		content, loaded := h.generatePAC(request, metadata)
+		if !loaded {
+			http.NotFound(writer, request)
+			return
+		}
+		h.logger.InfoContext(ctx, "serve PAC file to ", metadata.Source)
+		writer.Header().Set("Content-Type", pac.ContentType)
+		writer.Header().Set("Cache-Control", "no-cache")
+		writer.Write([]byte(content))
+		return
+	}
+	if request.Method != http.MethodConnect {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		h.NewError(ctx, E.New("process connection from ", metadata.Source, ": not CONNECT request"))
+		return
+	}
+	if h.authenticator != nil {
+		username, password, loaded := httpProxyAuthorization(request)
+		if !loaded || !h.accessAuthenticator(h.authenticator, metadata.Source).Verify(username, password) {
+			writer.Header().Set("Proxy-Authenticate", "Basic realm=\"proxy\"")
+			writer.WriteHeader(http.StatusProxyAuthRequired)
+			h.NewError(ctx, E.New("process connection from ", metadata.Source, ": authorization failed"))
+			return
+		}
+		ctx = auth.ContextWithUser(ctx, username)
+	}
+	hostPort := request.URL.Host
+	if hostPort == "" {
+		hostPort = request.Host
+	}
+	metadata.Destination = M.ParseSocksaddr(hostPort)
+	writer.WriteHeader(http.StatusOK)
+	writer.(http.Flusher).Flush()
+	h.logger.InfoContext(ctx, "inbound connection from ", metadata.Source)
+	conn := v2rayhttp.NewHTTP2Wrapper(&v2rayhttp.ServerHTTPConn{
+		HTTP2Conn: v2rayhttp.NewHTTPConn(request.Body, writer),
+		Flusher:   writer.(http.Flusher),
+	})
+	err := h.newUserConnection(ctx, conn, metadata)
+	conn.CloseWrapper()
+	if err != nil {
+		h.NewError(ctx, E.Cause(err, "process connection from ", metadata.Source))
+	}
+}
+
+func httpProxyAuthorization(request *http.Request) (username string, password string, loaded bool) {
+	const prefix = "Basic "
+	authorization := request.Header.Get("Proxy-Authorization")
+	if len(authorization) < len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return
+	}
+	userPassword, err := base64.StdEncoding.DecodeString(authorization[len(prefix):])
+	if err != nil {
+		return
+	}
+	return strings.Cut(string(userPassword), ":")
+}
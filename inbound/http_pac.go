@@ -0,0 +1,97 @@
+package inbound
+
+import (
+	std_bufio "bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/pac"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+)
+
+type pacServer struct {
+	path         string
+	proxyAddress string
+	tls          bool
+}
+
+func newPACServer(options *option.HTTPPACOptions, tls bool) *pacServer {
+	if options == nil || !options.Enabled {
+		return nil
+	}
+	path := options.Path
+	if path == "" {
+		path = pac.DefaultPath
+	} else if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return &pacServer{
+		path:         path,
+		proxyAddress: options.ProxyAddress,
+		tls:          tls,
+	}
+}
+
+func (a *myInboundAdapter) isPACRequest(reader *std_bufio.Reader) bool {
+	if a.pac == nil {
+		return false
+	}
+	prefix, err := reader.Peek(5)
+	return err == nil && string(prefix) == "GET /"
+}
+
+func (a *myInboundAdapter) servePAC(ctx context.Context, conn net.Conn, reader *std_bufio.Reader, metadata adapter.InboundContext) error {
+	request, err := http.ReadRequest(reader)
+	if err != nil {
+		return E.Cause(err, "read PAC request")
+	}
+	response := &http.Response{
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Close:      true,
+	}
+	content, loaded := a.generatePAC(request, metadata)
+	if loaded {
+		a.logger.InfoContext(ctx, "serve PAC file to ", metadata.Source)
+		response.StatusCode = http.StatusOK
+		response.Header.Set("Content-Type", pac.ContentType)
+		response.Header.Set("Cache-Control", "no-cache")
+	} else {
+		response.StatusCode = http.StatusNotFound
+		content = http.StatusText(http.StatusNotFound) + "\n"
+		response.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	response.ContentLength = int64(len(content))
+	response.Body = io.NopCloser(strings.NewReader(content))
+	err = response.Write(conn)
+	conn.Close()
+	return err
+}
+
+func (a *myInboundAdapter) generatePAC(request *http.Request, metadata adapter.InboundContext) (string, bool) {
+	if request.Method != http.MethodGet || (request.URL.Path != a.pac.path && request.URL.Path != pac.WPADPath) {
+		return "", false
+	}
+	proxyAddress := a.pac.proxyAddress
+	if proxyAddress == "" {
+		proxyAddress = request.Host
+	}
+	if proxyAddress == "" {
+		proxyAddress = metadata.OriginDestination.String()
+	}
+	proxyType := "PROXY "
+	if a.pac.tls {
+		proxyType = "HTTPS "
+	}
+	metadata.Inbound = a.tag
+	metadata.InboundType = a.protocol
+	metadata.Network = N.NetworkTCP
+	return pac.Generate(a.router, &metadata, proxyType+proxyAddress), true
+}
@@ -0,0 +1,46 @@
+//go:build with_quic
+
+package inbound
+
+import (
+	"io"
+
+	"github.com/sagernet/quic-go/http3"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing/common/auth"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func (a *myInboundAdapter) listenHTTP3(tlsConfig tls.ServerConfig, authenticator auth.Authenticator) (io.Closer, error) {
+	stdConfig, err := tlsConfig.Config()
+	if err != nil {
+		return nil, err
+	}
+	h3Server := &http3.Server{
+		Port:      int(a.listenOptions.ListenPort),
+		TLSConfig: stdConfig,
+		Handler: &httpConnectHandler{
+			myInboundAdapter: a,
+			authenticator:    authenticator,
+			metadata: adapter.InboundContext{
+				Inbound:        a.tag,
+				InboundType:    a.protocol,
+				InboundDetour:  a.listenOptions.Detour,
+				InboundOptions: a.listenOptions.InboundOptions,
+			},
+		},
+	}
+	udpConn, err := a.ListenUDP()
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		sErr := h3Server.Serve(udpConn)
+		udpConn.Close()
+		if sErr != nil && !E.IsClosedOrCanceled(sErr) {
+			a.logger.Error("http3 server serve error: ", sErr)
+		}
+	}()
+	return h3Server, nil
+}
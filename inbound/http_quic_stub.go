@@ -0,0 +1,15 @@
+//go:build !with_quic
+
+package inbound
+
+import (
+	"io"
+
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common/auth"
+)
+
+func (a *myInboundAdapter) listenHTTP3(tlsConfig tls.ServerConfig, authenticator auth.Authenticator) (io.Closer, error) {
+	return nil, C.ErrQUICNotIncluded
+}
@@ -4,11 +4,14 @@ package inbound
 
 import (
 	"context"
+	"crypto/x509"
+	"net"
 	"sync"
 
 	"github.com/sagernet/quic-go"
 	"github.com/sagernet/quic-go/congestion"
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/access"
 	"github.com/sagernet/sing-box/common/tls"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -24,7 +27,10 @@ import (
 	"golang.org/x/exp/slices"
 )
 
-var _ adapter.Inbound = (*Hysteria)(nil)
+var (
+	_ adapter.Inbound    = (*Hysteria)(nil)
+	_ adapter.TLSInbound = (*Hysteria)(nil)
+)
 
 type Hysteria struct {
 	myInboundAdapter
@@ -141,7 +147,9 @@ func (h *Hysteria) Start() error {
 	}
 	if len(h.xplusKey) > 0 {
 		packetConn = hysteria.NewXPlusPacketConn(packetConn, h.xplusKey)
-		packetConn = &hysteria.PacketConnWrapper{PacketConn: packetConn}
+		if _, isUDPConn := common.Cast[*net.UDPConn](packetConn); isUDPConn {
+			packetConn = &hysteria.PacketConnWrapper{PacketConn: packetConn}
+		}
 	}
 	err = h.tlsConfig.Start()
 	if err != nil {
@@ -168,10 +176,25 @@ func (h *Hysteria) acceptLoop() {
 		if err != nil {
 			return
 		}
+		source := M.SocksaddrFromNet(conn.RemoteAddr()).Unwrap()
+		if h.access != nil {
+			err = h.access.AcceptConnection(source.Addr)
+			if err != nil {
+				h.logger.Debug("rejected connection from ", source.Addr, ": ", err)
+				conn.CloseWithError(0, "")
+				continue
+			}
+		}
 		go func() {
+			if h.access != nil {
+				defer h.access.ReleaseConnection(source.Addr)
+			}
 			hErr := h.accept(ctx, conn)
 			if hErr != nil {
 				conn.CloseWithError(0, "")
+				if h.access != nil {
+					h.access.ConnectionFailed(source.Addr, hErr)
+				}
 				NewError(h.logger, ctx, E.Cause(hErr, "process connection from ", conn.RemoteAddr()))
 			}
 		}()
@@ -193,7 +216,7 @@ func (h *Hysteria) accept(ctx context.Context, conn quic.Connection) error {
 			err = hysteria.WriteServerHello(controlStream, hysteria.ServerHello{
 				Message: "wrong password",
 			})
-			return E.Errors(E.New("wrong password: ", string(clientHello.Auth)), err)
+			return E.Errors(access.NewAuthError(E.New("wrong password: ", string(clientHello.Auth))), err)
 		}
 		user := h.authUser[userIndex]
 		if user == "" {
@@ -202,6 +225,9 @@ func (h *Hysteria) accept(ctx context.Context, conn quic.Connection) error {
 			ctx = auth.ContextWithUser(ctx, user)
 		}
 		h.logger.InfoContext(ctx, "[", user, "] inbound connection from ", conn.RemoteAddr())
+	} else if user := tls.ClientCertificateUser(conn.ConnectionState().TLS.VerifiedChains); user != "" {
+		ctx = auth.ContextWithUser(ctx, user)
+		h.logger.InfoContext(ctx, "[", user, "] inbound connection from ", conn.RemoteAddr())
 	} else {
 		h.logger.InfoContext(ctx, "inbound connection from ", conn.RemoteAddr())
 	}
@@ -283,6 +309,7 @@ func (h *Hysteria) acceptStream(ctx context.Context, conn quic.Connection, strea
 	metadata.Source = M.SocksaddrFromNet(conn.RemoteAddr()).Unwrap()
 	metadata.OriginDestination = M.SocksaddrFromNet(conn.LocalAddr()).Unwrap()
 	metadata.Destination = M.ParseSocksaddrHostPort(request.Host, request.Port).Unwrap()
+	metadata.User, _ = auth.UserFromContext[string](ctx)
 
 	if !request.UDP {
 		err = hysteria.WriteServerResponse(stream, hysteria.ServerResponse{
@@ -336,3 +363,7 @@ func (h *Hysteria) Close() error {
 		h.tlsConfig,
 	)
 }
+
+func (h *Hysteria) Certificates() []*x509.Certificate {
+	return tls.ServerCertificates(h.tlsConfig)
+}
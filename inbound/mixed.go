@@ -3,37 +3,47 @@ package inbound
 import (
 	std_bufio "bufio"
 	"context"
+	"crypto/x509"
+	"io"
 	"net"
 	"os"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tls"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/auth"
 	"github.com/sagernet/sing/common/buf"
 	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/common/rw"
-	"github.com/sagernet/sing/protocol/http"
 	"github.com/sagernet/sing/protocol/socks"
 	"github.com/sagernet/sing/protocol/socks/socks4"
 	"github.com/sagernet/sing/protocol/socks/socks5"
+
+	"golang.org/x/net/http2"
 )
 
 var (
 	_ adapter.Inbound           = (*Mixed)(nil)
 	_ adapter.InjectableInbound = (*Mixed)(nil)
+	_ adapter.TLSInbound        = (*Mixed)(nil)
 )
 
 type Mixed struct {
 	myInboundAdapter
 	authenticator auth.Authenticator
+	tlsConfig     tls.ServerConfig
+	http3         bool
+	h3Server      io.Closer
 }
 
-func NewMixed(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.HTTPMixedInboundOptions) *Mixed {
+func NewMixed(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.HTTPMixedInboundOptions) (*Mixed, error) {
 	inbound := &Mixed{
-		myInboundAdapter{
+		myInboundAdapter: myInboundAdapter{
 			protocol:       C.TypeMixed,
 			network:        []string{N.NetworkTCP},
 			ctx:            ctx,
@@ -43,23 +53,78 @@ func NewMixed(ctx context.Context, router adapter.Router, logger log.ContextLogg
 			listenOptions:  options.ListenOptions,
 			setSystemProxy: options.SetSystemProxy,
 		},
-		auth.NewAuthenticator(options.Users),
+		authenticator: auth.NewAuthenticator(options.Users),
+		http3:         options.HTTP3,
+	}
+	if options.TLS != nil {
+		tlsConfig, err := tls.NewServer(ctx, router, logger, common.PtrValueOrDefault(options.TLS))
+		if err != nil {
+			return nil, err
+		}
+		inbound.tlsConfig = tlsConfig
 	}
+	if options.HTTP3 && inbound.tlsConfig == nil {
+		return nil, E.New("TLS is required for HTTP/3 server")
+	}
+	inbound.pac = newPACServer(options.PAC, options.TLS != nil)
 	inbound.connHandler = inbound
-	return inbound
+	return inbound, nil
+}
+
+func (h *Mixed) Start() error {
+	if h.tlsConfig != nil {
+		err := h.tlsConfig.Start()
+		if err != nil {
+			return E.Cause(err, "create TLS config")
+		}
+	}
+	err := h.myInboundAdapter.Start()
+	if err != nil {
+		return err
+	}
+	if h.http3 {
+		h.h3Server, err = h.listenHTTP3(h.tlsConfig, h.authenticator)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Mixed) Close() error {
+	return common.Close(
+		&h.myInboundAdapter,
+		h.h3Server,
+		h.tlsConfig,
+	)
+}
+
+func (h *Mixed) Certificates() []*x509.Certificate {
+	return tls.ServerCertificates(h.tlsConfig)
 }
 
 func (h *Mixed) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	if h.tlsConfig != nil {
+		tlsConn, err := tls.ServerHandshake(ctx, conn, h.tlsConfig)
+		if err != nil {
+			return err
+		}
+		metadata.User = tls.ClientCertificateUser(tlsConn.ConnectionState().VerifiedChains)
+		if tlsConn.ConnectionState().NegotiatedProtocol == http2.NextProtoTLS {
+			return h.newHTTP2Connection(ctx, tlsConn, h.authenticator, metadata)
+		}
+		conn = tlsConn
+	}
 	headerType, err := rw.ReadByte(conn)
 	if err != nil {
 		return err
 	}
 	switch headerType {
 	case socks4.Version, socks5.Version:
-		return socks.HandleConnection0(ctx, conn, headerType, h.authenticator, h.upstreamUserHandler(metadata), adapter.UpstreamMetadata(metadata))
+		return socks.HandleConnection0(ctx, conn, headerType, h.accessAuthenticator(h.authenticator, metadata.Source), h.upstreamUserHandler(metadata), adapter.UpstreamMetadata(metadata))
 	}
 	reader := std_bufio.NewReader(bufio.NewCachedReader(conn, buf.As([]byte{headerType})))
-	return http.HandleConnection(ctx, conn, reader, h.authenticator, h.upstreamUserHandler(metadata), adapter.UpstreamMetadata(metadata))
+	return h.newHTTPConnection(ctx, conn, reader, h.accessAuthenticator(h.authenticator, metadata.Source), metadata)
 }
 
 func (h *Mixed) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
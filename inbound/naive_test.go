@@ -0,0 +1,93 @@
+package inbound
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/sagernet/sing-box/transport/naive"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readNaiveFrames decodes count padded frames as written by a naive client.
+func readNaiveFrames(t *testing.T, reader io.Reader, count int) [][]byte {
+	var frames [][]byte
+	for i := 0; i < count; i++ {
+		header := make([]byte, 3)
+		_, err := io.ReadFull(reader, header)
+		require.NoError(t, err)
+		payload := make([]byte, binary.BigEndian.Uint16(header))
+		_, err = io.ReadFull(reader, payload)
+		require.NoError(t, err)
+		padding := make([]byte, header[2])
+		_, err = io.ReadFull(reader, padding)
+		require.NoError(t, err)
+		frames = append(frames, payload)
+	}
+	return frames
+}
+
+func naiveTestMessages() [][]byte {
+	var messages [][]byte
+	for i := 0; i < naive.FirstPaddings+2; i++ {
+		messages = append(messages, []byte("message "+strconv.Itoa(i)))
+	}
+	return messages
+}
+
+type testBufferConn struct {
+	net.Conn
+	output *bytes.Buffer
+}
+
+func (c *testBufferConn) Write(p []byte) (n int, err error) {
+	return c.output.Write(p)
+}
+
+func TestNaiveH1ConnWritePadding(t *testing.T) {
+	t.Parallel()
+	output := &bytes.Buffer{}
+	conn := newNaiveH1Conn(&testBufferConn{output: output})
+	messages := naiveTestMessages()
+	for i, message := range messages {
+		if i%2 == 0 {
+			_, err := conn.Write(message)
+			require.NoError(t, err)
+		} else {
+			buffer := buf.NewPacket()
+			buffer.Resize(conn.FrontHeadroom(), 0)
+			common.Must1(buffer.Write(message))
+			require.NoError(t, conn.WriteBuffer(buffer))
+		}
+	}
+	frames := readNaiveFrames(t, output, naive.FirstPaddings)
+	require.Equal(t, messages[:naive.FirstPaddings], frames)
+	require.Equal(t, bytes.Join(messages[naive.FirstPaddings:], nil), output.Bytes())
+}
+
+type testFlusher struct {
+	io.Writer
+}
+
+func (f *testFlusher) Flush() {
+}
+
+func TestNaiveH2ConnWritePadding(t *testing.T) {
+	t.Parallel()
+	output := &bytes.Buffer{}
+	conn := newNaiveH2Conn(nil, output, &testFlusher{output})
+	messages := naiveTestMessages()
+	for _, message := range messages {
+		_, err := conn.Write(message)
+		require.NoError(t, err)
+	}
+	frames := readNaiveFrames(t, output, naive.FirstPaddings)
+	require.Equal(t, messages[:naive.FirstPaddings], frames)
+	require.Equal(t, bytes.Join(messages[naive.FirstPaddings:], nil), output.Bytes())
+}
@@ -16,11 +16,13 @@ import (
 
 type Redirect struct {
 	myInboundAdapter
+	autoConfigure bool
+	clearFirewall func() error
 }
 
 func NewRedirect(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.RedirectInboundOptions) *Redirect {
 	redirect := &Redirect{
-		myInboundAdapter{
+		myInboundAdapter: myInboundAdapter{
 			protocol:      C.TypeRedirect,
 			network:       []string{N.NetworkTCP},
 			ctx:           ctx,
@@ -29,11 +31,41 @@ func NewRedirect(ctx context.Context, router adapter.Router, logger log.ContextL
 			tag:           tag,
 			listenOptions: options.ListenOptions,
 		},
+		autoConfigure: options.AutoConfigure,
 	}
 	redirect.connHandler = redirect
 	return redirect
 }
 
+func (r *Redirect) Start() error {
+	err := r.myInboundAdapter.Start()
+	if err != nil {
+		return err
+	}
+	if r.autoConfigure {
+		listenAddr := M.SocksaddrFromNet(r.tcpListener.Addr())
+		r.clearFirewall, err = redir.ConfigureFirewall(redir.FirewallOptions{
+			Mode:        C.TypeRedirect,
+			Address:     listenAddr.Addr,
+			Port:        listenAddr.Port,
+			Network:     r.network,
+			DefaultMark: r.router.DefaultMark(),
+		})
+		if err != nil {
+			return E.Cause(err, "configure firewall")
+		}
+	}
+	return nil
+}
+
+func (r *Redirect) Close() error {
+	var err error
+	if r.clearFirewall != nil {
+		err = r.clearFirewall()
+	}
+	return E.Errors(err, r.myInboundAdapter.Close())
+}
+
 func (r *Redirect) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
 	destination, err := redir.GetOriginalDestination(conn)
 	if err != nil {
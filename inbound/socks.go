@@ -42,7 +42,7 @@ func NewSocks(ctx context.Context, router adapter.Router, logger log.ContextLogg
 }
 
 func (h *Socks) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
-	return socks.HandleConnection(ctx, conn, h.authenticator, h.upstreamUserHandler(metadata), adapter.UpstreamMetadata(metadata))
+	return socks.HandleConnection(ctx, conn, h.accessAuthenticator(h.authenticator, metadata.Source), h.upstreamUserHandler(metadata), adapter.UpstreamMetadata(metadata))
 }
 
 func (h *Socks) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
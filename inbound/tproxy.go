@@ -22,7 +22,11 @@ import (
 
 type TProxy struct {
 	myInboundAdapter
-	udpNat *udpnat.Service[netip.AddrPort]
+	udpNat             *udpnat.Service[netip.AddrPort]
+	autoConfigure      bool
+	autoConfigureMark  uint32
+	autoConfigureTable int
+	clearFirewall      func() error
 }
 
 func NewTProxy(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.TProxyInboundOptions) *TProxy {
@@ -36,6 +40,15 @@ func NewTProxy(ctx context.Context, router adapter.Router, logger log.ContextLog
 			tag:           tag,
 			listenOptions: options.ListenOptions,
 		},
+		autoConfigure:      options.AutoConfigure,
+		autoConfigureMark:  options.AutoConfigureMark,
+		autoConfigureTable: options.AutoConfigureTableIndex,
+	}
+	if tproxy.autoConfigureMark == 0 {
+		tproxy.autoConfigureMark = C.DefaultTProxyMark
+	}
+	if tproxy.autoConfigureTable == 0 {
+		tproxy.autoConfigureTable = C.DefaultTProxyTableIndex
 	}
 	var udpTimeout int64
 	if options.UDPTimeout != 0 {
@@ -51,6 +64,9 @@ func NewTProxy(ctx context.Context, router adapter.Router, logger log.ContextLog
 }
 
 func (t *TProxy) Start() error {
+	if len(t.listenOptions.ListenPorts) > 0 {
+		return E.New("listen_ports is not supported for tproxy inbound")
+	}
 	err := t.myInboundAdapter.Start()
 	if err != nil {
 		return err
@@ -64,16 +80,44 @@ func (t *TProxy) Start() error {
 		}
 	}
 	if t.udpConn != nil {
-		err = control.Conn(t.udpConn, func(fd uintptr) error {
+		err = control.Conn(common.MustCast[syscall.Conn](t.udpConn), func(fd uintptr) error {
 			return redir.TProxy(fd, M.SocksaddrFromNet(t.udpConn.LocalAddr()).Addr.Is6())
 		})
 		if err != nil {
 			return E.Cause(err, "configure tproxy UDP listener")
 		}
 	}
+	if t.autoConfigure {
+		var listenAddr M.Socksaddr
+		if t.tcpListener != nil {
+			listenAddr = M.SocksaddrFromNet(t.tcpListener.Addr())
+		} else {
+			listenAddr = M.SocksaddrFromNet(t.udpConn.LocalAddr())
+		}
+		t.clearFirewall, err = redir.ConfigureFirewall(redir.FirewallOptions{
+			Mode:        C.TypeTProxy,
+			Address:     listenAddr.Addr,
+			Port:        listenAddr.Port,
+			Network:     t.network,
+			DefaultMark: t.router.DefaultMark(),
+			TProxyMark:  t.autoConfigureMark,
+			TableIndex:  t.autoConfigureTable,
+		})
+		if err != nil {
+			return E.Cause(err, "configure firewall")
+		}
+	}
 	return nil
 }
 
+func (t *TProxy) Close() error {
+	var err error
+	if t.clearFirewall != nil {
+		err = t.clearFirewall()
+	}
+	return E.Errors(err, t.myInboundAdapter.Close())
+}
+
 func (t *TProxy) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
 	metadata.Destination = M.SocksaddrFromNet(conn.LocalAddr()).Unwrap()
 	return t.newConnection(ctx, conn, metadata)
@@ -2,6 +2,7 @@ package inbound
 
 import (
 	"context"
+	"crypto/x509"
 	"net"
 	"os"
 
@@ -23,6 +24,7 @@ import (
 var (
 	_ adapter.Inbound           = (*Trojan)(nil)
 	_ adapter.InjectableInbound = (*Trojan)(nil)
+	_ adapter.TLSInbound        = (*Trojan)(nil)
 )
 
 type Trojan struct {
@@ -144,20 +146,25 @@ func (h *Trojan) Close() error {
 	)
 }
 
+func (h *Trojan) Certificates() []*x509.Certificate {
+	return tls.ServerCertificates(h.tlsConfig)
+}
+
 func (h *Trojan) newTransportConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
 	h.injectTCP(conn, metadata)
 	return nil
 }
 
 func (h *Trojan) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
-	var err error
 	if h.tlsConfig != nil && h.transport == nil {
-		conn, err = tls.ServerHandshake(ctx, conn, h.tlsConfig)
+		tlsConn, err := tls.ServerHandshake(ctx, conn, h.tlsConfig)
 		if err != nil {
 			return err
 		}
+		metadata.User = tls.ClientCertificateUser(tlsConn.ConnectionState().VerifiedChains)
+		conn = tlsConn
 	}
-	return h.service.NewConnection(adapter.WithContext(ctx, &metadata), conn, adapter.UpstreamMetadata(metadata))
+	return wrapAuthError(h.service.NewConnection(adapter.WithContext(ctx, &metadata), conn, adapter.UpstreamMetadata(metadata)), trojan.ErrBadRequest)
 }
 
 func (h *Trojan) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
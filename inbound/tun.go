@@ -5,8 +5,10 @@ import (
 	"net"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/fswatch"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/experimental/libbox/platform"
 	"github.com/sagernet/sing-box/log"
@@ -17,6 +19,8 @@ import (
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/common/ranges"
+
+	"golang.org/x/exp/slices"
 )
 
 var _ adapter.Inbound = (*Tun)(nil)
@@ -35,6 +39,12 @@ type Tun struct {
 	tunStack               tun.Stack
 	platformInterface      platform.Interface
 	platformOptions        option.TunPlatformOptions
+	routeOptions           tunRouteOptions
+	routeAccess            sync.Mutex
+	routeWatcher           *fswatch.Watcher
+	autoRedirect           bool
+	redirect               *Redirect
+	clearFirewall          func() error
 }
 
 func NewTun(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.TunInboundOptions, platformInterface platform.Interface) (*Tun, error) {
@@ -64,6 +74,30 @@ func NewTun(ctx context.Context, router adapter.Router, logger log.ContextLogger
 			return nil, E.Cause(err, "parse exclude_uid_range")
 		}
 	}
+	if options.AutoRedirect {
+		if !options.AutoRoute {
+			return nil, E.New("auto_redirect requires auto_route")
+		}
+		if !C.IsLinux || platformInterface != nil {
+			return nil, E.New("auto_redirect is only supported on Linux")
+		}
+		if len(options.IncludeAndroidUser) > 0 || len(options.IncludePackage) > 0 || len(options.ExcludePackage) > 0 {
+			return nil, E.New("include_android_user, include_package and exclude_package are unavailable with auto_redirect")
+		}
+	}
+	routeOptions := newTunRouteOptions(options)
+	inet4RouteAddress := routeOptions.inet4RouteAddress
+	inet6RouteAddress := routeOptions.inet6RouteAddress
+	if routeOptions.IsDynamic() {
+		if !options.AutoRoute {
+			return nil, E.New("route_exclude_address and route address paths require auto_route")
+		}
+		var err error
+		inet4RouteAddress, inet6RouteAddress, err = routeOptions.Build(len(options.Inet4Address) > 0, len(options.Inet6Address) > 0)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &Tun{
 		tag:            tag,
 		ctx:            ctx,
@@ -77,8 +111,8 @@ func NewTun(ctx context.Context, router adapter.Router, logger log.ContextLogger
 			Inet6Address:       common.Map(options.Inet6Address, option.ListenPrefix.Build),
 			AutoRoute:          options.AutoRoute,
 			StrictRoute:        options.StrictRoute,
-			Inet4RouteAddress:  common.Map(options.Inet4RouteAddress, option.ListenPrefix.Build),
-			Inet6RouteAddress:  common.Map(options.Inet6RouteAddress, option.ListenPrefix.Build),
+			Inet4RouteAddress:  inet4RouteAddress,
+			Inet6RouteAddress:  inet6RouteAddress,
 			IncludeUID:         includeUID,
 			ExcludeUID:         excludeUID,
 			IncludeAndroidUser: options.IncludeAndroidUser,
@@ -92,6 +126,8 @@ func NewTun(ctx context.Context, router adapter.Router, logger log.ContextLogger
 		stack:                  options.Stack,
 		platformInterface:      platformInterface,
 		platformOptions:        common.PtrValueOrDefault(options.Platform),
+		routeOptions:           routeOptions,
+		autoRedirect:           options.AutoRedirect,
 	}, nil
 }
 
@@ -180,15 +216,69 @@ func (t *Tun) Start() error {
 	if err != nil {
 		return err
 	}
+	if t.autoRedirect {
+		t.logger.Trace("configuring auto redirect")
+		err = t.startRedirect()
+		if err != nil {
+			return err
+		}
+	}
+	t.routeWatcher, err = fswatch.New(t.logger, t.routeOptions.Paths(), t.reloadRoutes)
+	if err != nil {
+		return E.Cause(err, "watch route address files")
+	}
 	t.logger.Info("started at ", t.tunOptions.Name)
 	return nil
 }
 
+func (t *Tun) reloadRoutes() {
+	t.routeAccess.Lock()
+	defer t.routeAccess.Unlock()
+	inet4RouteAddress, inet6RouteAddress, err := t.routeOptions.Build(len(t.tunOptions.Inet4Address) > 0, len(t.tunOptions.Inet6Address) > 0)
+	if err != nil {
+		t.logger.Error(E.Cause(err, "reload route address"))
+		return
+	}
+	if slices.Equal(inet4RouteAddress, t.tunOptions.Inet4RouteAddress) && slices.Equal(inet6RouteAddress, t.tunOptions.Inet6RouteAddress) {
+		return
+	}
+	if t.platformInterface != nil {
+		t.logger.Warn("route address changed, restart to apply")
+		return
+	}
+	inet4Applied, inet6Applied, err := updateTunRoutes(&t.tunOptions, inet4RouteAddress, inet6RouteAddress)
+	if slices.Equal(inet4Applied, t.tunOptions.Inet4RouteAddress) && slices.Equal(inet6Applied, t.tunOptions.Inet6RouteAddress) {
+		if err != nil {
+			t.logger.Error(E.Cause(err, "update routes"))
+		}
+		return
+	}
+	t.tunOptions.Inet4RouteAddress = inet4Applied
+	t.tunOptions.Inet6RouteAddress = inet6Applied
+	if err != nil {
+		t.logger.Error(E.Cause(err, "partially update routes"))
+	}
+	if t.redirect != nil {
+		err = t.configureRedirect()
+		if err != nil {
+			t.logger.Error(err)
+			return
+		}
+	}
+	t.logger.Info("updated routes: ", len(inet4Applied), " IPv4 and ", len(inet6Applied), " IPv6 prefixes")
+}
+
 func (t *Tun) Close() error {
-	return common.Close(
+	var err error
+	if t.clearFirewall != nil {
+		err = t.clearFirewall()
+	}
+	return E.Errors(err, common.Close(
+		common.PtrOrNil(t.routeWatcher),
+		common.PtrOrNil(t.redirect),
 		t.tunStack,
 		t.tunIf,
-	)
+	))
 }
 
 func (t *Tun) NewConnection(ctx context.Context, conn net.Conn, upstreamMetadata M.Metadata) error {
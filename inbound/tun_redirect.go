@@ -0,0 +1,73 @@
+package inbound
+
+import (
+	"net/netip"
+
+	"github.com/sagernet/sing-box/common/redir"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+func (t *Tun) startRedirect() error {
+	// forwarded connections are redirected to the address of the incoming interface,
+	// so listen on all addresses and let the firewall drop connections not redirected by it
+	listenAddress := netip.IPv6Unspecified()
+	if len(t.tunOptions.Inet6Address) == 0 {
+		listenAddress = netip.IPv4Unspecified()
+	}
+	t.redirect = &Redirect{
+		myInboundAdapter: myInboundAdapter{
+			protocol: C.TypeTun,
+			network:  []string{N.NetworkTCP},
+			ctx:      t.ctx,
+			router:   t.router,
+			logger:   t.logger,
+			tag:      t.tag,
+			listenOptions: option.ListenOptions{
+				Listen:         option.NewListenAddress(listenAddress),
+				InboundOptions: t.inboundOptions,
+			},
+		},
+	}
+	t.redirect.connHandler = t.redirect
+	err := t.redirect.Start()
+	if err != nil {
+		return E.Cause(err, "start redirect server")
+	}
+	return t.configureRedirect()
+}
+
+func (t *Tun) configureRedirect() error {
+	var routeAddress []netip.Prefix
+	if len(t.tunOptions.Inet4Address) > 0 {
+		if len(t.tunOptions.Inet4RouteAddress) > 0 {
+			routeAddress = append(routeAddress, t.tunOptions.Inet4RouteAddress...)
+		} else {
+			routeAddress = append(routeAddress, netip.PrefixFrom(netip.IPv4Unspecified(), 0))
+		}
+	}
+	if len(t.tunOptions.Inet6Address) > 0 {
+		if len(t.tunOptions.Inet6RouteAddress) > 0 {
+			routeAddress = append(routeAddress, t.tunOptions.Inet6RouteAddress...)
+		} else {
+			routeAddress = append(routeAddress, netip.PrefixFrom(netip.IPv6Unspecified(), 0))
+		}
+	}
+	clearFirewall, err := redir.ConfigureFirewall(redir.FirewallOptions{
+		Mode:         C.TypeTun,
+		Name:         "sing-box-tun-" + t.tunOptions.Name,
+		Port:         M.SocksaddrFromNet(t.redirect.tcpListener.Addr()).Port,
+		DefaultMark:  t.router.DefaultMark(),
+		RouteAddress: routeAddress,
+		IncludeUID:   t.tunOptions.IncludeUID,
+		ExcludeUID:   t.tunOptions.ExcludeUID,
+	})
+	if err != nil {
+		return E.Cause(err, "configure auto redirect")
+	}
+	t.clearFirewall = clearFirewall
+	return nil
+}
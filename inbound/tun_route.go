@@ -0,0 +1,137 @@
+package inbound
+
+import (
+	"bufio"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"go4.org/netipx"
+)
+
+type tunRouteOptions struct {
+	inet4RouteAddress       []netip.Prefix
+	inet6RouteAddress       []netip.Prefix
+	routeExcludeAddress     []netip.Prefix
+	routeAddressPath        []string
+	routeExcludeAddressPath []string
+}
+
+func newTunRouteOptions(options option.TunInboundOptions) tunRouteOptions {
+	return tunRouteOptions{
+		inet4RouteAddress:       common.Map(options.Inet4RouteAddress, option.ListenPrefix.Build),
+		inet6RouteAddress:       common.Map(options.Inet6RouteAddress, option.ListenPrefix.Build),
+		routeExcludeAddress:     common.Map(options.RouteExcludeAddress, option.ListenPrefix.Build),
+		routeAddressPath:        options.RouteAddressPath,
+		routeExcludeAddressPath: options.RouteExcludeAddressPath,
+	}
+}
+
+func (o *tunRouteOptions) IsDynamic() bool {
+	return len(o.routeExcludeAddress) > 0 || len(o.routeAddressPath) > 0 || len(o.routeExcludeAddressPath) > 0
+}
+
+func (o *tunRouteOptions) Paths() []string {
+	return append(append([]string(nil), o.routeAddressPath...), o.routeExcludeAddressPath...)
+}
+
+func (o *tunRouteOptions) Build(inet4 bool, inet6 bool) (inet4Routes []netip.Prefix, inet6Routes []netip.Prefix, err error) {
+	inet4RouteAddress := append([]netip.Prefix(nil), o.inet4RouteAddress...)
+	inet6RouteAddress := append([]netip.Prefix(nil), o.inet6RouteAddress...)
+	routeExcludeAddress := append([]netip.Prefix(nil), o.routeExcludeAddress...)
+	for _, path := range o.routeAddressPath {
+		prefixes, err := readPrefixFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, prefix := range prefixes {
+			if prefix.Addr().Is4() {
+				inet4RouteAddress = append(inet4RouteAddress, prefix)
+			} else {
+				inet6RouteAddress = append(inet6RouteAddress, prefix)
+			}
+		}
+	}
+	for _, path := range o.routeExcludeAddressPath {
+		prefixes, err := readPrefixFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		routeExcludeAddress = append(routeExcludeAddress, prefixes...)
+	}
+	if inet4 {
+		inet4Routes, err = buildRoutes(inet4RouteAddress, routeExcludeAddress, netip.PrefixFrom(netip.IPv4Unspecified(), 0))
+		if err != nil {
+			return nil, nil, E.Cause(err, "build IPv4 routes")
+		}
+	}
+	if inet6 {
+		inet6Routes, err = buildRoutes(inet6RouteAddress, routeExcludeAddress, netip.PrefixFrom(netip.IPv6Unspecified(), 0))
+		if err != nil {
+			return nil, nil, E.Cause(err, "build IPv6 routes")
+		}
+	}
+	return
+}
+
+func buildRoutes(routeAddress []netip.Prefix, routeExcludeAddress []netip.Prefix, defaultRoute netip.Prefix) ([]netip.Prefix, error) {
+	if len(routeAddress) == 0 {
+		routeAddress = []netip.Prefix{defaultRoute}
+	}
+	var builder netipx.IPSetBuilder
+	for _, prefix := range routeAddress {
+		builder.AddPrefix(prefix)
+	}
+	for _, prefix := range routeExcludeAddress {
+		builder.RemovePrefix(prefix)
+	}
+	ipSet, err := builder.IPSet()
+	if err != nil {
+		return nil, err
+	}
+	routes := ipSet.Prefixes()
+	if len(routes) == 0 {
+		return nil, E.New("all routes are excluded")
+	}
+	return routes, nil
+}
+
+func readPrefixFile(path string) ([]netip.Prefix, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var prefixes []netip.Prefix
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "{") || strings.ContainsRune(line, 0) {
+			return nil, E.New("parse ", path, ": rule-set and database files are not supported, expected one address or prefix per line")
+		}
+		var prefix netip.Prefix
+		if strings.Contains(line, "/") {
+			prefix, err = netip.ParsePrefix(line)
+		} else {
+			var address netip.Addr
+			address, err = netip.ParseAddr(line)
+			prefix = netip.PrefixFrom(address, address.BitLen())
+		}
+		if err != nil {
+			return nil, E.Cause(err, "parse ", path, ":", lineNumber)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	err = scanner.Err()
+	if err != nil {
+		return nil, E.Cause(err, "read ", path)
+	}
+	return prefixes, nil
+}
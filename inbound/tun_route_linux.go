@@ -0,0 +1,80 @@
+package inbound
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"sort"
+
+	"github.com/sagernet/netlink"
+	"github.com/sagernet/sing-tun"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/sys/unix"
+)
+
+// updateTunRoutes returns the routes actually installed, which differ from the
+// requested ones if some of the changes failed.
+func updateTunRoutes(options *tun.Options, inet4Routes []netip.Prefix, inet6Routes []netip.Prefix) ([]netip.Prefix, []netip.Prefix, error) {
+	tunLink, err := netlink.LinkByName(options.Name)
+	if err != nil {
+		return options.Inet4RouteAddress, options.Inet6RouteAddress, err
+	}
+	inet4Applied, inet4Errs := updateTunRouteFamily(tunLink, options.TableIndex, options.Inet4RouteAddress, inet4Routes)
+	inet6Applied, inet6Errs := updateTunRouteFamily(tunLink, options.TableIndex, options.Inet6RouteAddress, inet6Routes)
+	return inet4Applied, inet6Applied, E.Errors(append(inet4Errs, inet6Errs...)...)
+}
+
+func updateTunRouteFamily(tunLink netlink.Link, tableIndex int, oldRoutes []netip.Prefix, newRoutes []netip.Prefix) ([]netip.Prefix, []error) {
+	var (
+		applied []netip.Prefix
+		errs    []error
+	)
+	for _, prefix := range oldRoutes {
+		if containsPrefix(newRoutes, prefix) {
+			continue
+		}
+		err := netlink.RouteDel(tunRoute(tunLink, tableIndex, prefix))
+		if err != nil && !errors.Is(err, unix.ESRCH) {
+			errs = append(errs, E.Cause(err, "remove route ", prefix))
+			applied = append(applied, prefix)
+		}
+	}
+	for _, prefix := range newRoutes {
+		if !containsPrefix(oldRoutes, prefix) {
+			err := netlink.RouteReplace(tunRoute(tunLink, tableIndex, prefix))
+			if err != nil {
+				errs = append(errs, E.Cause(err, "add route ", prefix))
+				continue
+			}
+		}
+		applied = append(applied, prefix)
+	}
+	sort.Slice(applied, func(i, j int) bool {
+		if applied[i].Addr() != applied[j].Addr() {
+			return applied[i].Addr().Less(applied[j].Addr())
+		}
+		return applied[i].Bits() < applied[j].Bits()
+	})
+	return applied, errs
+}
+
+func tunRoute(tunLink netlink.Link, tableIndex int, prefix netip.Prefix) *netlink.Route {
+	return &netlink.Route{
+		Dst: &net.IPNet{
+			IP:   prefix.Addr().AsSlice(),
+			Mask: net.CIDRMask(prefix.Bits(), prefix.Addr().BitLen()),
+		},
+		LinkIndex: tunLink.Attrs().Index,
+		Table:     tableIndex,
+	}
+}
+
+func containsPrefix(prefixes []netip.Prefix, prefix netip.Prefix) bool {
+	for _, it := range prefixes {
+		if it == prefix {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package inbound
+
+import (
+	"net/netip"
+	"runtime"
+
+	"github.com/sagernet/sing-tun"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func updateTunRoutes(options *tun.Options, inet4Routes []netip.Prefix, inet6Routes []netip.Prefix) ([]netip.Prefix, []netip.Prefix, error) {
+	return options.Inet4RouteAddress, options.Inet6RouteAddress, E.New("updating routes of a running tun interface is not supported on ", runtime.GOOS)
+}
@@ -0,0 +1,128 @@
+package inbound
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sagernet/sing/common"
+
+	"github.com/stretchr/testify/require"
+)
+
+func parseTestPrefixes(prefixes ...string) []netip.Prefix {
+	return common.Map(prefixes, netip.MustParsePrefix)
+}
+
+func TestBuildRoutes(t *testing.T) {
+	t.Parallel()
+	inet4Default := netip.MustParsePrefix("0.0.0.0/0")
+	inet6Default := netip.MustParsePrefix("::/0")
+	for _, testCase := range []struct {
+		name         string
+		routeAddress []netip.Prefix
+		exclude      []netip.Prefix
+		defaultRoute netip.Prefix
+		expected     []netip.Prefix
+		error        bool
+	}{
+		{
+			name:         "default",
+			defaultRoute: inet4Default,
+			expected:     parseTestPrefixes("0.0.0.0/0"),
+		},
+		{
+			name:         "exclude from default",
+			exclude:      parseTestPrefixes("128.0.0.0/1"),
+			defaultRoute: inet4Default,
+			expected:     parseTestPrefixes("0.0.0.0/1"),
+		},
+		{
+			name:         "exclude from custom",
+			routeAddress: parseTestPrefixes("10.0.0.0/8"),
+			exclude:      parseTestPrefixes("10.0.0.0/9", "10.192.0.0/10"),
+			defaultRoute: inet4Default,
+			expected:     parseTestPrefixes("10.128.0.0/10"),
+		},
+		{
+			name:         "merge overlapping",
+			routeAddress: parseTestPrefixes("10.0.0.0/9", "10.128.0.0/9", "10.1.0.0/16"),
+			defaultRoute: inet4Default,
+			expected:     parseTestPrefixes("10.0.0.0/8"),
+		},
+		{
+			name:         "ignore other family",
+			routeAddress: parseTestPrefixes("2001:db8::/32"),
+			exclude:      parseTestPrefixes("192.168.0.0/16", "2001:db8:8000::/33"),
+			defaultRoute: inet6Default,
+			expected:     parseTestPrefixes("2001:db8::/33"),
+		},
+		{
+			name:         "all excluded",
+			routeAddress: parseTestPrefixes("192.168.1.0/24"),
+			exclude:      parseTestPrefixes("192.168.0.0/16"),
+			defaultRoute: inet4Default,
+			error:        true,
+		},
+	} {
+		routes, err := buildRoutes(testCase.routeAddress, testCase.exclude, testCase.defaultRoute)
+		if testCase.error {
+			require.Error(t, err, testCase.name)
+			continue
+		}
+		require.NoError(t, err, testCase.name)
+		require.Equal(t, testCase.expected, routes, testCase.name)
+	}
+}
+
+func writeTestPrefixFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "prefixes.txt")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+func TestReadPrefixFile(t *testing.T) {
+	t.Parallel()
+	prefixes, err := readPrefixFile(writeTestPrefixFile(t, "# office\n10.0.0.1\n\n  192.168.1.7/24  \n2001:db8::1\n2001:db8::1/32\n"))
+	require.NoError(t, err)
+	require.Equal(t, parseTestPrefixes("10.0.0.1/32", "192.168.1.0/24", "2001:db8::1/128", "2001:db8::/32"), prefixes)
+
+	prefixes, err = readPrefixFile(writeTestPrefixFile(t, ""))
+	require.NoError(t, err)
+	require.Empty(t, prefixes)
+
+	_, err = readPrefixFile(writeTestPrefixFile(t, "10.0.0.0/8\nexample.org\n"))
+	require.ErrorContains(t, err, ":2")
+	_, err = readPrefixFile(writeTestPrefixFile(t, "10.0.0.0/33\n"))
+	require.Error(t, err)
+	_, err = readPrefixFile(writeTestPrefixFile(t, "{\"version\": 1, \"rules\": []}\n"))
+	require.ErrorContains(t, err, "not supported")
+	_, err = readPrefixFile(writeTestPrefixFile(t, "SRS\x01\x00"))
+	require.ErrorContains(t, err, "not supported")
+	_, err = readPrefixFile(filepath.Join(t.TempDir(), "missing.txt"))
+	require.Error(t, err)
+}
+
+func TestTunRouteOptionsBuild(t *testing.T) {
+	t.Parallel()
+	options := tunRouteOptions{
+		inet4RouteAddress:       parseTestPrefixes("10.0.0.0/8"),
+		routeExcludeAddress:     parseTestPrefixes("10.0.0.0/9"),
+		routeAddressPath:        []string{writeTestPrefixFile(t, "172.16.0.0/12\n2001:db8::/32\n")},
+		routeExcludeAddressPath: []string{writeTestPrefixFile(t, "172.16.0.0/13\n2001:db8::/33\n")},
+	}
+	require.True(t, options.IsDynamic())
+	require.Equal(t, append(append([]string(nil), options.routeAddressPath...), options.routeExcludeAddressPath...), options.Paths())
+	inet4Routes, inet6Routes, err := options.Build(true, true)
+	require.NoError(t, err)
+	require.Equal(t, parseTestPrefixes("10.128.0.0/9", "172.24.0.0/13"), inet4Routes)
+	require.Equal(t, parseTestPrefixes("2001:db8:8000::/33"), inet6Routes)
+
+	inet4Routes, inet6Routes, err = options.Build(true, false)
+	require.NoError(t, err)
+	require.NotEmpty(t, inet4Routes)
+	require.Empty(t, inet6Routes)
+
+	require.False(t, (&tunRouteOptions{inet4RouteAddress: parseTestPrefixes("10.0.0.0/8")}).IsDynamic())
+}
@@ -2,6 +2,7 @@ package inbound
 
 import (
 	"context"
+	"crypto/x509"
 	"net"
 	"os"
 
@@ -25,6 +26,7 @@ import (
 var (
 	_ adapter.Inbound           = (*VLESS)(nil)
 	_ adapter.InjectableInbound = (*VLESS)(nil)
+	_ adapter.TLSInbound        = (*VLESS)(nil)
 )
 
 type VLESS struct {
@@ -123,20 +125,25 @@ func (h *VLESS) Close() error {
 	)
 }
 
+func (h *VLESS) Certificates() []*x509.Certificate {
+	return tls.ServerCertificates(h.tlsConfig)
+}
+
 func (h *VLESS) newTransportConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
 	h.injectTCP(conn, metadata)
 	return nil
 }
 
 func (h *VLESS) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
-	var err error
 	if h.tlsConfig != nil && h.transport == nil {
-		conn, err = tls.ServerHandshake(ctx, conn, h.tlsConfig)
+		tlsConn, err := tls.ServerHandshake(ctx, conn, h.tlsConfig)
 		if err != nil {
 			return err
 		}
+		metadata.User = tls.ClientCertificateUser(tlsConn.ConnectionState().VerifiedChains)
+		conn = tlsConn
 	}
-	return h.service.NewConnection(adapter.WithContext(log.ContextWithNewID(ctx), &metadata), conn, adapter.UpstreamMetadata(metadata))
+	return wrapAuthError(h.service.NewConnection(adapter.WithContext(log.ContextWithNewID(ctx), &metadata), conn, adapter.UpstreamMetadata(metadata)), vless.ErrUnknownUUID)
 }
 
 func (h *VLESS) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
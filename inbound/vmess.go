@@ -2,6 +2,7 @@ package inbound
 
 import (
 	"context"
+	"crypto/x509"
 	"net"
 	"os"
 
@@ -24,6 +25,7 @@ import (
 var (
 	_ adapter.Inbound           = (*VMess)(nil)
 	_ adapter.InjectableInbound = (*VMess)(nil)
+	_ adapter.TLSInbound        = (*VMess)(nil)
 )
 
 type VMess struct {
@@ -131,20 +133,25 @@ func (h *VMess) Close() error {
 	)
 }
 
+func (h *VMess) Certificates() []*x509.Certificate {
+	return tls.ServerCertificates(h.tlsConfig)
+}
+
 func (h *VMess) newTransportConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
 	h.injectTCP(conn, metadata)
 	return nil
 }
 
 func (h *VMess) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
-	var err error
 	if h.tlsConfig != nil && h.transport == nil {
-		conn, err = tls.ServerHandshake(ctx, conn, h.tlsConfig)
+		tlsConn, err := tls.ServerHandshake(ctx, conn, h.tlsConfig)
 		if err != nil {
 			return err
 		}
+		metadata.User = tls.ClientCertificateUser(tlsConn.ConnectionState().VerifiedChains)
+		conn = tlsConn
 	}
-	return h.service.NewConnection(adapter.WithContext(log.ContextWithNewID(ctx), &metadata), conn, adapter.UpstreamMetadata(metadata))
+	return wrapAuthError(h.service.NewConnection(adapter.WithContext(log.ContextWithNewID(ctx), &metadata), conn, adapter.UpstreamMetadata(metadata)), vmess.ErrBadRequest)
 }
 
 func (h *VMess) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
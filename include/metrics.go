@@ -0,0 +1,5 @@
+//go:build with_metrics
+
+package include
+
+import _ "github.com/sagernet/sing-box/experimental/metrics"
@@ -0,0 +1,19 @@
+//go:build !with_metrics
+
+package include
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/experimental"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func init() {
+	experimental.RegisterMetricsServerConstructor(func(ctx context.Context, router adapter.Router, logger log.Logger, options option.MetricsOptions) (adapter.MetricsServer, error) {
+		return nil, E.New(`metrics is not included in this build, rebuild with -tags with_metrics`)
+	})
+}
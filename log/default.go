@@ -17,10 +17,12 @@ type simpleFactory struct {
 	platformFormatter Formatter
 	writer            io.Writer
 	platformWriter    io.Writer
+	outputs           []Output
 	level             Level
+	tagLevels         map[string]Level
 }
 
-func NewFactory(formatter Formatter, writer io.Writer, platformWriter io.Writer) Factory {
+func NewFactory(formatter Formatter, writer io.Writer, platformWriter io.Writer, outputs ...Output) Factory {
 	return &simpleFactory{
 		formatter: formatter,
 		platformFormatter: Formatter{
@@ -29,6 +31,7 @@ func NewFactory(formatter Formatter, writer io.Writer, platformWriter io.Writer)
 		},
 		writer:         writer,
 		platformWriter: platformWriter,
+		outputs:        outputs,
 		level:          LevelTrace,
 	}
 }
@@ -41,12 +44,18 @@ func (f *simpleFactory) SetLevel(level Level) {
 	f.level = level
 }
 
+func (f *simpleFactory) SetTagLevels(levels map[string]Level) {
+	f.tagLevels = levels
+}
+
 func (f *simpleFactory) Logger() ContextLogger {
 	return f.NewLogger("")
 }
 
 func (f *simpleFactory) NewLogger(tag string) ContextLogger {
-	return &simpleLogger{f, tag}
+	logger := &simpleLogger{simpleFactory: f, tag: tag}
+	logger.tagLevel, logger.tagLevelLoaded = tagLevel(f.tagLevels, tag)
+	return logger
 }
 
 func (f *simpleFactory) Close() error {
@@ -57,15 +66,29 @@ var _ ContextLogger = (*simpleLogger)(nil)
 
 type simpleLogger struct {
 	*simpleFactory
-	tag string
+	tag            string
+	tagLevel       Level
+	tagLevelLoaded bool
 }
 
 func (l *simpleLogger) Log(ctx context.Context, level Level, args []any) {
 	level = OverrideLevelFromContext(level, ctx)
-	if level > l.level {
+	enabled := level <= l.maxLevel(l.level)
+	if !enabled && !l.outputEnabled(level) {
 		return
 	}
 	nowTime := time.Now()
+	if len(l.outputs) > 0 {
+		rawMessage := F.ToString(args...)
+		for _, output := range l.outputs {
+			if level <= output.maxLevel(l.maxLevel(l.level)) {
+				output.write(ctx, level, l.tag, rawMessage, nowTime)
+			}
+		}
+	}
+	if !enabled {
+		return
+	}
 	message := l.formatter.Format(ctx, level, l.tag, F.ToString(args...), nowTime)
 	if level == LevelPanic {
 		panic(message)
@@ -79,6 +102,22 @@ func (l *simpleLogger) Log(ctx context.Context, level Level, args []any) {
 	}
 }
 
+func (l *simpleLogger) maxLevel(defaultLevel Level) Level {
+	if l.tagLevelLoaded {
+		return l.tagLevel
+	}
+	return defaultLevel
+}
+
+func (l *simpleLogger) outputEnabled(level Level) bool {
+	for _, output := range l.outputs {
+		if level <= output.maxLevel(l.maxLevel(l.level)) {
+			return true
+		}
+	}
+	return false
+}
+
 func (l *simpleLogger) Trace(args ...any) {
 	l.TraceContext(context.Background(), args...)
 }
@@ -13,6 +13,7 @@ type (
 type Factory interface {
 	Level() Level
 	SetLevel(level Level)
+	SetTagLevels(levels map[string]Level)
 	Logger() ContextLogger
 	NewLogger(tag string) ContextLogger
 	Close() error
@@ -0,0 +1,29 @@
+package log
+
+import (
+	"context"
+)
+
+type Field struct {
+	Key   string
+	Value string
+}
+
+type fieldsKey struct{}
+
+// ContextWithFields attaches structured fields to log entries written with the context.
+func ContextWithFields(ctx context.Context, fields ...Field) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	origin := FieldsFromContext(ctx)
+	newFields := make([]Field, 0, len(origin)+len(fields))
+	newFields = append(newFields, origin...)
+	newFields = append(newFields, fields...)
+	return context.WithValue(ctx, (*fieldsKey)(nil), newFields)
+}
+
+func FieldsFromContext(ctx context.Context) []Field {
+	fields, _ := ctx.Value((*fieldsKey)(nil)).([]Field)
+	return fields
+}
@@ -17,9 +17,13 @@ type Formatter struct {
 	DisableTimestamp bool
 	FullTimestamp    bool
 	TimestampFormat  string
+	JSON             bool
 }
 
 func (f Formatter) Format(ctx context.Context, level Level, tag string, message string, timestamp time.Time) string {
+	if f.JSON {
+		return f.formatJSON(ctx, level, tag, message, timestamp)
+	}
 	levelString := strings.ToUpper(FormatLevel(level))
 	if !f.DisableColors {
 		switch level {
@@ -83,6 +87,12 @@ func (f Formatter) Format(ctx context.Context, level Level, tag string, message
 }
 
 func (f Formatter) FormatWithSimple(ctx context.Context, level Level, tag string, message string, timestamp time.Time) (string, string) {
+	if f.JSON {
+		textFormatter := f
+		textFormatter.JSON = false
+		_, messageSimple := textFormatter.FormatWithSimple(ctx, level, tag, message, timestamp)
+		return f.formatJSON(ctx, level, tag, message, timestamp), messageSimple
+	}
 	levelString := strings.ToUpper(FormatLevel(level))
 	if !f.DisableColors {
 		switch level {
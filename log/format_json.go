@@ -0,0 +1,48 @@
+package log
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/common/json"
+)
+
+type jsonEntry struct {
+	Time       string            `json:"time"`
+	Level      string            `json:"level"`
+	Tag        string            `json:"tag,omitempty"`
+	ID         *uint32           `json:"id,omitempty"`
+	DurationMS *int64            `json:"duration_ms,omitempty"`
+	Fields     map[string]string `json:"fields,omitempty"`
+	Message    string            `json:"message"`
+}
+
+func (f Formatter) formatJSON(ctx context.Context, level Level, tag string, message string, timestamp time.Time) string {
+	entry := jsonEntry{
+		Time:    timestamp.Format(time.RFC3339Nano),
+		Level:   FormatLevel(level),
+		Tag:     tag,
+		Message: strings.TrimSuffix(message, "\n"),
+	}
+	if ctx != nil {
+		if id, hasId := IDFromContext(ctx); hasId {
+			duration := time.Since(id.CreatedAt).Milliseconds()
+			entry.ID = &id.ID
+			entry.DurationMS = &duration
+		}
+		// structured fields are nested to keep them apart from the keys above
+		if fields := FieldsFromContext(ctx); len(fields) > 0 {
+			entry.Fields = make(map[string]string, len(fields))
+			for _, field := range fields {
+				entry.Fields[field.Key] = field.Value
+			}
+		}
+	}
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(entry)
+	return buffer.String()
+}
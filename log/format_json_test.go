@@ -0,0 +1,68 @@
+package log
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/common/json"
+
+	"github.com/stretchr/testify/require"
+)
+
+func decodeJSONEntry(t *testing.T, content string) map[string]any {
+	require.True(t, strings.HasSuffix(content, "}\n"), content)
+	require.Equal(t, 1, strings.Count(content, "\n"), content)
+	var entry map[string]any
+	require.NoError(t, json.Unmarshal([]byte(content), &entry), content)
+	return entry
+}
+
+func TestFormatJSON(t *testing.T) {
+	t.Parallel()
+	formatter := Formatter{JSON: true}
+	timestamp := time.Date(2022, 7, 1, 8, 0, 0, 5, time.UTC)
+
+	entry := decodeJSONEntry(t, formatter.Format(context.Background(), LevelInfo, "", "started\n", timestamp))
+	require.Equal(t, map[string]any{
+		"time":    "2022-07-01T08:00:00.000000005Z",
+		"level":   "info",
+		"message": "started",
+	}, entry)
+
+	message := "quote \" backslash \\ newline \n tab \t control \x01 html <a&b> unicode 你好 invalid \xff"
+	entry = decodeJSONEntry(t, formatter.Format(nil, LevelError, "dns", message, timestamp))
+	require.Equal(t, "error", entry["level"])
+	require.Equal(t, "dns", entry["tag"])
+	require.Equal(t, strings.ToValidUTF8(message, "�"), entry["message"])
+
+	ctx := ContextWithNewID(context.Background())
+	id, _ := IDFromContext(ctx)
+	ctx = ContextWithFields(ctx,
+		Field{Key: "inbound", Value: "mixed-in"},
+		Field{Key: "message", Value: "field"},
+		Field{Key: "level", Value: "field"},
+	)
+	content := formatter.Format(ctx, LevelDebug, "inbound/mixed[mixed-in]", "inbound connection", timestamp)
+	entry = decodeJSONEntry(t, content)
+	require.Equal(t, float64(id.ID), entry["id"])
+	require.Contains(t, entry, "duration_ms")
+	// fields named like the reserved keys do not replace them
+	require.Equal(t, "debug", entry["level"])
+	require.Equal(t, "inbound connection", entry["message"])
+	require.Equal(t, map[string]any{
+		"inbound": "mixed-in",
+		"message": "field",
+		"level":   "field",
+	}, entry["fields"])
+	require.True(t, strings.HasSuffix(content, `"message":"inbound connection"}`+"\n"), content)
+}
+
+func TestFormatJSONWithSimple(t *testing.T) {
+	t.Parallel()
+	formatter := Formatter{JSON: true, DisableColors: true, DisableTimestamp: true}
+	message, messageSimple := formatter.FormatWithSimple(context.Background(), LevelWarn, "router", "no route", time.Now())
+	require.Equal(t, "no route", decodeJSONEntry(t, message)["message"])
+	require.Equal(t, "router: no route", strings.TrimSuffix(messageSimple, "\n"))
+}
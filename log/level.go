@@ -1,6 +1,8 @@
 package log
 
 import (
+	"strings"
+
 	E "github.com/sagernet/sing/common/exceptions"
 )
 
@@ -57,3 +59,23 @@ func ParseLevel(level string) (Level, error) {
 		return LevelTrace, E.New("unknown log level: ", level)
 	}
 }
+
+// tagLevel returns the level set for the tag or its nearest parent,
+// e.g. `inbound` or `inbound/mixed` for `inbound/mixed[mixed-in]`.
+func tagLevel(levels map[string]Level, tag string) (Level, bool) {
+	var (
+		level    Level
+		matchLen = -1
+	)
+	for key, value := range levels {
+		if len(key) <= matchLen || !strings.HasPrefix(tag, key) {
+			continue
+		}
+		if len(tag) > len(key) && tag[len(key)] != '/' && tag[len(key)] != '[' {
+			continue
+		}
+		level = value
+		matchLen = len(key)
+	}
+	return level, matchLen >= 0
+}
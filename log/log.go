@@ -6,34 +6,33 @@ import (
 	"os"
 	"time"
 
+	"github.com/sagernet/sing-box/common/rotate"
+	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
-	"github.com/sagernet/sing/service/filemanager"
 )
 
-type factoryWithFile struct {
+type factoryWithOutputs struct {
 	Factory
-	file *os.File
+	closers []io.Closer
 }
 
-func (f *factoryWithFile) Close() error {
-	return common.Close(
-		f.Factory,
-		common.PtrOrNil(f.file),
-	)
+func (f *factoryWithOutputs) Close() error {
+	return common.Close(append([]any{f.Factory}, common.Map(f.closers, func(it io.Closer) any {
+		return it
+	})...)...)
 }
 
-type observableFactoryWithFile struct {
+type observableFactoryWithOutputs struct {
 	ObservableFactory
-	file *os.File
+	closers []io.Closer
 }
 
-func (f *observableFactoryWithFile) Close() error {
-	return common.Close(
-		f.ObservableFactory,
-		common.PtrOrNil(f.file),
-	)
+func (f *observableFactoryWithOutputs) Close() error {
+	return common.Close(append([]any{f.ObservableFactory}, common.Map(f.closers, func(it io.Closer) any {
+		return it
+	})...)...)
 }
 
 type Options struct {
@@ -45,46 +44,76 @@ type Options struct {
 	PlatformWriter io.Writer
 }
 
-func New(options Options) (Factory, error) {
+func New(options Options) (factory Factory, err error) {
 	logOptions := options.Options
 
 	if logOptions.Disabled {
 		return NewNOPFactory(), nil
 	}
 
-	var logFile *os.File
+	jsonFormat, err := parseFormat(logOptions.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	var closers []io.Closer
+	defer func() {
+		if err != nil {
+			common.Close(common.Map(closers, func(it io.Closer) any {
+				return it
+			})...)
+		}
+	}()
+
 	var logWriter io.Writer
+	var isFile bool
 
 	switch logOptions.Output {
 	case "":
+		if len(logOptions.Outputs) > 0 {
+			logWriter = io.Discard
+			break
+		}
 		logWriter = options.DefaultWriter
 		if logWriter == nil {
 			logWriter = os.Stderr
 		}
-	case "stderr":
+	case C.LogOutputStderr:
 		logWriter = os.Stderr
-	case "stdout":
+	case C.LogOutputStdout:
 		logWriter = os.Stdout
 	default:
-		var err error
-		logFile, err = filemanager.OpenFile(options.Context, logOptions.Output, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		logFile, err := rotate.NewWriter(options.Context, logOptions.Output, logOptions.RotateOptions)
 		if err != nil {
 			return nil, err
 		}
+		closers = append(closers, logFile)
 		logWriter = logFile
+		isFile = true
 	}
 	logFormatter := Formatter{
 		BaseTime:         options.BaseTime,
-		DisableColors:    logOptions.DisableColor || logFile != nil,
-		DisableTimestamp: !logOptions.Timestamp && logFile != nil,
+		DisableColors:    logOptions.DisableColor || isFile,
+		DisableTimestamp: !logOptions.Timestamp && isFile,
 		FullTimestamp:    logOptions.Timestamp,
 		TimestampFormat:  "-0700 2006-01-02 15:04:05",
+		JSON:             jsonFormat,
+	}
+	outputs := make([]Output, 0, len(logOptions.Outputs))
+	for i, outputOptions := range logOptions.Outputs {
+		output, closer, err := newOutput(options, outputOptions)
+		if err != nil {
+			return nil, E.Cause(err, "parse log output[", i, "]")
+		}
+		if closer != nil {
+			closers = append(closers, closer)
+		}
+		outputs = append(outputs, output)
 	}
-	var factory Factory
 	if options.Observable {
-		factory = NewObservableFactory(logFormatter, logWriter, options.PlatformWriter)
+		factory = NewObservableFactory(logFormatter, logWriter, options.PlatformWriter, outputs...)
 	} else {
-		factory = NewFactory(logFormatter, logWriter, options.PlatformWriter)
+		factory = NewFactory(logFormatter, logWriter, options.PlatformWriter, outputs...)
 	}
 	if logOptions.Level != "" {
 		logLevel, err := ParseLevel(logOptions.Level)
@@ -95,16 +124,27 @@ func New(options Options) (Factory, error) {
 	} else {
 		factory.SetLevel(LevelTrace)
 	}
-	if logFile != nil {
+	if len(logOptions.Levels) > 0 {
+		tagLevels := make(map[string]Level, len(logOptions.Levels))
+		for tag, level := range logOptions.Levels {
+			logLevel, err := ParseLevel(level)
+			if err != nil {
+				return nil, E.Cause(err, "parse log level for ", tag)
+			}
+			tagLevels[tag] = logLevel
+		}
+		factory.SetTagLevels(tagLevels)
+	}
+	if len(closers) > 0 {
 		if options.Observable {
-			factory = &observableFactoryWithFile{
+			factory = &observableFactoryWithOutputs{
 				ObservableFactory: factory.(ObservableFactory),
-				file:              logFile,
+				closers:           closers,
 			}
 		} else {
-			factory = &factoryWithFile{
+			factory = &factoryWithOutputs{
 				Factory: factory,
-				file:    logFile,
+				closers: closers,
 			}
 		}
 	}
@@ -22,6 +22,9 @@ func (f *nopFactory) Level() Level {
 func (f *nopFactory) SetLevel(level Level) {
 }
 
+func (f *nopFactory) SetTagLevels(levels map[string]Level) {
+}
+
 func (f *nopFactory) Logger() ContextLogger {
 	return f
 }
@@ -19,12 +19,14 @@ type observableFactory struct {
 	platformFormatter Formatter
 	writer            io.Writer
 	platformWriter    io.Writer
+	outputs           []Output
 	level             Level
+	tagLevels         map[string]Level
 	subscriber        *observable.Subscriber[Entry]
 	observer          *observable.Observer[Entry]
 }
 
-func NewObservableFactory(formatter Formatter, writer io.Writer, platformWriter io.Writer) ObservableFactory {
+func NewObservableFactory(formatter Formatter, writer io.Writer, platformWriter io.Writer, outputs ...Output) ObservableFactory {
 	factory := &observableFactory{
 		formatter: formatter,
 		platformFormatter: Formatter{
@@ -33,6 +35,7 @@ func NewObservableFactory(formatter Formatter, writer io.Writer, platformWriter
 		},
 		writer:         writer,
 		platformWriter: platformWriter,
+		outputs:        outputs,
 		level:          LevelTrace,
 		subscriber:     observable.NewSubscriber[Entry](128),
 	}
@@ -48,12 +51,18 @@ func (f *observableFactory) SetLevel(level Level) {
 	f.level = level
 }
 
+func (f *observableFactory) SetTagLevels(levels map[string]Level) {
+	f.tagLevels = levels
+}
+
 func (f *observableFactory) Logger() ContextLogger {
 	return f.NewLogger("")
 }
 
 func (f *observableFactory) NewLogger(tag string) ContextLogger {
-	return &observableLogger{f, tag}
+	logger := &observableLogger{observableFactory: f, tag: tag}
+	logger.tagLevel, logger.tagLevelLoaded = tagLevel(f.tagLevels, tag)
+	return logger
 }
 
 func (f *observableFactory) Subscribe() (subscription observable.Subscription[Entry], done <-chan struct{}, err error) {
@@ -74,15 +83,29 @@ var _ ContextLogger = (*observableLogger)(nil)
 
 type observableLogger struct {
 	*observableFactory
-	tag string
+	tag            string
+	tagLevel       Level
+	tagLevelLoaded bool
 }
 
 func (l *observableLogger) Log(ctx context.Context, level Level, args []any) {
 	level = OverrideLevelFromContext(level, ctx)
-	if level > l.level {
+	enabled := level <= l.maxLevel(l.level)
+	if !enabled && !l.outputEnabled(level) {
 		return
 	}
 	nowTime := time.Now()
+	if len(l.outputs) > 0 {
+		rawMessage := F.ToString(args...)
+		for _, output := range l.outputs {
+			if level <= output.maxLevel(l.maxLevel(l.level)) {
+				output.write(ctx, level, l.tag, rawMessage, nowTime)
+			}
+		}
+	}
+	if !enabled {
+		return
+	}
 	message, messageSimple := l.formatter.FormatWithSimple(ctx, level, l.tag, F.ToString(args...), nowTime)
 	if level == LevelPanic {
 		panic(message)
@@ -97,6 +120,22 @@ func (l *observableLogger) Log(ctx context.Context, level Level, args []any) {
 	}
 }
 
+func (l *observableLogger) maxLevel(defaultLevel Level) Level {
+	if l.tagLevelLoaded {
+		return l.tagLevel
+	}
+	return defaultLevel
+}
+
+func (l *observableLogger) outputEnabled(level Level) bool {
+	for _, output := range l.outputs {
+		if level <= output.maxLevel(l.maxLevel(l.level)) {
+			return true
+		}
+	}
+	return false
+}
+
 func (l *observableLogger) Trace(args ...any) {
 	l.TraceContext(context.Background(), args...)
 }
@@ -0,0 +1,109 @@
+package log
+
+import (
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"github.com/sagernet/sing-box/common/rotate"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// Output is an additional log destination with its own formatter and level.
+// The logger level is used if HasLevel is not set.
+type Output struct {
+	Formatter Formatter
+	Writer    io.Writer
+	Level     Level
+	HasLevel  bool
+}
+
+type levelWriter interface {
+	WriteLevel(level Level, message string) error
+}
+
+// maxLevel caps the level of the logger with the level of the output.
+func (o Output) maxLevel(loggerLevel Level) Level {
+	if o.HasLevel && o.Level < loggerLevel {
+		return o.Level
+	}
+	return loggerLevel
+}
+
+func (o Output) write(ctx context.Context, level Level, tag string, message string, timestamp time.Time) {
+	content := o.Formatter.Format(ctx, level, tag, message, timestamp)
+	if writer, isLevelWriter := o.Writer.(levelWriter); isLevelWriter {
+		writer.WriteLevel(level, content)
+	} else {
+		o.Writer.Write([]byte(content))
+	}
+}
+
+func newOutput(options Options, outputOptions option.LogOutputOptions) (Output, io.Closer, error) {
+	var output Output
+	if outputOptions.Level != "" {
+		level, err := ParseLevel(outputOptions.Level)
+		if err != nil {
+			return Output{}, nil, E.Cause(err, "parse log level")
+		}
+		output.Level = level
+		output.HasLevel = true
+	}
+	jsonFormat, err := parseFormat(outputOptions.Format)
+	if err != nil {
+		return Output{}, nil, err
+	}
+	output.Formatter = Formatter{
+		BaseTime:        options.BaseTime,
+		DisableColors:   true,
+		FullTimestamp:   outputOptions.Timestamp,
+		TimestampFormat: "-0700 2006-01-02 15:04:05",
+		JSON:            jsonFormat,
+	}
+	var closer io.Closer
+	switch outputOptions.Type {
+	case C.LogOutputStderr:
+		output.Writer = os.Stderr
+		output.Formatter.DisableColors = options.Options.DisableColor
+	case C.LogOutputStdout:
+		output.Writer = os.Stdout
+		output.Formatter.DisableColors = options.Options.DisableColor
+	case C.LogOutputFile:
+		if outputOptions.Path == "" {
+			return Output{}, nil, E.New("missing path")
+		}
+		writer, err := rotate.NewWriter(options.Context, outputOptions.Path, outputOptions.RotateOptions)
+		if err != nil {
+			return Output{}, nil, err
+		}
+		output.Writer = writer
+		output.Formatter.DisableTimestamp = !outputOptions.Timestamp
+		closer = writer
+	case C.LogOutputSyslog:
+		writer, err := newSyslogWriter(outputOptions.Path)
+		if err != nil {
+			return Output{}, nil, E.Cause(err, "connect to syslog")
+		}
+		output.Writer = writer
+		output.Formatter.DisableTimestamp = true
+		output.Formatter.FullTimestamp = false
+		closer = writer
+	default:
+		return Output{}, nil, E.New("unknown log output type: ", outputOptions.Type)
+	}
+	return output, closer, nil
+}
+
+func parseFormat(format string) (bool, error) {
+	switch format {
+	case "", C.LogFormatText:
+		return false, nil
+	case C.LogFormatJSON:
+		return true, nil
+	default:
+		return false, E.New("unknown log format: ", format)
+	}
+}
@@ -0,0 +1,59 @@
+package log
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputLevel(t *testing.T) {
+	t.Parallel()
+	for name, newFactory := range map[string]func(formatter Formatter, writer io.Writer, platformWriter io.Writer, outputs ...Output) Factory{
+		"simple": NewFactory,
+		"observable": func(formatter Formatter, writer io.Writer, platformWriter io.Writer, outputs ...Output) Factory {
+			return NewObservableFactory(formatter, writer, platformWriter, outputs...)
+		},
+	} {
+		formatter := Formatter{DisableColors: true, DisableTimestamp: true}
+		var console, warnOutput, defaultOutput bytes.Buffer
+		factory := newFactory(formatter, &console, nil,
+			Output{Formatter: formatter, Writer: &warnOutput, Level: LevelWarn, HasLevel: true},
+			Output{Formatter: formatter, Writer: &defaultOutput},
+		)
+		factory.SetLevel(LevelInfo)
+		factory.SetTagLevels(map[string]Level{"dns": LevelDebug, "router": LevelError})
+
+		dnsLogger := factory.NewLogger("dns")
+		dnsLogger.Debug("dns debug")
+		dnsLogger.Warn("dns warn")
+		routerLogger := factory.NewLogger("router")
+		routerLogger.Warn("router warn")
+		routerLogger.Error("router error")
+		inboundLogger := factory.NewLogger("inbound/mixed[mixed-in]")
+		inboundLogger.Debug("inbound debug")
+		inboundLogger.Info("inbound info")
+		inboundLogger.Warn("inbound warn")
+
+		// the tag levels apply to outputs without a level
+		require.Equal(t, []string{"dns debug", "dns warn", "router error", "inbound info", "inbound warn"}, logMessages(console.String()), name)
+		require.Equal(t, logMessages(console.String()), logMessages(defaultOutput.String()), name)
+		// and never enable messages below the output level
+		require.Equal(t, []string{"dns warn", "router error", "inbound warn"}, logMessages(warnOutput.String()), name)
+		factory.Close()
+	}
+}
+
+func logMessages(content string) []string {
+	var messages []string
+	for _, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		_, message, _ := strings.Cut(line, ": ")
+		messages = append(messages, message)
+	}
+	return messages
+}
@@ -0,0 +1,49 @@
+//go:build !windows
+
+package log
+
+import (
+	"log/syslog"
+	"strings"
+)
+
+type syslogWriter struct {
+	*syslog.Writer
+}
+
+func newSyslogWriter(address string) (*syslogWriter, error) {
+	const priority = syslog.LOG_INFO | syslog.LOG_DAEMON
+	if address == "" {
+		writer, err := syslog.New(priority, "sing-box")
+		if err != nil {
+			return nil, err
+		}
+		return &syslogWriter{writer}, nil
+	}
+	writer, err := syslog.Dial("unixgram", address, priority, "sing-box")
+	if err != nil {
+		writer, err = syslog.Dial("unix", address, priority, "sing-box")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &syslogWriter{writer}, nil
+}
+
+func (w *syslogWriter) WriteLevel(level Level, message string) error {
+	message = strings.TrimSuffix(message, "\n")
+	switch level {
+	case LevelTrace, LevelDebug:
+		return w.Debug(message)
+	case LevelInfo:
+		return w.Info(message)
+	case LevelWarn:
+		return w.Warning(message)
+	case LevelError:
+		return w.Err(message)
+	case LevelFatal:
+		return w.Crit(message)
+	default:
+		return w.Emerg(message)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package log
+
+import (
+	"io"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func newSyslogWriter(address string) (io.WriteCloser, error) {
+	return nil, E.New("syslog is not supported on windows")
+}
@@ -0,0 +1,7 @@
+package option
+
+type AccessLogOptions struct {
+	Path   string `json:"path"`
+	Format string `json:"format,omitempty"`
+	RotateOptions
+}
@@ -9,6 +9,9 @@ type ClashAPIOptions struct {
 	DefaultMode              string `json:"default_mode,omitempty"`
 	StoreSelected            bool   `json:"store_selected,omitempty"`
 	StoreFakeIP              bool   `json:"store_fakeip,omitempty"`
+	StoreQuota               bool   `json:"store_quota,omitempty"`
+	StoreConnectionHistory   bool   `json:"store_connection_history,omitempty"`
+	ConnectionHistory        int    `json:"connection_history,omitempty"`
 	CacheFile                string `json:"cache_file,omitempty"`
 	CacheID                  string `json:"cache_id,omitempty"`
 }
@@ -38,9 +38,22 @@ func (o *Options) UnmarshalJSON(content []byte) error {
 }
 
 type LogOptions struct {
-	Disabled     bool   `json:"disabled,omitempty"`
-	Level        string `json:"level,omitempty"`
-	Output       string `json:"output,omitempty"`
-	Timestamp    bool   `json:"timestamp,omitempty"`
-	DisableColor bool   `json:"-"`
+	Disabled     bool               `json:"disabled,omitempty"`
+	Level        string             `json:"level,omitempty"`
+	Levels       map[string]string  `json:"levels,omitempty"`
+	Format       string             `json:"format,omitempty"`
+	Output       string             `json:"output,omitempty"`
+	Timestamp    bool               `json:"timestamp,omitempty"`
+	DisableColor bool               `json:"-"`
+	Outputs      []LogOutputOptions `json:"outputs,omitempty"`
+	RotateOptions
+}
+
+type LogOutputOptions struct {
+	Type      string `json:"type"`
+	Level     string `json:"level,omitempty"`
+	Format    string `json:"format,omitempty"`
+	Timestamp bool   `json:"timestamp,omitempty"`
+	Path      string `json:"path,omitempty"`
+	RotateOptions
 }
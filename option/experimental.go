@@ -3,5 +3,6 @@ package option
 type ExperimentalOptions struct {
 	ClashAPI *ClashAPIOptions `json:"clash_api,omitempty"`
 	V2RayAPI *V2RayAPIOptions `json:"v2ray_api,omitempty"`
+	Metrics  *MetricsOptions  `json:"metrics,omitempty"`
 	Debug    *DebugOptions    `json:"debug,omitempty"`
 }
@@ -24,6 +24,8 @@ type HysteriaUser struct {
 type HysteriaOutboundOptions struct {
 	DialerOptions
 	ServerOptions
+	ServerPorts         Listable[string]    `json:"server_ports,omitempty"`
+	HopInterval         Duration            `json:"hop_interval,omitempty"`
 	Up                  string              `json:"up,omitempty"`
 	UpMbps              int                 `json:"up_mbps,omitempty"`
 	Down                string              `json:"down,omitempty"`
@@ -117,14 +117,32 @@ type InboundOptions struct {
 }
 
 type ListenOptions struct {
-	Listen                      *ListenAddress `json:"listen,omitempty"`
-	ListenPort                  uint16         `json:"listen_port,omitempty"`
-	TCPFastOpen                 bool           `json:"tcp_fast_open,omitempty"`
-	UDPFragment                 *bool          `json:"udp_fragment,omitempty"`
-	UDPFragmentDefault          bool           `json:"-"`
-	UDPTimeout                  int64          `json:"udp_timeout,omitempty"`
-	ProxyProtocol               bool           `json:"proxy_protocol,omitempty"`
-	ProxyProtocolAcceptNoHeader bool           `json:"proxy_protocol_accept_no_header,omitempty"`
-	Detour                      string         `json:"detour,omitempty"`
+	Listen                      *ListenAddress        `json:"listen,omitempty"`
+	ListenPort                  uint16                `json:"listen_port,omitempty"`
+	ListenPorts                 Listable[string]      `json:"listen_ports,omitempty"`
+	ListenUnix                  string                `json:"listen_unix,omitempty"`
+	ListenUnixMode              string                `json:"listen_unix_mode,omitempty"`
+	ListenUnixUser              string                `json:"listen_unix_user,omitempty"`
+	ListenUnixGroup             string                `json:"listen_unix_group,omitempty"`
+	TCPFastOpen                 bool                  `json:"tcp_fast_open,omitempty"`
+	UDPFragment                 *bool                 `json:"udp_fragment,omitempty"`
+	UDPFragmentDefault          bool                  `json:"-"`
+	UDPTimeout                  int64                 `json:"udp_timeout,omitempty"`
+	ProxyProtocol               bool                  `json:"proxy_protocol,omitempty"`
+	ProxyProtocolAcceptNoHeader bool                  `json:"proxy_protocol_accept_no_header,omitempty"`
+	Detour                      string                `json:"detour,omitempty"`
+	Access                      *InboundAccessOptions `json:"access,omitempty"`
 	InboundOptions
 }
+
+type InboundAccessOptions struct {
+	AllowAddress          Listable[ListenPrefix] `json:"allow_address,omitempty"`
+	DenyAddress           Listable[ListenPrefix] `json:"deny_address,omitempty"`
+	MaxConnectionsPerIP   int                    `json:"max_connections_per_ip,omitempty"`
+	MaxConnectionsPerUser int                    `json:"max_connections_per_user,omitempty"`
+	ConnectionRate        int                    `json:"connection_rate,omitempty"`
+	ConnectionBurst       int                    `json:"connection_burst,omitempty"`
+	AuthFailureLimit      int                    `json:"auth_failure_limit,omitempty"`
+	AuthFailureWindow     Duration               `json:"auth_failure_window,omitempty"`
+	BanDuration           Duration               `json:"ban_duration,omitempty"`
+}
@@ -13,6 +13,7 @@ type _Outbound struct {
 	DirectOptions       DirectOutboundOptions       `json:"-"`
 	SocksOptions        SocksOutboundOptions        `json:"-"`
 	HTTPOptions         HTTPOutboundOptions         `json:"-"`
+	NaiveOptions        NaiveOutboundOptions        `json:"-"`
 	ShadowsocksOptions  ShadowsocksOutboundOptions  `json:"-"`
 	VMessOptions        VMessOutboundOptions        `json:"-"`
 	TrojanOptions       TrojanOutboundOptions       `json:"-"`
@@ -40,6 +41,8 @@ func (h Outbound) MarshalJSON() ([]byte, error) {
 		v = h.SocksOptions
 	case C.TypeHTTP:
 		v = h.HTTPOptions
+	case C.TypeNaive:
+		v = h.NaiveOptions
 	case C.TypeShadowsocks:
 		v = h.ShadowsocksOptions
 	case C.TypeVMess:
@@ -85,6 +88,8 @@ func (h *Outbound) UnmarshalJSON(bytes []byte) error {
 		v = &h.SocksOptions
 	case C.TypeHTTP:
 		v = &h.HTTPOptions
+	case C.TypeNaive:
+		v = &h.NaiveOptions
 	case C.TypeShadowsocks:
 		v = &h.ShadowsocksOptions
 	case C.TypeVMess:
@@ -120,19 +125,31 @@ func (h *Outbound) UnmarshalJSON(bytes []byte) error {
 }
 
 type DialerOptions struct {
-	Detour             string         `json:"detour,omitempty"`
-	BindInterface      string         `json:"bind_interface,omitempty"`
-	Inet4BindAddress   *ListenAddress `json:"inet4_bind_address,omitempty"`
-	Inet6BindAddress   *ListenAddress `json:"inet6_bind_address,omitempty"`
-	ProtectPath        string         `json:"protect_path,omitempty"`
-	RoutingMark        int            `json:"routing_mark,omitempty"`
-	ReuseAddr          bool           `json:"reuse_addr,omitempty"`
-	ConnectTimeout     Duration       `json:"connect_timeout,omitempty"`
-	TCPFastOpen        bool           `json:"tcp_fast_open,omitempty"`
-	UDPFragment        *bool          `json:"udp_fragment,omitempty"`
-	UDPFragmentDefault bool           `json:"-"`
-	DomainStrategy     DomainStrategy `json:"domain_strategy,omitempty"`
-	FallbackDelay      Duration       `json:"fallback_delay,omitempty"`
+	Detour             string              `json:"detour,omitempty"`
+	UnixSocket         string              `json:"unix_socket,omitempty"`
+	BindInterface      string              `json:"bind_interface,omitempty"`
+	Inet4BindAddress   *ListenAddress      `json:"inet4_bind_address,omitempty"`
+	Inet6BindAddress   *ListenAddress      `json:"inet6_bind_address,omitempty"`
+	ProtectPath        string              `json:"protect_path,omitempty"`
+	RoutingMark        int                 `json:"routing_mark,omitempty"`
+	ReuseAddr          bool                `json:"reuse_addr,omitempty"`
+	ConnectTimeout     Duration            `json:"connect_timeout,omitempty"`
+	TCPFastOpen        bool                `json:"tcp_fast_open,omitempty"`
+	UDPFragment        *bool               `json:"udp_fragment,omitempty"`
+	UDPFragmentDefault bool                `json:"-"`
+	DomainStrategy     DomainStrategy      `json:"domain_strategy,omitempty"`
+	FallbackDelay      Duration            `json:"fallback_delay,omitempty"`
+	TLSFragment        *TLSFragmentOptions `json:"tls_fragment,omitempty"`
+	ProxyProtocol      uint8               `json:"proxy_protocol,omitempty"`
+}
+
+type TLSFragmentOptions struct {
+	Enabled bool     `json:"enabled,omitempty"`
+	Record  bool     `json:"record,omitempty"`
+	Segment bool     `json:"segment,omitempty"`
+	MinSize int      `json:"min_size,omitempty"`
+	MaxSize int      `json:"max_size,omitempty"`
+	Delay   Duration `json:"delay,omitempty"`
 }
 
 type ServerOptions struct {
@@ -0,0 +1,11 @@
+package option
+
+type QuotaOptions struct {
+	Users        Listable[string] `json:"users,omitempty"`
+	Inbounds     Listable[string] `json:"inbounds,omitempty"`
+	Limit        BytesLength      `json:"limit"`
+	Period       string           `json:"period,omitempty"`
+	ResetDay     int              `json:"reset_day,omitempty"`
+	Action       string           `json:"action,omitempty"`
+	ThrottleRate BytesLength      `json:"throttle_rate,omitempty"`
+}
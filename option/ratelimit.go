@@ -0,0 +1,12 @@
+package option
+
+type RateLimitOptions struct {
+	Tag       string           `json:"tag,omitempty"`
+	Users     Listable[string] `json:"users,omitempty"`
+	Inbounds  Listable[string] `json:"inbounds,omitempty"`
+	Outbounds Listable[string] `json:"outbounds,omitempty"`
+	Up        string           `json:"up,omitempty"`
+	UpMbps    int              `json:"up_mbps,omitempty"`
+	Down      string           `json:"down,omitempty"`
+	DownMbps  int              `json:"down_mbps,omitempty"`
+}
@@ -2,9 +2,13 @@ package option
 
 type RedirectInboundOptions struct {
 	ListenOptions
+	AutoConfigure bool `json:"auto_configure,omitempty"`
 }
 
 type TProxyInboundOptions struct {
 	ListenOptions
-	Network NetworkList `json:"network,omitempty"`
+	Network                 NetworkList `json:"network,omitempty"`
+	AutoConfigure           bool        `json:"auto_configure,omitempty"`
+	AutoConfigureMark       uint32      `json:"auto_configure_mark,omitempty"`
+	AutoConfigureTableIndex int         `json:"auto_configure_table_index,omitempty"`
 }
@@ -0,0 +1,9 @@
+package option
+
+type RotateOptions struct {
+	MaxSize        BytesLength `json:"max_size,omitempty"`
+	RotateInterval Duration    `json:"rotate_interval,omitempty"`
+	MaxBackups     int         `json:"max_backups,omitempty"`
+	MaxAge         Duration    `json:"max_age,omitempty"`
+	Compress       bool        `json:"compress,omitempty"`
+}
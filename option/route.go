@@ -1,15 +1,18 @@
 package option
 
 type RouteOptions struct {
-	GeoIP               *GeoIPOptions   `json:"geoip,omitempty"`
-	Geosite             *GeositeOptions `json:"geosite,omitempty"`
-	Rules               []Rule          `json:"rules,omitempty"`
-	Final               string          `json:"final,omitempty"`
-	FindProcess         bool            `json:"find_process,omitempty"`
-	AutoDetectInterface bool            `json:"auto_detect_interface,omitempty"`
-	OverrideAndroidVPN  bool            `json:"override_android_vpn,omitempty"`
-	DefaultInterface    string          `json:"default_interface,omitempty"`
-	DefaultMark         int             `json:"default_mark,omitempty"`
+	GeoIP               *GeoIPOptions      `json:"geoip,omitempty"`
+	Geosite             *GeositeOptions    `json:"geosite,omitempty"`
+	Rules               []Rule             `json:"rules,omitempty"`
+	Final               string             `json:"final,omitempty"`
+	FindProcess         bool               `json:"find_process,omitempty"`
+	AutoDetectInterface bool               `json:"auto_detect_interface,omitempty"`
+	OverrideAndroidVPN  bool               `json:"override_android_vpn,omitempty"`
+	DefaultInterface    string             `json:"default_interface,omitempty"`
+	DefaultMark         int                `json:"default_mark,omitempty"`
+	Quotas              []QuotaOptions     `json:"quotas,omitempty"`
+	RateLimits          []RateLimitOptions `json:"rate_limits,omitempty"`
+	AccessLog           *AccessLogOptions  `json:"access_log,omitempty"`
 }
 
 type GeoIPOptions struct {
@@ -54,46 +54,53 @@ func (r *Rule) UnmarshalJSON(bytes []byte) error {
 }
 
 type DefaultRule struct {
-	Inbound         Listable[string] `json:"inbound,omitempty"`
-	IPVersion       int              `json:"ip_version,omitempty"`
-	Network         Listable[string] `json:"network,omitempty"`
-	AuthUser        Listable[string] `json:"auth_user,omitempty"`
-	Protocol        Listable[string] `json:"protocol,omitempty"`
-	Domain          Listable[string] `json:"domain,omitempty"`
-	DomainSuffix    Listable[string] `json:"domain_suffix,omitempty"`
-	DomainKeyword   Listable[string] `json:"domain_keyword,omitempty"`
-	DomainRegex     Listable[string] `json:"domain_regex,omitempty"`
-	Geosite         Listable[string] `json:"geosite,omitempty"`
-	SourceGeoIP     Listable[string] `json:"source_geoip,omitempty"`
-	GeoIP           Listable[string] `json:"geoip,omitempty"`
-	SourceIPCIDR    Listable[string] `json:"source_ip_cidr,omitempty"`
-	IPCIDR          Listable[string] `json:"ip_cidr,omitempty"`
-	SourcePort      Listable[uint16] `json:"source_port,omitempty"`
-	SourcePortRange Listable[string] `json:"source_port_range,omitempty"`
-	Port            Listable[uint16] `json:"port,omitempty"`
-	PortRange       Listable[string] `json:"port_range,omitempty"`
-	ProcessName     Listable[string] `json:"process_name,omitempty"`
-	ProcessPath     Listable[string] `json:"process_path,omitempty"`
-	PackageName     Listable[string] `json:"package_name,omitempty"`
-	User            Listable[string] `json:"user,omitempty"`
-	UserID          Listable[int32]  `json:"user_id,omitempty"`
-	ClashMode       string           `json:"clash_mode,omitempty"`
-	Invert          bool             `json:"invert,omitempty"`
-	Outbound        string           `json:"outbound,omitempty"`
+	Inbound                Listable[string] `json:"inbound,omitempty"`
+	IPVersion              int              `json:"ip_version,omitempty"`
+	Network                Listable[string] `json:"network,omitempty"`
+	AuthUser               Listable[string] `json:"auth_user,omitempty"`
+	ProxyProtocolAuthority Listable[string] `json:"proxy_protocol_authority,omitempty"`
+	ProxyProtocolUniqueID  Listable[string] `json:"proxy_protocol_unique_id,omitempty"`
+	ProxyProtocolSSL       bool             `json:"proxy_protocol_ssl,omitempty"`
+	ProxyProtocolSSLCN     Listable[string] `json:"proxy_protocol_ssl_cn,omitempty"`
+	Protocol               Listable[string] `json:"protocol,omitempty"`
+	Domain                 Listable[string] `json:"domain,omitempty"`
+	DomainSuffix           Listable[string] `json:"domain_suffix,omitempty"`
+	DomainKeyword          Listable[string] `json:"domain_keyword,omitempty"`
+	DomainRegex            Listable[string] `json:"domain_regex,omitempty"`
+	Geosite                Listable[string] `json:"geosite,omitempty"`
+	SourceGeoIP            Listable[string] `json:"source_geoip,omitempty"`
+	GeoIP                  Listable[string] `json:"geoip,omitempty"`
+	SourceIPCIDR           Listable[string] `json:"source_ip_cidr,omitempty"`
+	IPCIDR                 Listable[string] `json:"ip_cidr,omitempty"`
+	SourcePort             Listable[uint16] `json:"source_port,omitempty"`
+	SourcePortRange        Listable[string] `json:"source_port_range,omitempty"`
+	Port                   Listable[uint16] `json:"port,omitempty"`
+	PortRange              Listable[string] `json:"port_range,omitempty"`
+	ProcessName            Listable[string] `json:"process_name,omitempty"`
+	ProcessPath            Listable[string] `json:"process_path,omitempty"`
+	PackageName            Listable[string] `json:"package_name,omitempty"`
+	User                   Listable[string] `json:"user,omitempty"`
+	UserID                 Listable[int32]  `json:"user_id,omitempty"`
+	ClashMode              string           `json:"clash_mode,omitempty"`
+	Invert                 bool             `json:"invert,omitempty"`
+	Outbound               string           `json:"outbound,omitempty"`
+	RateLimit              string           `json:"rate_limit,omitempty"`
 }
 
 func (r DefaultRule) IsValid() bool {
 	var defaultValue DefaultRule
 	defaultValue.Invert = r.Invert
 	defaultValue.Outbound = r.Outbound
+	defaultValue.RateLimit = r.RateLimit
 	return !reflect.DeepEqual(r, defaultValue)
 }
 
 type LogicalRule struct {
-	Mode     string        `json:"mode"`
-	Rules    []DefaultRule `json:"rules,omitempty"`
-	Invert   bool          `json:"invert,omitempty"`
-	Outbound string        `json:"outbound,omitempty"`
+	Mode      string        `json:"mode"`
+	Rules     []DefaultRule `json:"rules,omitempty"`
+	Invert    bool          `json:"invert,omitempty"`
+	Outbound  string        `json:"outbound,omitempty"`
+	RateLimit string        `json:"rate_limit,omitempty"`
 }
 
 func (r LogicalRule) IsValid() bool {
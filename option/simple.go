@@ -12,6 +12,14 @@ type HTTPMixedInboundOptions struct {
 	Users          []auth.User        `json:"users,omitempty"`
 	SetSystemProxy bool               `json:"set_system_proxy,omitempty"`
 	TLS            *InboundTLSOptions `json:"tls,omitempty"`
+	HTTP3          bool               `json:"http3,omitempty"`
+	PAC            *HTTPPACOptions    `json:"pac,omitempty"`
+}
+
+type HTTPPACOptions struct {
+	Enabled      bool   `json:"enabled,omitempty"`
+	Path         string `json:"path,omitempty"`
+	ProxyAddress string `json:"proxy_address,omitempty"`
 }
 
 type SocksOutboundOptions struct {
@@ -27,6 +35,7 @@ type SocksOutboundOptions struct {
 type HTTPOutboundOptions struct {
 	DialerOptions
 	ServerOptions
+	Version  string                      `json:"version,omitempty"`
 	Username string                      `json:"username,omitempty"`
 	Password string                      `json:"password,omitempty"`
 	TLS      *OutboundTLSOptions         `json:"tls,omitempty"`
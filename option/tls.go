@@ -1,35 +1,54 @@
 package option
 
 type InboundTLSOptions struct {
-	Enabled         bool                   `json:"enabled,omitempty"`
-	ServerName      string                 `json:"server_name,omitempty"`
-	Insecure        bool                   `json:"insecure,omitempty"`
-	ALPN            Listable[string]       `json:"alpn,omitempty"`
-	MinVersion      string                 `json:"min_version,omitempty"`
-	MaxVersion      string                 `json:"max_version,omitempty"`
-	CipherSuites    Listable[string]       `json:"cipher_suites,omitempty"`
-	Certificate     string                 `json:"certificate,omitempty"`
-	CertificatePath string                 `json:"certificate_path,omitempty"`
-	Key             string                 `json:"key,omitempty"`
-	KeyPath         string                 `json:"key_path,omitempty"`
-	ACME            *InboundACMEOptions    `json:"acme,omitempty"`
-	Reality         *InboundRealityOptions `json:"reality,omitempty"`
+	Enabled               bool                           `json:"enabled,omitempty"`
+	ServerName            string                         `json:"server_name,omitempty"`
+	Insecure              bool                           `json:"insecure,omitempty"`
+	ALPN                  Listable[string]               `json:"alpn,omitempty"`
+	MinVersion            string                         `json:"min_version,omitempty"`
+	MaxVersion            string                         `json:"max_version,omitempty"`
+	CipherSuites          Listable[string]               `json:"cipher_suites,omitempty"`
+	Certificate           string                         `json:"certificate,omitempty"`
+	CertificatePath       string                         `json:"certificate_path,omitempty"`
+	Key                   string                         `json:"key,omitempty"`
+	KeyPath               string                         `json:"key_path,omitempty"`
+	Certificates          []InboundTLSCertificateOptions `json:"certificates,omitempty"`
+	ClientAuthentication  string                         `json:"client_authentication,omitempty"`
+	ClientCertificate     Listable[string]               `json:"client_certificate,omitempty"`
+	ClientCertificatePath Listable[string]               `json:"client_certificate_path,omitempty"`
+	ACME                  *InboundACMEOptions            `json:"acme,omitempty"`
+	ECH                   *InboundECHOptions             `json:"ech,omitempty"`
+	Reality               *InboundRealityOptions         `json:"reality,omitempty"`
+}
+
+type InboundTLSCertificateOptions struct {
+	ServerName      Listable[string] `json:"server_name,omitempty"`
+	Certificate     string           `json:"certificate,omitempty"`
+	CertificatePath string           `json:"certificate_path,omitempty"`
+	Key             string           `json:"key,omitempty"`
+	KeyPath         string           `json:"key_path,omitempty"`
 }
 
 type OutboundTLSOptions struct {
-	Enabled         bool                    `json:"enabled,omitempty"`
-	DisableSNI      bool                    `json:"disable_sni,omitempty"`
-	ServerName      string                  `json:"server_name,omitempty"`
-	Insecure        bool                    `json:"insecure,omitempty"`
-	ALPN            Listable[string]        `json:"alpn,omitempty"`
-	MinVersion      string                  `json:"min_version,omitempty"`
-	MaxVersion      string                  `json:"max_version,omitempty"`
-	CipherSuites    Listable[string]        `json:"cipher_suites,omitempty"`
-	Certificate     string                  `json:"certificate,omitempty"`
-	CertificatePath string                  `json:"certificate_path,omitempty"`
-	ECH             *OutboundECHOptions     `json:"ech,omitempty"`
-	UTLS            *OutboundUTLSOptions    `json:"utls,omitempty"`
-	Reality         *OutboundRealityOptions `json:"reality,omitempty"`
+	Enabled                    bool                    `json:"enabled,omitempty"`
+	DisableSNI                 bool                    `json:"disable_sni,omitempty"`
+	ServerName                 string                  `json:"server_name,omitempty"`
+	Insecure                   bool                    `json:"insecure,omitempty"`
+	ALPN                       Listable[string]        `json:"alpn,omitempty"`
+	MinVersion                 string                  `json:"min_version,omitempty"`
+	MaxVersion                 string                  `json:"max_version,omitempty"`
+	CipherSuites               Listable[string]        `json:"cipher_suites,omitempty"`
+	Certificate                string                  `json:"certificate,omitempty"`
+	CertificatePath            string                  `json:"certificate_path,omitempty"`
+	CertificateSHA256          Listable[string]        `json:"certificate_sha256,omitempty"`
+	CertificatePublicKeySHA256 Listable[string]        `json:"certificate_public_key_sha256,omitempty"`
+	ClientCertificate          string                  `json:"client_certificate,omitempty"`
+	ClientCertificatePath      string                  `json:"client_certificate_path,omitempty"`
+	ClientKey                  string                  `json:"client_key,omitempty"`
+	ClientKeyPath              string                  `json:"client_key_path,omitempty"`
+	ECH                        *OutboundECHOptions     `json:"ech,omitempty"`
+	UTLS                       *OutboundUTLSOptions    `json:"utls,omitempty"`
+	Reality                    *OutboundRealityOptions `json:"reality,omitempty"`
 }
 
 type InboundRealityOptions struct {
@@ -45,6 +64,14 @@ type InboundRealityHandshakeOptions struct {
 	DialerOptions
 }
 
+type InboundECHOptions struct {
+	Enabled                     bool             `json:"enabled,omitempty"`
+	PQSignatureSchemesEnabled   bool             `json:"pq_signature_schemes_enabled,omitempty"`
+	DynamicRecordSizingDisabled bool             `json:"dynamic_record_sizing_disabled,omitempty"`
+	Key                         Listable[string] `json:"key,omitempty"`
+	KeyPath                     string           `json:"key_path,omitempty"`
+}
+
 type OutboundECHOptions struct {
 	Enabled                     bool   `json:"enabled,omitempty"`
 	PQSignatureSchemesEnabled   bool   `json:"pq_signature_schemes_enabled,omitempty"`
@@ -53,8 +80,22 @@ type OutboundECHOptions struct {
 }
 
 type OutboundUTLSOptions struct {
-	Enabled     bool   `json:"enabled,omitempty"`
-	Fingerprint string `json:"fingerprint,omitempty"`
+	Enabled         bool                        `json:"enabled,omitempty"`
+	Fingerprint     string                      `json:"fingerprint,omitempty"`
+	ClientHello     string                      `json:"client_hello,omitempty"`
+	ClientHelloSpec *UTLSClientHelloSpecOptions `json:"client_hello_spec,omitempty"`
+}
+
+type UTLSClientHelloSpecOptions struct {
+	CipherSuites           Listable[string] `json:"cipher_suites,omitempty"`
+	Extensions             Listable[string] `json:"extensions,omitempty"`
+	SupportedGroups        Listable[string] `json:"supported_groups,omitempty"`
+	KeyShares              Listable[string] `json:"key_shares,omitempty"`
+	SignatureAlgorithms    Listable[string] `json:"signature_algorithms,omitempty"`
+	SupportedVersions      Listable[string] `json:"supported_versions,omitempty"`
+	ALPN                   Listable[string] `json:"alpn,omitempty"`
+	CertificateCompression Listable[string] `json:"certificate_compression,omitempty"`
+	ApplicationSettings    Listable[string] `json:"application_settings,omitempty"`
 }
 
 type OutboundRealityOptions struct {
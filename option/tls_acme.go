@@ -1,5 +1,11 @@
 package option
 
+import (
+	"github.com/sagernet/sing-box/common/json"
+	C "github.com/sagernet/sing-box/constant"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
 type InboundACMEOptions struct {
 	Domain                  Listable[string]            `json:"domain,omitempty"`
 	DataDirectory           string                      `json:"data_directory,omitempty"`
@@ -11,9 +17,71 @@ type InboundACMEOptions struct {
 	AlternativeHTTPPort     uint16                      `json:"alternative_http_port,omitempty"`
 	AlternativeTLSPort      uint16                      `json:"alternative_tls_port,omitempty"`
 	ExternalAccount         *ACMEExternalAccountOptions `json:"external_account,omitempty"`
+	DNS01Challenge          *ACMEDNS01ChallengeOptions  `json:"dns01_challenge,omitempty"`
 }
 
 type ACMEExternalAccountOptions struct {
 	KeyID  string `json:"key_id,omitempty"`
 	MACKey string `json:"mac_key,omitempty"`
 }
+
+type _ACMEDNS01ChallengeOptions struct {
+	Provider           string                `json:"provider,omitempty"`
+	TTL                Duration              `json:"ttl,omitempty"`
+	PropagationDelay   Duration              `json:"propagation_delay,omitempty"`
+	PropagationTimeout Duration              `json:"propagation_timeout,omitempty"`
+	Resolvers          Listable[string]      `json:"resolvers,omitempty"`
+	RFC2136Options     ACMEDNSRFC2136Options `json:"-"`
+	WebhookOptions     ACMEDNSWebhookOptions `json:"-"`
+}
+
+type ACMEDNS01ChallengeOptions _ACMEDNS01ChallengeOptions
+
+func (o ACMEDNS01ChallengeOptions) MarshalJSON() ([]byte, error) {
+	var v any
+	switch o.Provider {
+	case C.DNSProviderRFC2136:
+		v = o.RFC2136Options
+	case C.DNSProviderWebhook:
+		v = o.WebhookOptions
+	default:
+		return nil, E.New("unknown provider type: " + o.Provider)
+	}
+	return MarshallObjects((_ACMEDNS01ChallengeOptions)(o), v)
+}
+
+func (o *ACMEDNS01ChallengeOptions) UnmarshalJSON(bytes []byte) error {
+	err := json.Unmarshal(bytes, (*_ACMEDNS01ChallengeOptions)(o))
+	if err != nil {
+		return err
+	}
+	var v any
+	switch o.Provider {
+	case C.DNSProviderRFC2136:
+		v = &o.RFC2136Options
+	case C.DNSProviderWebhook:
+		v = &o.WebhookOptions
+	default:
+		return E.New("unknown provider type: " + o.Provider)
+	}
+	err = UnmarshallExcluded(bytes, (*_ACMEDNS01ChallengeOptions)(o), v)
+	if err != nil {
+		return E.Cause(err, "dns01 challenge options")
+	}
+	return nil
+}
+
+type ACMEDNSRFC2136Options struct {
+	Server        string `json:"server,omitempty"`
+	Network       string `json:"network,omitempty"`
+	TSIGKeyName   string `json:"tsig_key_name,omitempty"`
+	TSIGSecret    string `json:"tsig_secret,omitempty"`
+	TSIGAlgorithm string `json:"tsig_algorithm,omitempty"`
+}
+
+type ACMEDNSWebhookOptions struct {
+	URL      string                      `json:"url,omitempty"`
+	Username string                      `json:"username,omitempty"`
+	Password string                      `json:"password,omitempty"`
+	Headers  map[string]Listable[string] `json:"headers,omitempty"`
+}
@@ -8,17 +8,15 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/proxyproto"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-dns"
 	"github.com/sagernet/sing/common/buf"
 	"github.com/sagernet/sing/common/bufio"
-	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
-
-	"github.com/pires/go-proxyproto"
 )
 
 var (
@@ -38,6 +36,14 @@ type Direct struct {
 
 func NewDirect(router adapter.Router, logger log.ContextLogger, tag string, options option.DirectOutboundOptions) (*Direct, error) {
 	options.UDPFragmentDefault = true
+	err := proxyproto.CheckVersion(options.ProxyProtocol)
+	if err != nil {
+		return nil, err
+	}
+	// the header is written below, with the destination before override_address applies
+	proxyProto := options.ProxyProtocol
+	options.ProxyProtocol = 0
+	outboundDialer := dialer.New(router, options.DialerOptions)
 	outbound := &Direct{
 		myOutboundAdapter: myOutboundAdapter{
 			protocol:     C.TypeDirect,
@@ -49,11 +55,8 @@ func NewDirect(router adapter.Router, logger log.ContextLogger, tag string, opti
 		},
 		domainStrategy: dns.DomainStrategy(options.DomainStrategy),
 		fallbackDelay:  time.Duration(options.FallbackDelay),
-		dialer:         dialer.New(router, options.DialerOptions),
-		proxyProto:     options.ProxyProtocol,
-	}
-	if options.ProxyProtocol > 2 {
-		return nil, E.New("invalid proxy protocol option: ", options.ProxyProtocol)
+		dialer:         outboundDialer,
+		proxyProto:     proxyProto,
 	}
 	if options.OverrideAddress != "" && options.OverridePort != 0 {
 		outbound.overrideOption = 1
@@ -99,14 +102,13 @@ func (h *Direct) DialContext(ctx context.Context, network string, destination M.
 		if !source.IsValid() {
 			source = M.SocksaddrFromNet(conn.LocalAddr())
 		}
-		if originDestination.Addr.Is6() {
-			source = M.SocksaddrFrom(netip.AddrFrom16(source.Addr.As16()), source.Port)
+		if !originDestination.IsIP() {
+			originDestination = M.SocksaddrFromNet(conn.RemoteAddr())
 		}
-		header := proxyproto.HeaderProxyFromAddrs(h.proxyProto, source.TCPAddr(), originDestination.TCPAddr())
-		_, err = header.WriteTo(conn)
+		err = proxyproto.WriteHeader(conn, h.proxyProto, source, originDestination, metadata)
 		if err != nil {
 			conn.Close()
-			return nil, E.Cause(err, "write proxy protocol header")
+			return nil, err
 		}
 	}
 	return conn, nil
@@ -146,14 +148,13 @@ func (h *Direct) DialParallel(ctx context.Context, network string, destination M
 		if !source.IsValid() {
 			source = M.SocksaddrFromNet(conn.LocalAddr())
 		}
-		if originDestination.Addr.Is6() {
-			source = M.SocksaddrFrom(netip.AddrFrom16(source.Addr.As16()), source.Port)
+		if !originDestination.IsIP() {
+			originDestination = M.SocksaddrFromNet(conn.RemoteAddr())
 		}
-		header := proxyproto.HeaderProxyFromAddrs(h.proxyProto, source.TCPAddr(), originDestination.TCPAddr())
-		_, err = header.WriteTo(conn)
+		err = proxyproto.WriteHeader(conn, h.proxyProto, source, originDestination, metadata)
 		if err != nil {
 			conn.Close()
-			return nil, E.Cause(err, "write proxy protocol header")
+			return nil, err
 		}
 	}
 	return conn, nil
@@ -12,7 +12,9 @@ import (
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/httpconnect"
 	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 	sHTTP "github.com/sagernet/sing/protocol/http"
@@ -22,14 +24,10 @@ var _ adapter.Outbound = (*HTTP)(nil)
 
 type HTTP struct {
 	myOutboundAdapter
-	client *sHTTP.Client
+	client N.Dialer
 }
 
 func NewHTTP(router adapter.Router, logger log.ContextLogger, tag string, options option.HTTPOutboundOptions) (*HTTP, error) {
-	detour, err := tls.NewDialerFromOptions(router, dialer.New(router, options.DialerOptions), options.Server, common.PtrValueOrDefault(options.TLS))
-	if err != nil {
-		return nil, err
-	}
 	var headers http.Header
 	if options.Headers != nil {
 		headers = make(http.Header)
@@ -37,8 +35,8 @@ func NewHTTP(router adapter.Router, logger log.ContextLogger, tag string, option
 			headers[key] = values
 		}
 	}
-	return &HTTP{
-		myOutboundAdapter{
+	outbound := &HTTP{
+		myOutboundAdapter: myOutboundAdapter{
 			protocol:     C.TypeHTTP,
 			network:      []string{N.NetworkTCP},
 			router:       router,
@@ -46,15 +44,46 @@ func NewHTTP(router adapter.Router, logger log.ContextLogger, tag string, option
 			tag:          tag,
 			dependencies: withDialerDependency(options.DialerOptions),
 		},
-		sHTTP.NewClient(sHTTP.Options{
+	}
+	outboundDialer := dialer.New(router, options.DialerOptions)
+	switch options.Version {
+	case "", "1.1":
+		detour, err := tls.NewDialerFromOptions(router, outboundDialer, options.Server, common.PtrValueOrDefault(options.TLS))
+		if err != nil {
+			return nil, err
+		}
+		outbound.client = sHTTP.NewClient(sHTTP.Options{
 			Dialer:   detour,
 			Server:   options.ServerOptions.Build(),
 			Username: options.Username,
 			Password: options.Password,
 			Path:     options.Path,
 			Headers:  headers,
-		}),
-	}, nil
+		})
+	case "2", "3":
+		if options.Path != "" {
+			return nil, E.New("path is not supported in HTTP/", options.Version)
+		}
+		tlsConfig, err := tls.NewClient(router, options.Server, common.PtrValueOrDefault(options.TLS))
+		if err != nil {
+			return nil, err
+		}
+		outbound.client, err = httpconnect.NewClient(httpconnect.Options{
+			Dialer:    outboundDialer,
+			Server:    options.ServerOptions.Build(),
+			TLSConfig: tlsConfig,
+			HTTP3:     options.Version == "3",
+			Username:  options.Username,
+			Password:  options.Password,
+			Headers:   headers,
+		})
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, E.New("unknown HTTP version: ", options.Version)
+	}
+	return outbound, nil
 }
 
 func (h *HTTP) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
@@ -76,3 +105,7 @@ func (h *HTTP) NewConnection(ctx context.Context, conn net.Conn, metadata adapte
 func (h *HTTP) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
 	return os.ErrInvalid
 }
+
+func (h *HTTP) Close() error {
+	return common.Close(h.client)
+}
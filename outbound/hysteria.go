@@ -4,13 +4,16 @@ package outbound
 
 import (
 	"context"
+	"io"
 	"net"
 	"sync"
+	"time"
 
 	"github.com/sagernet/quic-go"
 	"github.com/sagernet/quic-go/congestion"
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/portrange"
 	"github.com/sagernet/sing-box/common/tls"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -33,6 +36,8 @@ type Hysteria struct {
 	ctx          context.Context
 	dialer       N.Dialer
 	serverAddr   M.Socksaddr
+	serverPorts  []uint16
+	hopInterval  time.Duration
 	tlsConfig    *tls.STDConfig
 	quicConfig   *quic.Config
 	authKey      []byte
@@ -41,7 +46,7 @@ type Hysteria struct {
 	recvBPS      uint64
 	connAccess   sync.Mutex
 	conn         quic.Connection
-	rawConn      net.Conn
+	rawConn      io.Closer
 	udpAccess    sync.RWMutex
 	udpSessions  map[uint32]chan *hysteria.UDPMessage
 	udpDefragger hysteria.Defragger
@@ -111,6 +116,16 @@ func NewHysteria(ctx context.Context, router adapter.Router, logger log.ContextL
 	} else {
 		down = uint64(options.DownMbps) * hysteria.MbpsToBps
 	}
+	var serverPorts []uint16
+	if len(options.ServerPorts) > 0 {
+		serverPorts, err = portrange.Parse(options.ServerPorts)
+		if err != nil {
+			return nil, E.Cause(err, "parse server_ports")
+		}
+		if options.ServerPort != 0 && !common.Contains(serverPorts, options.ServerPort) {
+			serverPorts = append([]uint16{options.ServerPort}, serverPorts...)
+		}
+	}
 	if up < hysteria.MinSpeedBPS {
 		return nil, E.New("invalid up speed")
 	}
@@ -126,15 +141,17 @@ func NewHysteria(ctx context.Context, router adapter.Router, logger log.ContextL
 			tag:          tag,
 			dependencies: withDialerDependency(options.DialerOptions),
 		},
-		ctx:        ctx,
-		dialer:     dialer.New(router, options.DialerOptions),
-		serverAddr: options.ServerOptions.Build(),
-		tlsConfig:  tlsConfig,
-		quicConfig: quicConfig,
-		authKey:    auth,
-		xplusKey:   xplus,
-		sendBPS:    up,
-		recvBPS:    down,
+		ctx:         ctx,
+		dialer:      dialer.New(router, options.DialerOptions),
+		serverAddr:  options.ServerOptions.Build(),
+		serverPorts: serverPorts,
+		hopInterval: time.Duration(options.HopInterval),
+		tlsConfig:   tlsConfig,
+		quicConfig:  quicConfig,
+		authKey:     auth,
+		xplusKey:    xplus,
+		sendBPS:     up,
+		recvBPS:     down,
 	}, nil
 }
 
@@ -168,17 +185,35 @@ func (h *Hysteria) offer(ctx context.Context) (quic.Connection, error) {
 }
 
 func (h *Hysteria) offerNew(ctx context.Context) (quic.Connection, error) {
-	udpConn, err := h.dialer.DialContext(h.ctx, "udp", h.serverAddr)
-	if err != nil {
-		return nil, err
+	var (
+		packetConn net.PacketConn
+		rawConn    io.Closer
+		remoteAddr net.Addr
+	)
+	if len(h.serverPorts) > 0 {
+		hopConn, err := hysteria.NewHopPacketConn(h.ctx, h.logger, h.dialer, h.serverAddr, h.serverPorts, h.hopInterval)
+		if err != nil {
+			return nil, err
+		}
+		packetConn = hopConn
+		rawConn = hopConn
+		remoteAddr = hopConn.RemoteAddr()
+	} else {
+		udpConn, err := h.dialer.DialContext(h.ctx, "udp", h.serverAddr)
+		if err != nil {
+			return nil, err
+		}
+		packetConn = bufio.NewUnbindPacketConn(udpConn)
+		rawConn = udpConn
+		remoteAddr = udpConn.RemoteAddr()
 	}
-	var packetConn net.PacketConn
-	packetConn = bufio.NewUnbindPacketConn(udpConn)
 	if h.xplusKey != nil {
 		packetConn = hysteria.NewXPlusPacketConn(packetConn, h.xplusKey)
 	}
-	packetConn = &hysteria.PacketConnWrapper{PacketConn: packetConn}
-	quicConn, err := quic.Dial(h.ctx, packetConn, udpConn.RemoteAddr(), h.tlsConfig, h.quicConfig)
+	if _, isUDPConn := common.Cast[*net.UDPConn](packetConn); isUDPConn {
+		packetConn = &hysteria.PacketConnWrapper{PacketConn: packetConn}
+	}
+	quicConn, err := quic.Dial(h.ctx, packetConn, remoteAddr, h.tlsConfig, h.quicConfig)
 	if err != nil {
 		packetConn.Close()
 		return nil, err
@@ -208,7 +243,7 @@ func (h *Hysteria) offerNew(ctx context.Context) (quic.Connection, error) {
 	}
 	quicConn.SetCongestionControl(hysteria.NewBrutalSender(congestion.ByteCount(serverHello.RecvBPS)))
 	h.conn = quicConn
-	h.rawConn = udpConn
+	h.rawConn = rawConn
 	return quicConn, nil
 }
 
@@ -0,0 +1,103 @@
+package outbound
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/transport/httpconnect"
+	"github.com/sagernet/sing-box/transport/naive"
+	"github.com/sagernet/sing/common"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+var _ adapter.Outbound = (*Naive)(nil)
+
+type Naive struct {
+	myOutboundAdapter
+	client *httpconnect.Client
+}
+
+func NewNaive(router adapter.Router, logger log.ContextLogger, tag string, options option.NaiveOutboundOptions) (*Naive, error) {
+	if options.TLS == nil || !options.TLS.Enabled {
+		return nil, C.ErrTLSRequired
+	}
+	tlsConfig, err := tls.NewClient(router, options.Server, common.PtrValueOrDefault(options.TLS))
+	if err != nil {
+		return nil, err
+	}
+	var headers http.Header
+	if options.ExtraHeaders != nil {
+		headers = make(http.Header)
+		for key, values := range options.ExtraHeaders {
+			headers[key] = values
+		}
+	}
+	client, err := httpconnect.NewClient(httpconnect.Options{
+		Dialer:    dialer.New(router, options.DialerOptions),
+		Server:    options.ServerOptions.Build(),
+		TLSConfig: tlsConfig,
+		HTTP3:     options.QUIC,
+		Username:  options.Username,
+		Password:  options.Password,
+		Headers:   headers,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Naive{
+		myOutboundAdapter{
+			protocol:     C.TypeNaive,
+			network:      []string{N.NetworkTCP},
+			router:       router,
+			logger:       logger,
+			tag:          tag,
+			dependencies: withDialerDependency(options.DialerOptions),
+		},
+		client,
+	}, nil
+}
+
+func (h *Naive) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	if network != N.NetworkTCP {
+		return nil, os.ErrInvalid
+	}
+	ctx, metadata := adapter.AppendContext(ctx)
+	metadata.Outbound = h.tag
+	metadata.Destination = destination
+	h.logger.InfoContext(ctx, "outbound connection to ", destination)
+	header := make(http.Header)
+	header.Set("Padding", naive.GeneratePaddingHeader())
+	conn, responseHeader, err := h.client.Connect(ctx, destination, header)
+	if err != nil {
+		return nil, err
+	}
+	if responseHeader.Get("Padding") == "" {
+		return conn, nil
+	}
+	return naive.NewPaddingConn(conn), nil
+}
+
+func (h *Naive) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return nil, os.ErrInvalid
+}
+
+func (h *Naive) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	return NewConnection(ctx, h, conn, metadata)
+}
+
+func (h *Naive) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	return os.ErrInvalid
+}
+
+func (h *Naive) Close() error {
+	return h.client.Close()
+}
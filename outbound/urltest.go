@@ -96,6 +96,13 @@ func (s *URLTest) URLTest(ctx context.Context, link string) (map[string]uint16,
 	return s.group.URLTest(ctx, link)
 }
 
+func (s *URLTest) HistoryStorage() *urltest.HistoryStorage {
+	if s.group == nil {
+		return nil
+	}
+	return s.group.history
+}
+
 func (s *URLTest) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
 	s.group.Start()
 	outbound := s.group.Select(network)
@@ -11,13 +11,17 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/accesslog"
 	"github.com/sagernet/sing-box/common/dialer"
 	"github.com/sagernet/sing-box/common/dialer/conntrack"
 	"github.com/sagernet/sing-box/common/geoip"
 	"github.com/sagernet/sing-box/common/geosite"
 	"github.com/sagernet/sing-box/common/mux"
 	"github.com/sagernet/sing-box/common/process"
+	"github.com/sagernet/sing-box/common/quota"
+	"github.com/sagernet/sing-box/common/ratelimit"
 	"github.com/sagernet/sing-box/common/sniff"
+	"github.com/sagernet/sing-box/common/traffic"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/experimental/libbox/platform"
 	"github.com/sagernet/sing-box/log"
@@ -38,6 +42,7 @@ import (
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/common/uot"
+	"github.com/sagernet/sing/service"
 )
 
 var _ adapter.Router = (*Router)(nil)
@@ -46,10 +51,11 @@ type Router struct {
 	ctx                                context.Context
 	logger                             log.ContextLogger
 	dnsLogger                          log.ContextLogger
+	inbounds                           []adapter.Inbound
 	inboundByTag                       map[string]adapter.Inbound
 	outbounds                          []adapter.Outbound
 	outboundByTag                      map[string]adapter.Outbound
-	rules                              []adapter.Rule
+	rules                              []adapter.RouteRule
 	defaultDetour                      string
 	defaultOutboundForConnection       adapter.Outbound
 	defaultOutboundForPacketConnection adapter.Outbound
@@ -78,8 +84,13 @@ type Router struct {
 	packageManager                     tun.PackageManager
 	processSearcher                    process.Searcher
 	timeService                        adapter.TimeService
+	quotaManager                       *quota.Manager
+	rateLimitManager                   *ratelimit.Manager
+	accessLog                          *accesslog.Manager
 	clashServer                        adapter.ClashServer
+	trafficManager                     *traffic.Manager
 	v2rayServer                        adapter.V2RayServer
+	metricsServer                      adapter.MetricsServer
 	platformInterface                  platform.Interface
 }
 
@@ -97,7 +108,7 @@ func NewRouter(
 		logger:                logFactory.NewLogger("router"),
 		dnsLogger:             logFactory.NewLogger("dns"),
 		outboundByTag:         make(map[string]adapter.Outbound),
-		rules:                 make([]adapter.Rule, 0, len(options.Rules)),
+		rules:                 make([]adapter.RouteRule, 0, len(options.Rules)),
 		dnsRules:              make([]adapter.DNSRule, 0, len(dnsOptions.Rules)),
 		needGeoIPDatabase:     hasRule(options.Rules, isGeoIPRule) || hasDNSRule(dnsOptions.Rules, isGeoIPDNSRule),
 		needGeositeDatabase:   hasRule(options.Rules, isGeositeRule) || hasDNSRule(dnsOptions.Rules, isGeositeDNSRule),
@@ -110,6 +121,7 @@ func NewRouter(
 		defaultInterface:      options.DefaultInterface,
 		defaultMark:           options.DefaultMark,
 		platformInterface:     platformInterface,
+		trafficManager:        service.FromContext[*traffic.Manager](ctx),
 	}
 	router.dnsClient = dns.NewClient(dns.ClientOptions{
 		DisableCache:     dnsOptions.DNSClientOptions.DisableCache,
@@ -317,6 +329,32 @@ func NewRouter(
 	if ntpOptions.Enabled {
 		router.timeService = ntp.NewService(ctx, router, logFactory.NewLogger("ntp"), ntpOptions)
 	}
+	if len(options.Quotas) > 0 {
+		quotaManager, err := quota.NewManager(router, logFactory.NewLogger("quota"), options.Quotas)
+		if err != nil {
+			return nil, E.Cause(err, "create quota manager")
+		}
+		router.quotaManager = quotaManager
+	}
+	if len(options.RateLimits) > 0 {
+		rateLimitManager, err := ratelimit.NewManager(router, options.RateLimits)
+		if err != nil {
+			return nil, E.Cause(err, "create rate limit manager")
+		}
+		router.rateLimitManager = rateLimitManager
+	}
+	if options.AccessLog != nil {
+		accessLog, err := accesslog.NewManager(ctx, logFactory.NewLogger("access-log"), *options.AccessLog)
+		if err != nil {
+			return nil, E.Cause(err, "create access log")
+		}
+		router.accessLog = accessLog
+	}
+	for i, rule := range router.rules {
+		if rule.RateLimit() != "" && (router.rateLimitManager == nil || !router.rateLimitManager.HasTag(rule.RateLimit())) {
+			return nil, E.New("rate limit not found for rule[", i, "]: ", rule.RateLimit())
+		}
+	}
 	return router, nil
 }
 
@@ -389,6 +427,7 @@ func (r *Router) Initialize(inbounds []adapter.Inbound, outbounds []adapter.Outb
 		r.logger.Info("using ", defaultOutboundForConnection.Type(), "[", description, "] as default outbound for connection")
 		r.logger.Info("using ", defaultOutboundForPacketConnection.Type(), "[", packetDescription, "] as default outbound for packet connection")
 	}
+	r.inbounds = inbounds
 	r.inboundByTag = inboundByTag
 	r.outbounds = outbounds
 	r.defaultOutboundForConnection = defaultOutboundForConnection
@@ -402,6 +441,10 @@ func (r *Router) Initialize(inbounds []adapter.Inbound, outbounds []adapter.Outb
 	return nil
 }
 
+func (r *Router) Inbounds() []adapter.Inbound {
+	return r.inbounds
+}
+
 func (r *Router) Outbounds() []adapter.Outbound {
 	return r.outbounds
 }
@@ -487,6 +530,18 @@ func (r *Router) Start() error {
 			return E.Cause(err, "initialize time service")
 		}
 	}
+	if r.quotaManager != nil {
+		err := r.quotaManager.Start()
+		if err != nil {
+			return E.Cause(err, "initialize quota manager")
+		}
+	}
+	if r.accessLog != nil {
+		err := r.accessLog.Start()
+		if err != nil {
+			return E.Cause(err, "initialize access log")
+		}
+	}
 	return nil
 }
 
@@ -540,6 +595,18 @@ func (r *Router) Close() error {
 			return E.Cause(err, "close time service")
 		})
 	}
+	if r.quotaManager != nil {
+		r.logger.Trace("closing quota manager")
+		err = E.Append(err, r.quotaManager.Close(), func(err error) error {
+			return E.Cause(err, "close quota manager")
+		})
+	}
+	if r.accessLog != nil {
+		r.logger.Trace("closing access log")
+		err = E.Append(err, r.accessLog.Close(), func(err error) error {
+			return E.Cause(err, "close access log")
+		})
+	}
 	if r.fakeIPStore != nil {
 		r.logger.Trace("closing fakeip store")
 		err = E.Append(err, r.fakeIPStore.Close(), func(err error) error {
@@ -566,7 +633,7 @@ func (r *Router) FakeIPStore() adapter.FakeIPStore {
 	return r.fakeIPStore
 }
 
-func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) (err error) {
 	if metadata.InboundDetour != "" {
 		if metadata.LastInbound == metadata.InboundDetour {
 			return E.New("routing loop on detour: ", metadata.InboundDetour)
@@ -619,6 +686,13 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 		metadata.Destination = M.Socksaddr{Addr: netip.IPv4Unspecified()}
 		return r.RoutePacketConnection(ctx, uot.NewConn(conn, uot.Request{}), metadata)
 	}
+	accessTracker, err := r.inboundAccess(ctx, metadata)
+	if err != nil {
+		return err
+	}
+	if accessTracker != nil {
+		defer accessTracker.Leave()
+	}
 
 	if r.fakeIPStore != nil && r.fakeIPStore.Contains(metadata.Destination.Addr) {
 		domain, loaded := r.fakeIPStore.Lookup(metadata.Destination.Addr)
@@ -685,12 +759,26 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 	if err != nil {
 		return err
 	}
+	ctx = log.ContextWithFields(ctx, connectionLogFields(N.NetworkTCP, metadata, detour.Tag())...)
 	if !common.Contains(detour.Network(), N.NetworkTCP) {
 		return E.New("missing supported outbound, closing connection")
 	}
-	if r.clashServer != nil {
-		trackerConn, tracker := r.clashServer.RoutedConnection(ctx, conn, metadata, matchedRule)
+	if r.quotaManager != nil {
+		quotaConn, tracker, err := r.quotaManager.RoutedConnection(ctx, conn, metadata)
+		if err != nil {
+			return err
+		}
 		defer tracker.Leave()
+		conn = quotaConn
+	}
+	if r.rateLimitManager != nil {
+		conn = r.rateLimitManager.RoutedConnection(conn, metadata, matchedRule, detour)
+	}
+	if r.trafficManager != nil {
+		trackerConn, connection := r.trafficManager.RoutedConnection(ctx, conn, metadata, matchedRule, detour, r)
+		defer func() {
+			connection.Leave(err)
+		}()
 		conn = trackerConn
 	}
 	if r.v2rayServer != nil {
@@ -701,7 +789,19 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 	return detour.NewConnection(ctx, conn, metadata)
 }
 
-func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+func (r *Router) inboundAccess(ctx context.Context, metadata adapter.InboundContext) (adapter.Tracker, error) {
+	inbound, isAccessControl := r.inboundByTag[metadata.Inbound].(adapter.AccessControlInbound)
+	if !isAccessControl {
+		return nil, nil
+	}
+	controller := inbound.AccessController()
+	if controller == nil {
+		return nil, nil
+	}
+	return controller.RoutedConnection(ctx, metadata)
+}
+
+func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) (err error) {
 	if metadata.InboundDetour != "" {
 		if metadata.LastInbound == metadata.InboundDetour {
 			return E.New("routing loop on detour: ", metadata.InboundDetour)
@@ -727,6 +827,13 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 		return nil
 	}
 	metadata.Network = N.NetworkUDP
+	accessTracker, err := r.inboundAccess(ctx, metadata)
+	if err != nil {
+		return err
+	}
+	if accessTracker != nil {
+		defer accessTracker.Leave()
+	}
 
 	var originAddress M.Socksaddr
 	if r.fakeIPStore != nil && r.fakeIPStore.Contains(metadata.Destination.Addr) {
@@ -793,12 +900,26 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 	if err != nil {
 		return err
 	}
+	ctx = log.ContextWithFields(ctx, connectionLogFields(N.NetworkUDP, metadata, detour.Tag())...)
 	if !common.Contains(detour.Network(), N.NetworkUDP) {
 		return E.New("missing supported outbound, closing packet connection")
 	}
-	if r.clashServer != nil {
-		trackerConn, tracker := r.clashServer.RoutedPacketConnection(ctx, conn, metadata, matchedRule)
+	if r.quotaManager != nil {
+		quotaConn, tracker, err := r.quotaManager.RoutedPacketConnection(ctx, conn, metadata)
+		if err != nil {
+			return err
+		}
 		defer tracker.Leave()
+		conn = quotaConn
+	}
+	if r.rateLimitManager != nil {
+		conn = r.rateLimitManager.RoutedPacketConnection(conn, metadata, matchedRule, detour)
+	}
+	if r.trafficManager != nil {
+		trackerConn, connection := r.trafficManager.RoutedPacketConnection(ctx, conn, metadata, matchedRule, detour, r)
+		defer func() {
+			connection.Leave(err)
+		}()
 		conn = trackerConn
 	}
 	if r.v2rayServer != nil {
@@ -812,7 +933,23 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 	return detour.NewPacketConnection(ctx, conn, metadata)
 }
 
-func (r *Router) match(ctx context.Context, metadata *adapter.InboundContext, defaultOutbound adapter.Outbound) (context.Context, adapter.Rule, adapter.Outbound, error) {
+func connectionLogFields(network string, metadata adapter.InboundContext, outbound string) []log.Field {
+	fields := []log.Field{
+		{Key: "network", Value: network},
+		{Key: "inbound", Value: metadata.Inbound},
+		{Key: "source", Value: metadata.Source.String()},
+		{Key: "destination", Value: metadata.Destination.String()},
+	}
+	if metadata.Domain != "" {
+		fields = append(fields, log.Field{Key: "domain", Value: metadata.Domain})
+	}
+	if metadata.User != "" {
+		fields = append(fields, log.Field{Key: "user", Value: metadata.User})
+	}
+	return append(fields, log.Field{Key: "outbound", Value: outbound})
+}
+
+func (r *Router) match(ctx context.Context, metadata *adapter.InboundContext, defaultOutbound adapter.Outbound) (context.Context, adapter.RouteRule, adapter.Outbound, error) {
 	matchRule, matchOutbound := r.match0(ctx, metadata, defaultOutbound)
 	if contextOutbound, loaded := outbound.TagFromContext(ctx); loaded {
 		if contextOutbound == matchOutbound.Tag() {
@@ -823,7 +960,7 @@ func (r *Router) match(ctx context.Context, metadata *adapter.InboundContext, de
 	return ctx, matchRule, matchOutbound, nil
 }
 
-func (r *Router) match0(ctx context.Context, metadata *adapter.InboundContext, defaultOutbound adapter.Outbound) (adapter.Rule, adapter.Outbound) {
+func (r *Router) match0(ctx context.Context, metadata *adapter.InboundContext, defaultOutbound adapter.Outbound) (adapter.RouteRule, adapter.Outbound) {
 	if r.processSearcher != nil {
 		var originDestination netip.AddrPort
 		if metadata.OriginDestination.IsValid() {
@@ -919,7 +1056,9 @@ func (r *Router) DefaultMark() int {
 }
 
 func (r *Router) Rules() []adapter.Rule {
-	return r.rules
+	return common.Map(r.rules, func(it adapter.RouteRule) adapter.Rule {
+		return it
+	})
 }
 
 func (r *Router) NetworkMonitor() tun.NetworkUpdateMonitor {
@@ -957,6 +1096,14 @@ func (r *Router) SetV2RayServer(server adapter.V2RayServer) {
 	r.v2rayServer = server
 }
 
+func (r *Router) MetricsServer() adapter.MetricsServer {
+	return r.metricsServer
+}
+
+func (r *Router) SetMetricsServer(server adapter.MetricsServer) {
+	r.metricsServer = server
+}
+
 func (r *Router) OnPackagesUpdated(packages int, sharedUsers int) {
 	r.logger.Info("updated packages list: ", packages, " packages, ", sharedUsers, " shared users")
 }
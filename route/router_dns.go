@@ -99,7 +99,11 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, er
 		ctx, transport, strategy := r.matchDNS(ctx)
 		ctx, cancel := context.WithTimeout(ctx, C.DNSTimeout)
 		defer cancel()
+		start := time.Now()
 		response, err = r.dnsClient.Exchange(ctx, transport, message, strategy)
+		if r.metricsServer != nil && len(message.Question) > 0 {
+			r.metricsServer.DNSQuery(transport.Name(), mDNS.TypeToString[message.Question[0].Qtype], time.Since(start), err)
+		}
 		if err != nil && len(message.Question) > 0 {
 			r.dnsLogger.ErrorContext(ctx, E.Cause(err, "exchange failed for ", formatQuestion(message.Question[0].String())))
 		}
@@ -130,7 +134,11 @@ func (r *Router) Lookup(ctx context.Context, domain string, strategy dns.DomainS
 	}
 	ctx, cancel := context.WithTimeout(ctx, C.DNSTimeout)
 	defer cancel()
+	start := time.Now()
 	addrs, err := r.dnsClient.Lookup(ctx, transport, domain, strategy)
+	if r.metricsServer != nil {
+		r.metricsServer.DNSQuery(transport.Name(), lookupQueryType(strategy), time.Since(start), err)
+	}
 	if len(addrs) > 0 {
 		r.dnsLogger.InfoContext(ctx, "lookup succeed for ", domain, ": ", strings.Join(F.MapToString(addrs), " "))
 	} else {
@@ -146,6 +154,17 @@ func (r *Router) LookupDefault(ctx context.Context, domain string) ([]netip.Addr
 	return r.Lookup(ctx, domain, dns.DomainStrategyAsIS)
 }
 
+func lookupQueryType(strategy dns.DomainStrategy) string {
+	switch strategy {
+	case dns.DomainStrategyUseIPv4:
+		return "A"
+	case dns.DomainStrategyUseIPv6:
+		return "AAAA"
+	default:
+		return "A+AAAA"
+	}
+}
+
 func LogDNSAnswers(logger log.ContextLogger, ctx context.Context, domain string, answers []mDNS.RR) {
 	for _, answer := range answers {
 		logger.InfoContext(ctx, "exchanged ", domain, " ", mDNS.Type(answer.Header().Rrtype).String(), " ", formatQuestion(answer.String()))
@@ -8,7 +8,7 @@ import (
 	E "github.com/sagernet/sing/common/exceptions"
 )
 
-func NewRule(router adapter.Router, logger log.ContextLogger, options option.Rule) (adapter.Rule, error) {
+func NewRule(router adapter.Router, logger log.ContextLogger, options option.Rule) (adapter.RouteRule, error) {
 	switch options.Type {
 	case "", C.RuleTypeDefault:
 		if !options.DefaultOptions.IsValid() {
@@ -31,10 +31,11 @@ func NewRule(router adapter.Router, logger log.ContextLogger, options option.Rul
 	}
 }
 
-var _ adapter.Rule = (*DefaultRule)(nil)
+var _ adapter.RouteRule = (*DefaultRule)(nil)
 
 type DefaultRule struct {
 	abstractDefaultRule
+	rateLimit string
 }
 
 type RuleItem interface {
@@ -44,10 +45,11 @@ type RuleItem interface {
 
 func NewDefaultRule(router adapter.Router, logger log.ContextLogger, options option.DefaultRule) (*DefaultRule, error) {
 	rule := &DefaultRule{
-		abstractDefaultRule{
+		abstractDefaultRule: abstractDefaultRule{
 			invert:   options.Invert,
 			outbound: options.Outbound,
 		},
+		rateLimit: options.RateLimit,
 	}
 	if len(options.Inbound) > 0 {
 		item := NewInboundRule(options.Inbound)
@@ -74,6 +76,32 @@ func NewDefaultRule(router adapter.Router, logger log.ContextLogger, options opt
 		rule.items = append(rule.items, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.ProxyProtocolAuthority) > 0 {
+		item := NewProxyProtocolItem("proxy_protocol_authority", options.ProxyProtocolAuthority, func(info *adapter.ProxyProtocolInfo) string {
+			return info.Authority
+		})
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.ProxyProtocolUniqueID) > 0 {
+		item := NewProxyProtocolItem("proxy_protocol_unique_id", options.ProxyProtocolUniqueID, func(info *adapter.ProxyProtocolInfo) string {
+			return info.UniqueID
+		})
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if options.ProxyProtocolSSL {
+		item := NewProxyProtocolSSLItem()
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.ProxyProtocolSSLCN) > 0 {
+		item := NewProxyProtocolItem("proxy_protocol_ssl_cn", options.ProxyProtocolSSLCN, func(info *adapter.ProxyProtocolInfo) string {
+			return info.SSLCN
+		})
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if len(options.Protocol) > 0 {
 		item := NewProtocolItem(options.Protocol)
 		rule.items = append(rule.items, item)
@@ -187,19 +215,25 @@ func NewDefaultRule(router adapter.Router, logger log.ContextLogger, options opt
 	return rule, nil
 }
 
-var _ adapter.Rule = (*LogicalRule)(nil)
+func (r *DefaultRule) RateLimit() string {
+	return r.rateLimit
+}
+
+var _ adapter.RouteRule = (*LogicalRule)(nil)
 
 type LogicalRule struct {
 	abstractLogicalRule
+	rateLimit string
 }
 
 func NewLogicalRule(router adapter.Router, logger log.ContextLogger, options option.LogicalRule) (*LogicalRule, error) {
 	r := &LogicalRule{
-		abstractLogicalRule{
+		abstractLogicalRule: abstractLogicalRule{
 			rules:    make([]adapter.Rule, len(options.Rules)),
 			invert:   options.Invert,
 			outbound: options.Outbound,
 		},
+		rateLimit: options.RateLimit,
 	}
 	switch options.Mode {
 	case C.LogicalTypeAnd:
@@ -218,3 +252,7 @@ func NewLogicalRule(router adapter.Router, logger log.ContextLogger, options opt
 	}
 	return r, nil
 }
+
+func (r *LogicalRule) RateLimit() string {
+	return r.rateLimit
+}
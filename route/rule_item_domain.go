@@ -10,8 +10,10 @@ import (
 var _ RuleItem = (*DomainItem)(nil)
 
 type DomainItem struct {
-	matcher     *domain.Matcher
-	description string
+	domains        []string
+	domainSuffixes []string
+	matcher        *domain.Matcher
+	description    string
 }
 
 func NewDomainItem(domains []string, domainSuffixes []string) *DomainItem {
@@ -38,6 +40,8 @@ func NewDomainItem(domains []string, domainSuffixes []string) *DomainItem {
 		}
 	}
 	return &DomainItem{
+		domains,
+		domainSuffixes,
 		domain.NewMatcher(domains, domainSuffixes),
 		description,
 	}
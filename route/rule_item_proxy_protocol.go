@@ -0,0 +1,61 @@
+package route
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	F "github.com/sagernet/sing/common/format"
+)
+
+var (
+	_ RuleItem = (*ProxyProtocolItem)(nil)
+	_ RuleItem = (*ProxyProtocolSSLItem)(nil)
+)
+
+type ProxyProtocolItem struct {
+	name     string
+	values   []string
+	valueMap map[string]bool
+	getter   func(info *adapter.ProxyProtocolInfo) string
+}
+
+func NewProxyProtocolItem(name string, values []string, getter func(info *adapter.ProxyProtocolInfo) string) *ProxyProtocolItem {
+	valueMap := make(map[string]bool)
+	for _, value := range values {
+		valueMap[value] = true
+	}
+	return &ProxyProtocolItem{
+		name:     name,
+		values:   values,
+		valueMap: valueMap,
+		getter:   getter,
+	}
+}
+
+func (r *ProxyProtocolItem) Match(metadata *adapter.InboundContext) bool {
+	if metadata.ProxyProtocol == nil {
+		return false
+	}
+	return r.valueMap[r.getter(metadata.ProxyProtocol)]
+}
+
+func (r *ProxyProtocolItem) String() string {
+	if len(r.values) == 1 {
+		return F.ToString(r.name, "=", r.values[0])
+	}
+	return F.ToString(r.name, "=[", strings.Join(r.values, " "), "]")
+}
+
+type ProxyProtocolSSLItem struct{}
+
+func NewProxyProtocolSSLItem() *ProxyProtocolSSLItem {
+	return &ProxyProtocolSSLItem{}
+}
+
+func (r *ProxyProtocolSSLItem) Match(metadata *adapter.InboundContext) bool {
+	return metadata.ProxyProtocol != nil && metadata.ProxyProtocol.SSL
+}
+
+func (r *ProxyProtocolSSLItem) String() string {
+	return "proxy_protocol_ssl=true"
+}
@@ -0,0 +1,168 @@
+package route
+
+import (
+	"encoding/hex"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/json"
+	C "github.com/sagernet/sing-box/constant"
+)
+
+var (
+	_ adapter.PACRule = (*DefaultRule)(nil)
+	_ adapter.PACRule = (*LogicalRule)(nil)
+)
+
+func (r *abstractDefaultRule) PACCondition(metadata *adapter.InboundContext) (string, bool) {
+	if len(r.allItems) == 0 {
+		return "true", true
+	}
+	if len(r.sourceAddressItems) > 0 || len(r.sourcePortItems) > 0 {
+		return "", false
+	}
+	var conditions []string
+	for _, item := range r.items {
+		condition, loaded := pacItemCondition(item, metadata)
+		if !loaded {
+			return "", false
+		}
+		conditions = append(conditions, condition)
+	}
+	for _, group := range [][]RuleItem{r.destinationAddressItems, r.destinationPortItems} {
+		if len(group) == 0 {
+			continue
+		}
+		var groupConditions []string
+		for _, item := range group {
+			condition, loaded := pacItemCondition(item, metadata)
+			if !loaded {
+				return "", false
+			}
+			groupConditions = append(groupConditions, condition)
+		}
+		conditions = append(conditions, pacJoin(groupConditions, " || "))
+	}
+	condition := pacJoin(conditions, " && ")
+	if r.invert {
+		condition = "!" + condition
+	}
+	return condition, true
+}
+
+func (r *abstractLogicalRule) PACCondition(metadata *adapter.InboundContext) (string, bool) {
+	separator := " || "
+	if r.mode == C.LogicalTypeAnd {
+		separator = " && "
+	}
+	conditions := make([]string, 0, len(r.rules))
+	for _, rule := range r.rules {
+		pacRule, isPAC := rule.(adapter.PACRule)
+		if !isPAC {
+			return "", false
+		}
+		condition, loaded := pacRule.PACCondition(metadata)
+		if !loaded {
+			return "", false
+		}
+		conditions = append(conditions, condition)
+	}
+	condition := pacJoin(conditions, separator)
+	if r.invert {
+		condition = "!" + condition
+	}
+	return condition, true
+}
+
+func pacItemCondition(item RuleItem, metadata *adapter.InboundContext) (string, bool) {
+	switch item := item.(type) {
+	case *DomainItem:
+		return "domainMatch(host, " + pacArray(item.domains) + ", " + pacArray(item.domainSuffixes) + ")", true
+	case *DomainKeywordItem:
+		return "keywordMatch(host, " + pacArray(item.keywords) + ")", true
+	case *DomainRegexItem:
+		expressions := make([]string, 0, len(item.matchers))
+		for _, matcher := range item.matchers {
+			expressions = append(expressions, matcher.String())
+		}
+		return "regexMatch(host, " + pacArray(expressions) + ")", true
+	case *GeositeItem:
+		conditions := make([]string, 0, len(item.matchers))
+		for _, matcher := range item.matchers {
+			pacRule, isPAC := matcher.(adapter.PACRule)
+			if !isPAC {
+				return "", false
+			}
+			condition, loaded := pacRule.PACCondition(metadata)
+			if !loaded {
+				return "", false
+			}
+			conditions = append(conditions, condition)
+		}
+		return pacJoin(conditions, " || "), true
+	case *IPCIDRItem:
+		if item.isSource {
+			return "", false
+		}
+		var (
+			ranges4 [][2]uint32
+			ranges6 [][2]string
+		)
+		for _, ipRange := range item.ipSet.Ranges() {
+			if ipRange.From().Is4() {
+				ranges4 = append(ranges4, [2]uint32{pacUint32(ipRange.From().As4()), pacUint32(ipRange.To().As4())})
+			} else {
+				ranges6 = append(ranges6, [2]string{pacHex(ipRange.From().As16()), pacHex(ipRange.To().As16())})
+			}
+		}
+		return "ipMatch(host, " + pacArray(ranges4) + ", " + pacArray(ranges6) + ")", true
+	case *PortItem:
+		if item.isSource {
+			return "", false
+		}
+		ranges := make([][2]uint16, 0, len(item.ports))
+		for _, port := range item.ports {
+			ranges = append(ranges, [2]uint16{port, port})
+		}
+		return "portMatch(port, " + pacArray(ranges) + ")", true
+	case *PortRangeItem:
+		if item.isSource {
+			return "", false
+		}
+		ranges := make([][2]uint16, 0, len(item.portRangeList))
+		for _, portRange := range item.portRangeList {
+			ranges = append(ranges, [2]uint16{portRange.start, portRange.end})
+		}
+		return "portMatch(port, " + pacArray(ranges) + ")", true
+	case *InboundItem, *NetworkItem, *ClashModeItem:
+		if item.Match(metadata) {
+			return "true", true
+		}
+		return "false", true
+	default:
+		return "", false
+	}
+}
+
+func pacJoin(conditions []string, separator string) string {
+	if len(conditions) == 1 {
+		return conditions[0]
+	}
+	return "(" + strings.Join(conditions, separator) + ")"
+}
+
+func pacArray(value any) string {
+	content, _ := json.Marshal(value)
+	if string(content) == "null" {
+		return "[]"
+	}
+	return string(content)
+}
+
+func pacUint32(address [4]byte) uint32 {
+	return uint32(address[0])<<24 | uint32(address[1])<<16 | uint32(address[2])<<8 | uint32(address[3])
+}
+
+func pacHex(address [16]byte) string {
+	return hex.EncodeToString(address[:])
+}
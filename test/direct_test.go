@@ -40,9 +40,11 @@ func TestProxyProtocol(t *testing.T) {
 				Type: C.TypeDirect,
 				Tag:  "trojan-out",
 				DirectOptions: option.DirectOutboundOptions{
+					DialerOptions: option.DialerOptions{
+						ProxyProtocol: 2,
+					},
 					OverrideAddress: "127.0.0.1",
 					OverridePort:    serverPort,
-					ProxyProtocol:   2,
 				},
 			},
 		},
@@ -1,11 +1,18 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"net/netip"
+	"os"
 	"testing"
+	"time"
 
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
+	F "github.com/sagernet/sing/common/format"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestUTLS(t *testing.T) {
@@ -82,3 +89,139 @@ func TestUTLS(t *testing.T) {
 	})
 	testSuit(t, clientPort, testPort)
 }
+
+func TestMutualTLS(t *testing.T) {
+	caPem, certPem, keyPem := createSelfSignedCertificate(t, "example.org")
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeMixed,
+				Tag:  "mixed-in",
+				MixedOptions: option.HTTPMixedInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: clientPort,
+					},
+				},
+			},
+			{
+				Type: C.TypeTrojan,
+				TrojanOptions: option.TrojanInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: serverPort,
+					},
+					Users: []option.TrojanUser{
+						{
+							Password: "password",
+						},
+					},
+					TLS: &option.InboundTLSOptions{
+						Enabled:               true,
+						ServerName:            "example.org",
+						CertificatePath:       certPem,
+						KeyPath:               keyPem,
+						ClientCertificatePath: []string{caPem},
+					},
+				},
+			},
+		},
+		Outbounds: []option.Outbound{
+			{
+				Type: C.TypeDirect,
+			},
+			{
+				Type: C.TypeTrojan,
+				Tag:  "trojan-out",
+				TrojanOptions: option.TrojanOutboundOptions{
+					ServerOptions: option.ServerOptions{
+						Server:     "127.0.0.1",
+						ServerPort: serverPort,
+					},
+					Password: "password",
+					TLS: &option.OutboundTLSOptions{
+						Enabled:               true,
+						ServerName:            "example.org",
+						CertificatePath:       certPem,
+						ClientCertificatePath: certPem,
+						ClientKeyPath:         keyPem,
+						UTLS: &option.OutboundUTLSOptions{
+							Enabled:     true,
+							Fingerprint: "chrome",
+						},
+					},
+				},
+			},
+		},
+		Route: &option.RouteOptions{
+			Rules: []option.Rule{
+				{
+					DefaultOptions: option.DefaultRule{
+						Inbound:  []string{"mixed-in"},
+						Outbound: "trojan-out",
+					},
+				},
+			},
+		},
+	})
+	testSuit(t, clientPort, testPort)
+}
+
+func TestMutualTLSRejected(t *testing.T) {
+	caPem, certPem, keyPem := createSelfSignedCertificate(t, "example.org")
+	_, untrustedCertPem, untrustedKeyPem := createSelfSignedCertificate(t, "example.org")
+	startInstance(t, option.Options{
+		Inbounds: []option.Inbound{
+			{
+				Type: C.TypeTrojan,
+				TrojanOptions: option.TrojanInboundOptions{
+					ListenOptions: option.ListenOptions{
+						Listen:     option.NewListenAddress(netip.IPv4Unspecified()),
+						ListenPort: serverPort,
+					},
+					Users: []option.TrojanUser{
+						{
+							Password: "password",
+						},
+					},
+					TLS: &option.InboundTLSOptions{
+						Enabled:               true,
+						ServerName:            "example.org",
+						CertificatePath:       certPem,
+						KeyPath:               keyPem,
+						ClientCertificatePath: []string{caPem},
+					},
+				},
+			},
+		},
+	})
+	caContent, err := os.ReadFile(caPem)
+	require.NoError(t, err)
+	rootCAs := x509.NewCertPool()
+	require.True(t, rootCAs.AppendCertsFromPEM(caContent))
+	untrustedKeyPair, err := tls.LoadX509KeyPair(untrustedCertPem, untrustedKeyPem)
+	require.NoError(t, err)
+	for _, testCase := range []struct {
+		name         string
+		certificates []tls.Certificate
+	}{
+		{"no certificate", nil},
+		{"untrusted certificate", []tls.Certificate{untrustedKeyPair}},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			conn, err := tls.Dial("tcp", "127.0.0.1:"+F.ToString(serverPort), &tls.Config{
+				ServerName:   "example.org",
+				RootCAs:      rootCAs,
+				Certificates: testCase.certificates,
+			})
+			if err == nil {
+				// With TLS 1.3 the server verifies the client certificate after
+				// the client considers the handshake finished.
+				conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+				_, err = conn.Read(make([]byte, 1))
+				conn.Close()
+			}
+			require.ErrorContains(t, err, "certificate")
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package fakeip
 
 import (
+	"math/big"
 	"net/netip"
+	"sync"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-dns"
@@ -15,8 +17,11 @@ type Store struct {
 	inet4Range   netip.Prefix
 	inet6Range   netip.Prefix
 	storage      adapter.FakeIPStorage
+	access       sync.Mutex
 	inet4Current netip.Addr
 	inet6Current netip.Addr
+	inet4Wrapped bool
+	inet6Wrapped bool
 }
 
 func NewStore(router adapter.Router, inet4Range netip.Prefix, inet6Range netip.Prefix) *Store {
@@ -38,6 +43,8 @@ func (s *Store) Start() error {
 		storage = NewMemoryStorage()
 	}
 	metadata := storage.FakeIPMetadata()
+	s.access.Lock()
+	defer s.access.Unlock()
 	if metadata != nil && metadata.Inet4Range == s.inet4Range && metadata.Inet6Range == s.inet6Range {
 		s.inet4Current = metadata.Inet4Current
 		s.inet6Current = metadata.Inet6Current
@@ -61,37 +68,46 @@ func (s *Store) Close() error {
 	if s.storage == nil {
 		return nil
 	}
-	return s.storage.FakeIPSaveMetadata(&adapter.FakeIPMetadata{
+	s.access.Lock()
+	metadata := &adapter.FakeIPMetadata{
 		Inet4Range:   s.inet4Range,
 		Inet6Range:   s.inet6Range,
 		Inet4Current: s.inet4Current,
 		Inet6Current: s.inet6Current,
-	})
+	}
+	s.access.Unlock()
+	return s.storage.FakeIPSaveMetadata(metadata)
 }
 
 func (s *Store) Create(domain string, strategy dns.DomainStrategy) (netip.Addr, error) {
 	var address netip.Addr
+	s.access.Lock()
 	if strategy == dns.DomainStrategyUseIPv4 {
 		if !s.inet4Current.IsValid() {
+			s.access.Unlock()
 			return netip.Addr{}, E.New("missing IPv4 fakeip address range")
 		}
 		nextAddress := s.inet4Current.Next()
 		if !s.inet4Range.Contains(nextAddress) {
 			nextAddress = s.inet4Range.Addr().Next().Next()
+			s.inet4Wrapped = true
 		}
 		s.inet4Current = nextAddress
 		address = nextAddress
 	} else {
 		if !s.inet6Current.IsValid() {
+			s.access.Unlock()
 			return netip.Addr{}, E.New("missing IPv6 fakeip address range")
 		}
 		nextAddress := s.inet6Current.Next()
 		if !s.inet6Range.Contains(nextAddress) {
 			nextAddress = s.inet6Range.Addr().Next().Next()
+			s.inet6Wrapped = true
 		}
 		s.inet6Current = nextAddress
 		address = nextAddress
 	}
+	s.access.Unlock()
 	err := s.storage.FakeIPStore(address, domain)
 	if err != nil {
 		return netip.Addr{}, err
@@ -106,3 +122,27 @@ func (s *Store) Lookup(address netip.Addr) (string, bool) {
 func (s *Store) Reset() error {
 	return s.storage.FakeIPReset()
 }
+
+func (s *Store) Usage() []adapter.FakeIPUsage {
+	s.access.Lock()
+	defer s.access.Unlock()
+	var usage []adapter.FakeIPUsage
+	if s.inet4Current.IsValid() {
+		usage = append(usage, rangeUsage(s.inet4Range, s.inet4Current, s.inet4Wrapped))
+	}
+	if s.inet6Current.IsValid() {
+		usage = append(usage, rangeUsage(s.inet6Range, s.inet6Current, s.inet6Wrapped))
+	}
+	return usage
+}
+
+func rangeUsage(prefix netip.Prefix, current netip.Addr, wrapped bool) adapter.FakeIPUsage {
+	// the first two addresses of the range are never allocated
+	size, _ := new(big.Float).SetInt(new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(prefix.Addr().BitLen()-prefix.Bits())), big.NewInt(2))).Float64()
+	if wrapped {
+		return adapter.FakeIPUsage{Range: prefix, Allocated: size, Size: size}
+	}
+	start := prefix.Addr().Next().Next()
+	allocated, _ := new(big.Float).SetInt(new(big.Int).Sub(new(big.Int).SetBytes(current.AsSlice()), new(big.Int).SetBytes(start.AsSlice()))).Float64()
+	return adapter.FakeIPUsage{Range: prefix, Allocated: allocated, Size: size}
+}
@@ -0,0 +1,134 @@
+package httpconnect
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/transport/v2rayhttp"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"golang.org/x/net/http2"
+)
+
+type Options struct {
+	Dialer    N.Dialer
+	Server    M.Socksaddr
+	TLSConfig tls.Config
+	HTTP3     bool
+	Username  string
+	Password  string
+	Headers   http.Header
+}
+
+var _ N.Dialer = (*Client)(nil)
+
+type Client struct {
+	transport http.RoundTripper
+	url       *url.URL
+	headers   http.Header
+}
+
+func NewClient(options Options) (*Client, error) {
+	client := &Client{
+		url: &url.URL{
+			Scheme: "https",
+			Host:   options.Server.String(),
+		},
+		headers: options.Headers.Clone(),
+	}
+	if client.headers == nil {
+		client.headers = make(http.Header)
+	}
+	if options.Username != "" {
+		auth := options.Username + ":" + options.Password
+		client.headers.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(auth)))
+	}
+	if options.HTTP3 {
+		if options.TLSConfig == nil {
+			return nil, E.New("TLS is required for HTTP/3")
+		}
+		transport, err := newHTTP3Transport(options.Dialer, options.Server, options.TLSConfig)
+		if err != nil {
+			return nil, err
+		}
+		client.transport = transport
+		return client, nil
+	}
+	tlsConfig := options.TLSConfig
+	if tlsConfig == nil {
+		client.url.Scheme = "http"
+	} else if len(tlsConfig.NextProtos()) == 0 {
+		tlsConfig.SetNextProtos([]string{http2.NextProtoTLS})
+	} else if !common.Contains(tlsConfig.NextProtos(), http2.NextProtoTLS) {
+		return nil, E.New("ALPN must include ", http2.NextProtoTLS, " for HTTP/2")
+	}
+	client.transport = &http2.Transport{
+		AllowHTTP:          tlsConfig == nil,
+		DisableCompression: true,
+		DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.STDConfig) (net.Conn, error) {
+			conn, err := options.Dialer.DialContext(ctx, N.NetworkTCP, options.Server)
+			if err != nil {
+				return nil, err
+			}
+			if tlsConfig == nil {
+				return conn, nil
+			}
+			return tls.ClientHandshake(ctx, conn, tlsConfig)
+		},
+	}
+	return client, nil
+}
+
+func (c *Client) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	if network != N.NetworkTCP {
+		return nil, os.ErrInvalid
+	}
+	conn, _, err := c.Connect(ctx, destination, nil)
+	return conn, err
+}
+
+func (c *Client) Connect(ctx context.Context, destination M.Socksaddr, header http.Header) (net.Conn, http.Header, error) {
+	requestHeader := c.headers.Clone()
+	for key, values := range header {
+		requestHeader[key] = values
+	}
+	pipeInReader, pipeInWriter := io.Pipe()
+	request := &http.Request{
+		Method: http.MethodConnect,
+		URL:    c.url,
+		Host:   destination.String(),
+		Header: requestHeader,
+		Body:   pipeInReader,
+	}
+	request = request.WithContext(ctx)
+	response, err := c.transport.RoundTrip(request)
+	if err != nil {
+		pipeInWriter.Close()
+		return nil, nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		pipeInWriter.Close()
+		response.Body.Close()
+		return nil, nil, E.New("unexpected status: ", response.Status)
+	}
+	conn := v2rayhttp.NewHTTPConn(response.Body, pipeInWriter)
+	return &conn, response.Header, nil
+}
+
+func (c *Client) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return nil, os.ErrInvalid
+}
+
+func (c *Client) Close() error {
+	v2rayhttp.CloseIdleConnections(c.transport)
+	return nil
+}
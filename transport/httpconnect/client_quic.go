@@ -0,0 +1,47 @@
+//go:build with_quic
+
+package httpconnect
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/quic-go/http3"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common/bufio"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+func newHTTP3Transport(dialer N.Dialer, serverAddr M.Socksaddr, tlsConfig tls.Config) (http.RoundTripper, error) {
+	stdConfig, err := tlsConfig.Config()
+	if err != nil {
+		return nil, err
+	}
+	return &http3.RoundTripper{
+		TLSClientConfig:    stdConfig,
+		DisableCompression: true,
+		QuicConfig: &quic.Config{
+			DisablePathMTUDiscovery: !C.IsLinux && !C.IsWindows,
+		},
+		Dial: func(ctx context.Context, addr string, tlsCfg *tls.STDConfig, cfg *quic.Config) (quic.EarlyConnection, error) {
+			udpConn, err := dialer.DialContext(ctx, N.NetworkUDP, serverAddr)
+			if err != nil {
+				return nil, err
+			}
+			packetConn := bufio.NewUnbindPacketConn(udpConn)
+			quicConn, err := quic.DialEarly(ctx, packetConn, udpConn.RemoteAddr(), tlsCfg, cfg)
+			if err != nil {
+				packetConn.Close()
+				return nil, err
+			}
+			go func() {
+				<-quicConn.Context().Done()
+				packetConn.Close()
+			}()
+			return quicConn, nil
+		},
+	}, nil
+}
@@ -0,0 +1,50 @@
+package httpconnect
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/option"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testRouter struct {
+	adapter.Router
+}
+
+func (r *testRouter) TimeFunc() func() time.Time {
+	return time.Now
+}
+
+func TestClientALPN(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		alpn    []string
+		success bool
+	}{
+		{nil, true},
+		{[]string{"h2"}, true},
+		{[]string{"h2", "http/1.1"}, true},
+		{[]string{"http/1.1"}, false},
+	} {
+		tlsConfig, err := tls.NewSTDClient(&testRouter{}, "example.org", option.OutboundTLSOptions{
+			Enabled: true,
+			ALPN:    testCase.alpn,
+		})
+		require.NoError(t, err)
+		_, err = NewClient(Options{
+			Dialer:    N.SystemDialer,
+			TLSConfig: tlsConfig,
+		})
+		if testCase.success {
+			require.NoError(t, err, testCase.alpn)
+			require.Contains(t, tlsConfig.NextProtos(), "h2")
+		} else {
+			require.Error(t, err, testCase.alpn)
+		}
+	}
+}
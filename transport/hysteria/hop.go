@@ -0,0 +1,225 @@
+package hysteria
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const DefaultHopInterval = 30 * time.Second
+
+// HopPacketConn sends packets to a random server port, switching to a new port
+// and a new local socket on every interval. Packets are reported as coming from
+// the initial server address, so the QUIC connection on top is not migrated.
+type HopPacketConn struct {
+	ctx        context.Context
+	logger     logger.ContextLogger
+	dialer     N.Dialer
+	serverAddr M.Socksaddr
+	ports      []uint16
+	interval   time.Duration
+	remoteAddr net.Addr
+	packets    chan *buf.Buffer
+	done       chan struct{}
+	closeOnce  sync.Once
+
+	access   sync.RWMutex
+	current  net.Conn
+	previous net.Conn
+
+	deadlineAccess sync.Mutex
+	readDeadline   time.Time
+	deadlineNotify chan struct{}
+}
+
+func NewHopPacketConn(ctx context.Context, logger logger.ContextLogger, dialer N.Dialer, serverAddr M.Socksaddr, ports []uint16, interval time.Duration) (*HopPacketConn, error) {
+	if interval == 0 {
+		interval = DefaultHopInterval
+	}
+	conn := &HopPacketConn{
+		ctx:            ctx,
+		logger:         logger,
+		dialer:         dialer,
+		serverAddr:     serverAddr,
+		ports:          ports,
+		interval:       interval,
+		packets:        make(chan *buf.Buffer, 256),
+		done:           make(chan struct{}),
+		deadlineNotify: make(chan struct{}),
+	}
+	current, err := conn.dial()
+	if err != nil {
+		return nil, err
+	}
+	conn.current = current
+	conn.remoteAddr = current.RemoteAddr()
+	go conn.loopRead(current)
+	go conn.loopHop()
+	return conn, nil
+}
+
+func (c *HopPacketConn) dial() (net.Conn, error) {
+	serverAddr := c.serverAddr
+	serverAddr.Port = c.ports[rand.Intn(len(c.ports))]
+	return c.dialer.DialContext(c.ctx, N.NetworkUDP, serverAddr)
+}
+
+func (c *HopPacketConn) loopHop() {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			conn, err := c.dial()
+			if err != nil {
+				c.logger.ErrorContext(c.ctx, E.Cause(err, "hop to new port"))
+				continue
+			}
+			c.access.Lock()
+			select {
+			case <-c.done:
+				c.access.Unlock()
+				conn.Close()
+				return
+			default:
+			}
+			common.Close(c.previous)
+			c.previous = c.current
+			c.current = conn
+			c.access.Unlock()
+			go c.loopRead(conn)
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *HopPacketConn) loopRead(conn net.Conn) {
+	for {
+		buffer := buf.NewPacket()
+		_, err := buffer.ReadOnceFrom(conn)
+		if err != nil {
+			buffer.Release()
+			if E.IsClosed(err) {
+				return
+			}
+			select {
+			case <-c.done:
+				return
+			default:
+				continue
+			}
+		}
+		select {
+		case c.packets <- buffer:
+		case <-c.done:
+			buffer.Release()
+			return
+		}
+	}
+}
+
+func (c *HopPacketConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	for {
+		c.deadlineAccess.Lock()
+		deadline := c.readDeadline
+		deadlineNotify := c.deadlineNotify
+		c.deadlineAccess.Unlock()
+		var (
+			timer   *time.Timer
+			timeout <-chan time.Time
+		)
+		if !deadline.IsZero() {
+			duration := time.Until(deadline)
+			if duration <= 0 {
+				return 0, nil, os.ErrDeadlineExceeded
+			}
+			timer = time.NewTimer(duration)
+			timeout = timer.C
+		}
+		var (
+			buffer          *buf.Buffer
+			deadlineChanged bool
+		)
+		select {
+		case buffer = <-c.packets:
+		case <-c.done:
+			err = net.ErrClosed
+		case <-timeout:
+			err = os.ErrDeadlineExceeded
+		case <-deadlineNotify:
+			deadlineChanged = true
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+		if deadlineChanged {
+			continue
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+		n = copy(p, buffer.Bytes())
+		buffer.Release()
+		return n, c.remoteAddr, nil
+	}
+}
+
+func (c *HopPacketConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
+	c.access.RLock()
+	conn := c.current
+	c.access.RUnlock()
+	return conn.Write(p)
+}
+
+func (c *HopPacketConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+	c.access.Lock()
+	defer c.access.Unlock()
+	return common.Close(c.current, c.previous)
+}
+
+func (c *HopPacketConn) LocalAddr() net.Addr {
+	c.access.RLock()
+	defer c.access.RUnlock()
+	return c.current.LocalAddr()
+}
+
+func (c *HopPacketConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+func (c *HopPacketConn) SetDeadline(t time.Time) error {
+	err := c.SetReadDeadline(t)
+	if err != nil {
+		return err
+	}
+	return c.SetWriteDeadline(t)
+}
+
+func (c *HopPacketConn) SetReadDeadline(t time.Time) error {
+	c.deadlineAccess.Lock()
+	defer c.deadlineAccess.Unlock()
+	c.readDeadline = t
+	close(c.deadlineNotify)
+	c.deadlineNotify = make(chan struct{})
+	return nil
+}
+
+func (c *HopPacketConn) SetWriteDeadline(t time.Time) error {
+	c.access.RLock()
+	defer c.access.RUnlock()
+	return c.current.SetWriteDeadline(t)
+}
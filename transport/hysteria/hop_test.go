@@ -0,0 +1,158 @@
+package hysteria
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+var _ N.Dialer = (*testHopDialer)(nil)
+
+type testHopDialer struct {
+	access   sync.Mutex
+	dials    int
+	failFrom int
+}
+
+func (d *testHopDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	d.access.Lock()
+	d.dials++
+	dials := d.dials
+	d.access.Unlock()
+	if d.failFrom > 0 && dials >= d.failFrom {
+		return nil, E.New("dial failed")
+	}
+	return net.Dial(network, destination.String())
+}
+
+func (d *testHopDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return nil, os.ErrInvalid
+}
+
+func (d *testHopDialer) Dials() int {
+	d.access.Lock()
+	defer d.access.Unlock()
+	return d.dials
+}
+
+// startHopServers starts UDP echo servers on loopback that prefix each reply with their port.
+func startHopServers(t *testing.T, count int) []uint16 {
+	var ports []uint16
+	for i := 0; i < count; i++ {
+		conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			conn.Close()
+		})
+		port := uint16(conn.LocalAddr().(*net.UDPAddr).Port)
+		ports = append(ports, port)
+		go func() {
+			buffer := make([]byte, 1024)
+			for {
+				n, addr, err := conn.ReadFrom(buffer)
+				if err != nil {
+					return
+				}
+				conn.WriteTo(append([]byte(strconv.Itoa(int(port))+" "), buffer[:n]...), addr)
+			}
+		}()
+	}
+	return ports
+}
+
+func TestHopPacketConn(t *testing.T) {
+	t.Parallel()
+	ports := startHopServers(t, 3)
+	dialer := &testHopDialer{}
+	conn, err := NewHopPacketConn(context.Background(), logger.NOP(), dialer, M.ParseSocksaddrHostPort("127.0.0.1", 0), ports, 20*time.Millisecond)
+	require.NoError(t, err)
+	defer conn.Close()
+	remoteAddr := conn.RemoteAddr()
+	seenPorts := make(map[string]bool)
+	buffer := make([]byte, 1024)
+	for i := 0; i < 100 && len(seenPorts) < len(ports); i++ {
+		_, err = conn.WriteTo([]byte("ping"), remoteAddr)
+		require.NoError(t, err)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		n, addr, err := conn.ReadFrom(buffer)
+		require.NoError(t, err)
+		// replies are always reported as coming from the initial server address
+		require.Equal(t, remoteAddr, addr)
+		port, content, found := strings.Cut(string(buffer[:n]), " ")
+		require.True(t, found)
+		require.Equal(t, "ping", content)
+		seenPorts[port] = true
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.Len(t, seenPorts, len(ports))
+	require.Greater(t, dialer.Dials(), 1)
+}
+
+func TestHopPacketConnDialFailure(t *testing.T) {
+	t.Parallel()
+	ports := startHopServers(t, 1)
+	dialer := &testHopDialer{failFrom: 2}
+	conn, err := NewHopPacketConn(context.Background(), logger.NOP(), dialer, M.ParseSocksaddrHostPort("127.0.0.1", 0), ports, 10*time.Millisecond)
+	require.NoError(t, err)
+	defer conn.Close()
+	for dialer.Dials() < 4 {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// failed hops keep the current socket
+	_, err = conn.WriteTo([]byte("ping"), conn.RemoteAddr())
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buffer := make([]byte, 1024)
+	n, _, err := conn.ReadFrom(buffer)
+	require.NoError(t, err)
+	require.Equal(t, strconv.Itoa(int(ports[0]))+" ping", string(buffer[:n]))
+
+	_, err = NewHopPacketConn(context.Background(), logger.NOP(), &testHopDialer{failFrom: 1}, M.ParseSocksaddrHostPort("127.0.0.1", 0), ports, 0)
+	require.Error(t, err)
+}
+
+func TestHopPacketConnDeadline(t *testing.T) {
+	t.Parallel()
+	ports := startHopServers(t, 1)
+	conn, err := NewHopPacketConn(context.Background(), logger.NOP(), &testHopDialer{}, M.ParseSocksaddrHostPort("127.0.0.1", 0), ports, time.Hour)
+	require.NoError(t, err)
+	buffer := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(-time.Second)))
+	_, _, err = conn.ReadFrom(buffer)
+	require.True(t, errors.Is(err, os.ErrDeadlineExceeded))
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Hour)))
+	done := make(chan error)
+	go func() {
+		_, _, err := conn.ReadFrom(buffer)
+		done <- err
+	}()
+	for i := 0; i < 100; i++ {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Hour)))
+	}
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+	require.True(t, errors.Is(<-done, os.ErrDeadlineExceeded))
+
+	require.NoError(t, conn.SetReadDeadline(time.Time{}))
+	go func() {
+		_, _, err := conn.ReadFrom(buffer)
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(t, conn.Close())
+	require.ErrorIs(t, <-done, net.ErrClosed)
+}
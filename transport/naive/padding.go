@@ -0,0 +1,209 @@
+package naive
+
+import (
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"net"
+
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/rw"
+)
+
+const (
+	FirstPaddings  = 8
+	maxPaddingData = 65535
+	maxPaddingSize = 255
+)
+
+func GeneratePaddingHeader() string {
+	paddingLen := rand.Intn(32) + 30
+	padding := make([]byte, paddingLen)
+	bits := rand.Uint64()
+	for i := 0; i < 16; i++ {
+		// Codes that won't be Huffman coded.
+		padding[i] = "!#$()+<>?@[]^`{}"[bits&15]
+		bits >>= 4
+	}
+	for i := 16; i < paddingLen; i++ {
+		padding[i] = '~'
+	}
+	return string(padding)
+}
+
+// PaddingStream frames the first FirstPaddings reads and writes of a naive tunnel
+// as a 2-byte data length, a 1-byte padding length, the data and zeroed padding.
+type PaddingStream struct {
+	reader           io.Reader
+	writer           io.Writer
+	readPadding      int
+	writePadding     int
+	readRemaining    int
+	paddingRemaining int
+}
+
+func NewPaddingStream(reader io.Reader, writer io.Writer) *PaddingStream {
+	return &PaddingStream{reader: reader, writer: writer}
+}
+
+func (s *PaddingStream) Read(p []byte) (n int, err error) {
+	if s.readRemaining > 0 {
+		if len(p) > s.readRemaining {
+			p = p[:s.readRemaining]
+		}
+		n, err = s.reader.Read(p)
+		if err != nil {
+			return
+		}
+		s.readRemaining -= n
+		return
+	}
+	if s.paddingRemaining > 0 {
+		err = rw.SkipN(s.reader, s.paddingRemaining)
+		if err != nil {
+			return
+		}
+		s.paddingRemaining = 0
+	}
+	if s.readPadding < FirstPaddings {
+		var paddingHdr []byte
+		if len(p) >= 3 {
+			paddingHdr = p[:3]
+		} else {
+			paddingHdr = make([]byte, 3)
+		}
+		_, err = io.ReadFull(s.reader, paddingHdr)
+		if err != nil {
+			return
+		}
+		originalDataSize := int(binary.BigEndian.Uint16(paddingHdr[:2]))
+		paddingSize := int(paddingHdr[2])
+		if len(p) > originalDataSize {
+			p = p[:originalDataSize]
+		}
+		n, err = s.reader.Read(p)
+		if err != nil {
+			return
+		}
+		s.readPadding++
+		s.readRemaining = originalDataSize - n
+		s.paddingRemaining = paddingSize
+		return
+	}
+	return s.reader.Read(p)
+}
+
+func (s *PaddingStream) Write(p []byte) (n int, err error) {
+	for pLen := len(p); pLen > 0; {
+		var data []byte
+		if pLen > maxPaddingData {
+			data = p[:maxPaddingData]
+			p = p[maxPaddingData:]
+			pLen -= maxPaddingData
+		} else {
+			data = p
+			pLen = 0
+		}
+		var writeN int
+		writeN, err = s.write(data)
+		n += writeN
+		if err != nil {
+			break
+		}
+	}
+	return
+}
+
+func (s *PaddingStream) write(p []byte) (n int, err error) {
+	if s.writePadding < FirstPaddings {
+		paddingSize := rand.Intn(maxPaddingSize + 1)
+		buffer := buf.NewSize(3 + len(p) + paddingSize)
+		defer buffer.Release()
+		header := buffer.Extend(3)
+		binary.BigEndian.PutUint16(header, uint16(len(p)))
+		header[2] = byte(paddingSize)
+		common.Must1(buffer.Write(p))
+		clearPadding(buffer.Extend(paddingSize))
+		_, err = s.writer.Write(buffer.Bytes())
+		if err == nil {
+			n = len(p)
+		}
+		s.writePadding++
+		return
+	}
+	return s.writer.Write(p)
+}
+
+func (s *PaddingStream) WriteBuffer(buffer *buf.Buffer) error {
+	defer buffer.Release()
+	if s.writePadding < FirstPaddings {
+		bufferLen := buffer.Len()
+		if bufferLen > maxPaddingData {
+			return common.Error(s.Write(buffer.Bytes()))
+		}
+		paddingSize := rand.Intn(maxPaddingSize + 1)
+		header := buffer.ExtendHeader(3)
+		binary.BigEndian.PutUint16(header, uint16(bufferLen))
+		header[2] = byte(paddingSize)
+		clearPadding(buffer.Extend(paddingSize))
+		s.writePadding++
+	}
+	return common.Error(s.writer.Write(buffer.Bytes()))
+}
+
+func (s *PaddingStream) FrontHeadroom() int {
+	if s.writePadding < FirstPaddings {
+		return 3
+	}
+	return 0
+}
+
+func (s *PaddingStream) RearHeadroom() int {
+	if s.writePadding < FirstPaddings {
+		return maxPaddingSize
+	}
+	return 0
+}
+
+func (s *PaddingStream) WriterMTU() int {
+	if s.writePadding < FirstPaddings {
+		return maxPaddingData
+	}
+	return 0
+}
+
+func (s *PaddingStream) ReaderReplaceable() bool {
+	return s.readPadding == FirstPaddings && s.readRemaining == 0 && s.paddingRemaining == 0
+}
+
+func (s *PaddingStream) WriterReplaceable() bool {
+	return s.writePadding == FirstPaddings
+}
+
+func clearPadding(padding []byte) {
+	for i := range padding {
+		padding[i] = 0
+	}
+}
+
+type PaddingConn struct {
+	net.Conn
+	*PaddingStream
+}
+
+func NewPaddingConn(conn net.Conn) *PaddingConn {
+	return &PaddingConn{conn, NewPaddingStream(conn, conn)}
+}
+
+func (c *PaddingConn) Read(p []byte) (n int, err error) {
+	return c.PaddingStream.Read(p)
+}
+
+func (c *PaddingConn) Write(p []byte) (n int, err error) {
+	return c.PaddingStream.Write(p)
+}
+
+func (c *PaddingConn) Upstream() any {
+	return c.Conn
+}
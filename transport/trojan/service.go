@@ -36,7 +36,10 @@ func NewService[K comparable](handler Handler, fallbackHandler N.TCPConnectionHa
 	}
 }
 
-var ErrUserExists = E.New("user already exists")
+var (
+	ErrUserExists = E.New("user already exists")
+	ErrBadRequest = E.New("bad request")
+)
 
 func (s *Service[K]) UpdateUsers(userList []K, passwordList []string) error {
 	users := make(map[K][56]byte)
@@ -69,7 +72,7 @@ func (s *Service[K]) NewConnection(ctx context.Context, conn net.Conn, metadata
 	if user, loaded := s.keys[key]; loaded {
 		ctx = auth.ContextWithUser(ctx, user)
 	} else {
-		return s.fallback(ctx, conn, metadata, key[:], E.New("bad request"))
+		return s.fallback(ctx, conn, metadata, key[:], ErrBadRequest)
 	}
 
 	err = rw.SkipN(conn, 2)
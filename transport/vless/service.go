@@ -18,6 +18,8 @@ import (
 	"github.com/gofrs/uuid/v5"
 )
 
+var ErrUnknownUUID = E.New("unknown UUID")
+
 type Service[T comparable] struct {
 	userMap  map[[16]byte]T
 	userFlow map[T]string
@@ -62,7 +64,7 @@ func (s *Service[T]) NewConnection(ctx context.Context, conn net.Conn, metadata
 	}
 	user, loaded := s.userMap[request.UUID]
 	if !loaded {
-		return E.New("unknown UUID: ", uuid.FromBytesOrNil(request.UUID[:]))
+		return E.Extend(ErrUnknownUUID, uuid.FromBytesOrNil(request.UUID[:]))
 	}
 	ctx = auth.ContextWithUser(ctx, user)
 	metadata.Destination = request.Destination